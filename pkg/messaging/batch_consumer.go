@@ -0,0 +1,268 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ========== 批量消费 ==========
+
+// ErrBatchConsumerClosed 批量消费者已关闭，消息未进入缓冲（由订阅者 Nack）
+var ErrBatchConsumerClosed = errors.New("messaging: batch consumer is closed")
+
+// BatchNackPolicy 批次处理失败时的拒绝策略
+type BatchNackPolicy string
+
+const (
+	// BatchNackAll 拒绝整个批次（默认）
+	BatchNackAll BatchNackPolicy = "all"
+
+	// BatchNackFailed 只拒绝失败的消息，其余确认
+	// 批处理函数需返回 *BatchError 标明失败的消息，返回其他错误时仍拒绝整个批次
+	BatchNackFailed BatchNackPolicy = "failed"
+)
+
+// BatchConfig 批量消费配置
+type BatchConfig struct {
+	// Size 批次大小（默认 100）
+	// 不应超过 NSQ 的 MaxInFlight / RabbitMQ 的 PrefetchCount，否则批次只能等 FlushInterval 到期
+	Size int
+
+	// FlushInterval 批次第一条消息到达后的最长等待时间（默认 1s）
+	// 应明显小于 NSQ 的 MsgTimeout，避免缓冲中的消息超时重投
+	FlushInterval time.Duration
+
+	// NackPolicy 批次处理失败时的拒绝策略（默认 BatchNackAll）
+	NackPolicy BatchNackPolicy
+}
+
+// BatchHandler 批处理函数
+// 返回 nil 时确认整个批次；需要逐条报告失败时返回 NewBatchError
+type BatchHandler func(ctx context.Context, msgs []*Message) error
+
+// BatchError 批处理的逐条错误
+// Errors 与批次中的消息一一对应，nil 表示该消息处理成功
+type BatchError struct {
+	Errors []error
+}
+
+// NewBatchError 根据逐条错误构造 *BatchError；全部成功时返回 nil
+func NewBatchError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return &BatchError{Errors: errs}
+		}
+	}
+	return nil
+}
+
+func (e *BatchError) Error() string {
+	failed := e.Failed()
+	msgs := make([]string, 0, len(failed))
+	for _, i := range failed {
+		msgs = append(msgs, fmt.Sprintf("message %d: %v", i, e.Errors[i]))
+	}
+	return fmt.Sprintf("messaging: %d of %d messages failed in batch: %s",
+		len(failed), len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Unwrap 支持 errors.Is / errors.As 匹配任一消息的错误
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// Failed 失败消息的下标
+func (e *BatchError) Failed() []int {
+	var failed []int
+	for i, err := range e.Errors {
+		if err != nil {
+			failed = append(failed, i)
+		}
+	}
+	return failed
+}
+
+// BatchConsumer 批量消费者
+//
+// 消息进入缓冲后由处理器接管确认（Message.Defer），订阅者不再自动 Ack；
+// 缓冲达到 Size 或第一条消息等待超过 FlushInterval 时交给批处理函数，
+// 成功后统一 Ack，失败时按 NackPolicy 拒绝。进程在批次处理前退出时，
+// 未确认的消息由 broker 重新投递，不会丢失。
+//
+// 实现了 BatchProcessor 接口。批处理函数的错误不会经过 DLQ 等中间件。
+type BatchConsumer struct {
+	cfg BatchConfig
+	fn  BatchHandler
+
+	mu     sync.Mutex
+	buf    []*Message
+	gen    uint64 // 每取走一个批次递增，用于识别过期的定时器
+	timer  *time.Timer
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewBatchConsumer 创建批量消费者
+//
+// 使用示例：
+//
+//	batch := messaging.NewBatchConsumer(messaging.BatchConfig{Size: 100, FlushInterval: time.Second},
+//	    func(ctx context.Context, msgs []*messaging.Message) error {
+//	        return repo.BulkInsert(ctx, msgs)
+//	    })
+//	router.AddHandler("metrics.reported", "ingest", batch.Handler())
+//	defer batch.Close()
+func NewBatchConsumer(cfg BatchConfig, fn BatchHandler) *BatchConsumer {
+	if cfg.Size <= 0 {
+		cfg.Size = 100
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.NackPolicy == "" {
+		cfg.NackPolicy = BatchNackAll
+	}
+	return &BatchConsumer{
+		cfg: cfg,
+		fn:  fn,
+		buf: make([]*Message, 0, cfg.Size),
+	}
+}
+
+// Handler 返回订阅使用的处理器
+// 达到批次大小时在当前投递的 goroutine 中处理批次，对顺序投递的订阅者形成背压
+func (c *BatchConsumer) Handler() Handler {
+	return func(ctx context.Context, msg *Message) error {
+		return c.Add(msg)
+	}
+}
+
+// Add 实现 BatchProcessor 接口：将消息加入缓冲，满批时立即处理
+// 批次处理的错误已通过 Nack 反馈给 broker，这里只返回缓冲本身的错误
+func (c *BatchConsumer) Add(msg *Message) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrBatchConsumerClosed
+	}
+
+	msg.Defer()
+	c.buf = append(c.buf, msg)
+
+	var batch []*Message
+	if len(c.buf) >= c.cfg.Size {
+		batch = c.takeLocked()
+	} else if len(c.buf) == 1 {
+		gen := c.gen
+		c.timer = time.AfterFunc(c.cfg.FlushInterval, func() { c.flushExpired(gen) })
+	}
+	c.mu.Unlock()
+
+	if batch != nil {
+		_ = c.process(batch)
+	}
+	return nil
+}
+
+// Flush 实现 BatchProcessor 接口：立即处理缓冲中的消息，返回批处理函数的错误
+func (c *BatchConsumer) Flush() error {
+	c.mu.Lock()
+	batch := c.takeLocked()
+	c.mu.Unlock()
+
+	if batch == nil {
+		return nil
+	}
+	return c.process(batch)
+}
+
+// Close 停止接收新消息，处理缓冲中剩余的消息并等待所有批次完成
+func (c *BatchConsumer) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+
+	err := c.Flush()
+	c.wg.Wait()
+	return err
+}
+
+// flushExpired 定时器到期：仅处理定时器创建时的那一批
+func (c *BatchConsumer) flushExpired(gen uint64) {
+	c.mu.Lock()
+	if gen != c.gen {
+		c.mu.Unlock()
+		return
+	}
+	batch := c.takeLocked()
+	c.mu.Unlock()
+
+	if batch != nil {
+		_ = c.process(batch)
+	}
+}
+
+// takeLocked 取走缓冲中的批次并停止定时器；调用方持有 mu
+func (c *BatchConsumer) takeLocked() []*Message {
+	if len(c.buf) == 0 {
+		return nil
+	}
+	batch := c.buf
+	c.buf = make([]*Message, 0, c.cfg.Size)
+	c.gen++
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.wg.Add(1)
+	return batch
+}
+
+// process 调用批处理函数并按结果确认消息
+func (c *BatchConsumer) process(batch []*Message) error {
+	defer c.wg.Done()
+
+	err := c.fn(context.Background(), batch)
+	if err == nil {
+		for _, msg := range batch {
+			settleLogged(msg, msg.Ack, "ack")
+		}
+		return nil
+	}
+
+	log.Printf("[messaging] batch of %d messages failed: %v", len(batch), err)
+
+	var batchErr *BatchError
+	if c.cfg.NackPolicy == BatchNackFailed && errors.As(err, &batchErr) && len(batchErr.Errors) == len(batch) {
+		for i, msg := range batch {
+			if batchErr.Errors[i] != nil {
+				settleLogged(msg, msg.Nack, "nack")
+			} else {
+				settleLogged(msg, msg.Ack, "ack")
+			}
+		}
+		return err
+	}
+
+	for _, msg := range batch {
+		settleLogged(msg, msg.Nack, "nack")
+	}
+	return err
+}
+
+func settleLogged(msg *Message, settle func() error, action string) {
+	if err := settle(); err != nil {
+		log.Printf("[messaging] %s message %s failed: %v", action, msg.UUID, err)
+	}
+}
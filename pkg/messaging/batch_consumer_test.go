@@ -0,0 +1,190 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// settleRecorder 记录消息的 Ack/Nack
+type settleRecorder struct {
+	mu     sync.Mutex
+	acked  []string
+	nacked []string
+}
+
+func (r *settleRecorder) message(id string) *Message {
+	msg := NewMessage(id, nil)
+	msg.SetAckFunc(func() error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.acked = append(r.acked, id)
+		return nil
+	})
+	msg.SetNackFunc(func() error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.nacked = append(r.nacked, id)
+		return nil
+	})
+	return msg
+}
+
+func (r *settleRecorder) counts() (acked, nacked int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.acked), len(r.nacked)
+}
+
+func TestBatchConsumerAcksAfterBatchHandler(t *testing.T) {
+	rec := &settleRecorder{}
+	var batches [][]*Message
+	consumer := NewBatchConsumer(BatchConfig{Size: 3, FlushInterval: time.Hour},
+		func(ctx context.Context, msgs []*Message) error {
+			if acked, _ := rec.counts(); acked != 0 {
+				t.Errorf("messages acked before batch handler ran")
+			}
+			batches = append(batches, msgs)
+			return nil
+		})
+	handler := consumer.Handler()
+
+	for i := 0; i < 2; i++ {
+		msg := rec.message(fmt.Sprintf("m%d", i))
+		if err := handler(context.Background(), msg); err != nil {
+			t.Fatalf("handler: %v", err)
+		}
+		if !msg.IsDeferred() || msg.IsSettled() {
+			t.Fatalf("buffered message should be deferred and unsettled")
+		}
+	}
+	if len(batches) != 0 {
+		t.Fatalf("flushed before batch was full")
+	}
+
+	_ = handler(context.Background(), rec.message("m2"))
+	if len(batches) != 1 || len(batches[0]) != 3 {
+		t.Fatalf("batches = %d, want one batch of 3", len(batches))
+	}
+	if acked, nacked := rec.counts(); acked != 3 || nacked != 0 {
+		t.Fatalf("acked=%d nacked=%d, want 3/0", acked, nacked)
+	}
+}
+
+func TestBatchConsumerFlushesOnTimer(t *testing.T) {
+	rec := &settleRecorder{}
+	flushed := make(chan int, 1)
+	consumer := NewBatchConsumer(BatchConfig{Size: 10, FlushInterval: 20 * time.Millisecond},
+		func(ctx context.Context, msgs []*Message) error {
+			flushed <- len(msgs)
+			return nil
+		})
+
+	_ = consumer.Add(rec.message("m0"))
+	_ = consumer.Add(rec.message("m1"))
+
+	select {
+	case n := <-flushed:
+		if n != 2 {
+			t.Fatalf("flushed %d messages, want 2", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("batch was not flushed by timer")
+	}
+	if err := consumer.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if acked, _ := rec.counts(); acked != 2 {
+		t.Fatalf("acked = %d, want 2", acked)
+	}
+}
+
+func TestBatchConsumerNackPolicies(t *testing.T) {
+	partial := func(ctx context.Context, msgs []*Message) error {
+		errs := make([]error, len(msgs))
+		errs[1] = errors.New("bad row")
+		return NewBatchError(errs)
+	}
+
+	tests := []struct {
+		policy      BatchNackPolicy
+		wantAcked   int
+		wantNacked  int
+		wantFailure bool
+	}{
+		{policy: BatchNackAll, wantAcked: 0, wantNacked: 3},
+		{policy: BatchNackFailed, wantAcked: 2, wantNacked: 1},
+	}
+	for _, tt := range tests {
+		rec := &settleRecorder{}
+		consumer := NewBatchConsumer(BatchConfig{Size: 10, FlushInterval: time.Hour, NackPolicy: tt.policy}, partial)
+		for i := 0; i < 3; i++ {
+			_ = consumer.Add(rec.message(fmt.Sprintf("m%d", i)))
+		}
+
+		err := consumer.Flush()
+		var batchErr *BatchError
+		if !errors.As(err, &batchErr) || len(batchErr.Failed()) != 1 || batchErr.Failed()[0] != 1 {
+			t.Fatalf("%s: Flush error = %v", tt.policy, err)
+		}
+		if acked, nacked := rec.counts(); acked != tt.wantAcked || nacked != tt.wantNacked {
+			t.Fatalf("%s: acked=%d nacked=%d, want %d/%d", tt.policy, acked, nacked, tt.wantAcked, tt.wantNacked)
+		}
+	}
+}
+
+func TestBatchConsumerCloseFlushesAndRejects(t *testing.T) {
+	rec := &settleRecorder{}
+	consumer := NewBatchConsumer(BatchConfig{Size: 10, FlushInterval: time.Hour},
+		func(ctx context.Context, msgs []*Message) error { return nil })
+
+	_ = consumer.Add(rec.message("m0"))
+	if err := consumer.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if acked, _ := rec.counts(); acked != 1 {
+		t.Fatalf("acked = %d after Close, want 1", acked)
+	}
+
+	late := rec.message("late")
+	if err := consumer.Handler()(context.Background(), late); !errors.Is(err, ErrBatchConsumerClosed) {
+		t.Fatalf("error = %v, want ErrBatchConsumerClosed", err)
+	}
+	if late.IsDeferred() {
+		t.Fatalf("rejected message should be left to the subscriber")
+	}
+}
+
+func TestBatchConsumerConcurrentAdd(t *testing.T) {
+	rec := &settleRecorder{}
+	var mu sync.Mutex
+	total := 0
+	consumer := NewBatchConsumer(BatchConfig{Size: 7, FlushInterval: 5 * time.Millisecond},
+		func(ctx context.Context, msgs []*Message) error {
+			mu.Lock()
+			total += len(msgs)
+			mu.Unlock()
+			return nil
+		})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_ = consumer.Add(rec.message(fmt.Sprintf("m%d", i)))
+		}(i)
+	}
+	wg.Wait()
+	_ = consumer.Close()
+
+	if total != 100 {
+		t.Fatalf("processed %d messages, want 100", total)
+	}
+	if acked, _ := rec.counts(); acked != 100 {
+		t.Fatalf("acked = %d, want 100", acked)
+	}
+}
@@ -0,0 +1,199 @@
+package messaging
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ========== 多事件总线 ==========
+
+// DefaultBusName 顶层 Config（Provider 非空时）在 BusRegistry 中的名称
+const DefaultBusName = "default"
+
+// ErrBusRegistryClosed 总线注册表已关闭
+var ErrBusRegistryClosed = errors.New("messaging: bus registry is closed")
+
+// BusRegistry 具名事件总线注册表
+//
+// 根据 Config.Buses（以及 Provider 非空时的顶层配置，名为 DefaultBusName）
+// 在首次使用时通过工厂创建 EventBus，之后复用同一实例。并发安全。
+// 每个总线未设置的字段按 Provider 分节使用 DefaultConfig() 的值。
+type BusRegistry struct {
+	configs map[string]Config
+
+	mu      sync.Mutex
+	buses   map[string]EventBus
+	pending map[string]chan struct{} // 正在创建的总线，创建完成时关闭
+	closed  bool
+}
+
+// NewBusRegistry 创建总线注册表
+// 先校验配置（未注册的 Provider 在建立任何连接之前被拒绝），不会立即连接
+//
+// 使用示例：
+//
+//	registry, err := messaging.NewBusRegistry(config)
+//	defer registry.Close()
+//
+//	inbound, err := registry.Bus("inbound")   // NSQ
+//	outbound, err := registry.Bus("outbound") // RabbitMQ
+func NewBusRegistry(config *Config) (*BusRegistry, error) {
+	if config == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	configs := make(map[string]Config, len(config.Buses)+1)
+	for name, bus := range config.Buses {
+		configs[name] = bus.withDefaults()
+	}
+	if config.Provider != "" {
+		if _, exists := configs[DefaultBusName]; exists {
+			return nil, fmt.Errorf("bus name %q is reserved for the top-level provider", DefaultBusName)
+		}
+		top := config.withDefaults()
+		top.Buses = nil
+		configs[DefaultBusName] = top
+	}
+
+	return &BusRegistry{
+		configs: configs,
+		buses:   make(map[string]EventBus),
+		pending: make(map[string]chan struct{}),
+	}, nil
+}
+
+// Bus 获取具名事件总线，首次调用时创建
+// 创建（连接 broker）时不持有注册表锁，一个总线连接缓慢不会阻塞其他总线与 Health；
+// 同一总线的并发调用等待同一次创建，创建失败后下次调用重新创建
+func (r *BusRegistry) Bus(name string) (EventBus, error) {
+	for {
+		r.mu.Lock()
+		if r.closed {
+			r.mu.Unlock()
+			return nil, ErrBusRegistryClosed
+		}
+		if bus, ok := r.buses[name]; ok {
+			r.mu.Unlock()
+			return bus, nil
+		}
+		config, ok := r.configs[name]
+		if !ok {
+			r.mu.Unlock()
+			return nil, fmt.Errorf("unknown bus %s (available: %v)", name, r.Names())
+		}
+		if wait, creating := r.pending[name]; creating {
+			r.mu.Unlock()
+			<-wait
+			continue
+		}
+		done := make(chan struct{})
+		r.pending[name] = done
+		r.mu.Unlock()
+
+		return r.create(name, config, done)
+	}
+}
+
+// create 在锁外创建总线，完成后登记并唤醒等待者
+func (r *BusRegistry) create(name string, config Config, done chan struct{}) (EventBus, error) {
+	bus, err := NewEventBus(&config)
+
+	r.mu.Lock()
+	delete(r.pending, name)
+	close(done)
+	if err != nil {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("create bus %s: %w", name, err)
+	}
+	if r.closed {
+		r.mu.Unlock()
+		_ = bus.Close()
+		return nil, ErrBusRegistryClosed
+	}
+	r.buses[name] = bus
+	r.mu.Unlock()
+	return bus, nil
+}
+
+// MustBus 获取具名事件总线，失败则 panic
+// 适用于启动阶段，配置错误时快速失败
+func (r *BusRegistry) MustBus(name string) EventBus {
+	bus, err := r.Bus(name)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create event bus: %v", err))
+	}
+	return bus
+}
+
+// Names 返回已配置的总线名称（有序）
+func (r *BusRegistry) Names() []string {
+	names := make([]string, 0, len(r.configs))
+	for name := range r.configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Health 检查所有已创建的总线，返回按名称标注的聚合错误；尚未创建的总线不参与检查
+// 在锁外逐个检查，不阻塞 Bus
+func (r *BusRegistry) Health() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return ErrBusRegistryClosed
+	}
+	buses := r.snapshotLocked()
+	r.mu.Unlock()
+
+	var errs []error
+	for _, name := range r.Names() {
+		bus, ok := buses[name]
+		if !ok {
+			continue
+		}
+		if err := bus.Health(); err != nil {
+			errs = append(errs, fmt.Errorf("bus %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close 关闭所有已创建的总线，返回聚合错误
+// 正在创建的总线在创建完成后关闭
+func (r *BusRegistry) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	buses := r.snapshotLocked()
+	r.buses = nil
+	r.mu.Unlock()
+
+	var errs []error
+	for _, name := range r.Names() {
+		bus, ok := buses[name]
+		if !ok {
+			continue
+		}
+		if err := bus.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("bus %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *BusRegistry) snapshotLocked() map[string]EventBus {
+	buses := make(map[string]EventBus, len(r.buses))
+	for name, bus := range r.buses {
+		buses[name] = bus
+	}
+	return buses
+}
@@ -0,0 +1,258 @@
+package messaging
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const providerRegistryTest Provider = "registry-test"
+
+// registryTestBuses 按 NSQdAddr 记录测试 Provider 创建的总线
+var registryTestBuses = struct {
+	sync.Mutex
+	created map[string]*registryTestBus
+}{created: make(map[string]*registryTestBus)}
+
+// registryTestDialing 地址为 "slow" 的总线在创建时阻塞，直到通道关闭
+var (
+	registryTestDialing = make(chan struct{})
+	registryTestRelease = make(chan struct{})
+)
+
+type registryTestBus struct {
+	*loopbackBus
+	health error
+	closed bool
+}
+
+func (b *registryTestBus) Health() error { return b.health }
+
+func (b *registryTestBus) Close() error {
+	b.closed = true
+	return nil
+}
+
+func init() {
+	RegisterProvider(providerRegistryTest, func(config *Config) (EventBus, error) {
+		if config.NSQ.NSQdAddr == "unreachable" {
+			return nil, errors.New("dial failed")
+		}
+		if config.NSQ.NSQdAddr == "slow" {
+			close(registryTestDialing)
+			<-registryTestRelease
+		}
+		bus := &registryTestBus{loopbackBus: newLoopbackBus()}
+		if config.NSQ.NSQdAddr == "unhealthy" {
+			bus.health = errors.New("connection lost")
+		}
+		registryTestBuses.Lock()
+		registryTestBuses.created[config.NSQ.NSQdAddr] = bus
+		registryTestBuses.Unlock()
+		return bus, nil
+	})
+}
+
+func registryTestConfig(addr string) Config {
+	return Config{Provider: providerRegistryTest, NSQ: NSQConfig{NSQdAddr: addr}}
+}
+
+func TestConfigValidateRejectsUnknownProviders(t *testing.T) {
+	tests := map[string]*Config{
+		"empty":          {},
+		"unknown top":    {Provider: "kafka"},
+		"unknown bus":    {Buses: map[string]Config{"outbound": {Provider: "kafka"}}},
+		"bus w/o driver": {Buses: map[string]Config{"outbound": {}}},
+		"nested buses": {Buses: map[string]Config{"outbound": {
+			Provider: providerRegistryTest,
+			Buses:    map[string]Config{"inner": registryTestConfig("x")},
+		}}},
+	}
+	for name, config := range tests {
+		if err := config.Validate(); err == nil {
+			t.Fatalf("%s: Validate should fail", name)
+		}
+		if _, err := NewBusRegistry(config); err == nil {
+			t.Fatalf("%s: NewBusRegistry should fail", name)
+		}
+	}
+
+	valid := &Config{Buses: map[string]Config{"outbound": registryTestConfig("x")}}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+}
+
+func TestBusRegistryCreatesBusesLazily(t *testing.T) {
+	config := registryTestConfig("lazy-default")
+	config.Buses = map[string]Config{
+		"inbound":  registryTestConfig("lazy-inbound"),
+		"outbound": registryTestConfig("lazy-outbound"),
+	}
+	registry, err := NewBusRegistry(&config)
+	if err != nil {
+		t.Fatalf("NewBusRegistry: %v", err)
+	}
+	if names := strings.Join(registry.Names(), ","); names != "default,inbound,outbound" {
+		t.Fatalf("Names = %s", names)
+	}
+
+	registryTestBuses.Lock()
+	_, created := registryTestBuses.created["lazy-inbound"]
+	registryTestBuses.Unlock()
+	if created {
+		t.Fatalf("bus created before first use")
+	}
+
+	first, err := registry.Bus("inbound")
+	if err != nil {
+		t.Fatalf("Bus: %v", err)
+	}
+	second := registry.MustBus("inbound")
+	if first != second {
+		t.Fatalf("Bus should return the same instance")
+	}
+	if _, err := registry.Bus(DefaultBusName); err != nil {
+		t.Fatalf("Bus(default): %v", err)
+	}
+	if _, err := registry.Bus("missing"); err == nil {
+		t.Fatalf("unknown bus should fail")
+	}
+
+	if err := registry.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	registryTestBuses.Lock()
+	defer registryTestBuses.Unlock()
+	if !registryTestBuses.created["lazy-inbound"].closed || !registryTestBuses.created["lazy-default"].closed {
+		t.Fatalf("Close should close every created bus")
+	}
+	if _, ok := registryTestBuses.created["lazy-outbound"]; ok {
+		t.Fatalf("unused bus should never be created")
+	}
+	if _, err := registry.Bus("inbound"); !errors.Is(err, ErrBusRegistryClosed) {
+		t.Fatalf("Bus after Close = %v, want ErrBusRegistryClosed", err)
+	}
+}
+
+func TestBusRegistryAppliesDefaultsPerProviderSection(t *testing.T) {
+	config := Config{Buses: map[string]Config{
+		"inbound": registryTestConfig("defaults-inbound"),
+		"outbound": {
+			Provider: providerRegistryTest,
+			RabbitMQ: RabbitMQConfig{URL: "amqp://broker/", ExchangeType: "topic", PrefetchCount: 10},
+		},
+	}}
+	registry, err := NewBusRegistry(&config)
+	if err != nil {
+		t.Fatalf("NewBusRegistry: %v", err)
+	}
+	defaults := DefaultConfig()
+
+	inbound := registry.configs["inbound"].NSQ
+	if inbound.NSQdAddr != "defaults-inbound" {
+		t.Fatalf("NSQdAddr = %q, explicit value should be kept", inbound.NSQdAddr)
+	}
+	if inbound.ReadTimeout != defaults.NSQ.ReadTimeout || inbound.MaxInFlight != defaults.NSQ.MaxInFlight {
+		t.Fatalf("NSQ section = %+v, want defaults for unset fields", inbound)
+	}
+
+	outbound := registry.configs["outbound"].RabbitMQ
+	if outbound.URL != "amqp://broker/" || outbound.ExchangeType != "topic" || outbound.PrefetchCount != 10 {
+		t.Fatalf("RabbitMQ section = %+v, explicit values should be kept", outbound)
+	}
+	if !outbound.AutoReconnect || outbound.ReconnectDelay != defaults.RabbitMQ.ReconnectDelay || outbound.DelayMode != defaults.RabbitMQ.DelayMode {
+		t.Fatalf("RabbitMQ section = %+v, want defaults for unset fields", outbound)
+	}
+	if outbound.Host != "" {
+		t.Fatalf("Host = %q, connection fields should not be defaulted", outbound.Host)
+	}
+
+	if config.Buses["inbound"].NSQ.ReadTimeout != 0 {
+		t.Fatalf("NewBusRegistry should not modify the caller's config")
+	}
+}
+
+func TestBusRegistryDefaultsURLOnlyRabbitMQBusToDurable(t *testing.T) {
+	registry, err := NewBusRegistry(&Config{Buses: map[string]Config{
+		"outbound": {Provider: providerRegistryTest, RabbitMQ: RabbitMQConfig{URL: "amqp://broker/"}},
+		"transient": {Provider: providerRegistryTest, RabbitMQ: RabbitMQConfig{
+			URL: "amqp://broker/", Durable: Bool(false), PersistentMessages: Bool(false),
+		}},
+	}})
+	if err != nil {
+		t.Fatalf("NewBusRegistry: %v", err)
+	}
+
+	outbound := registry.configs["outbound"].RabbitMQ
+	if !outbound.IsDurable() || !outbound.IsPersistentMessages() || outbound.Durable == nil || outbound.PersistentMessages == nil {
+		t.Fatalf("URL-only RabbitMQ bus: Durable=%v PersistentMessages=%v, want true", outbound.Durable, outbound.PersistentMessages)
+	}
+	transient := registry.configs["transient"].RabbitMQ
+	if transient.IsDurable() || transient.IsPersistentMessages() {
+		t.Fatalf("explicit false should be kept")
+	}
+}
+
+func TestBusRegistryCreatesBusesOutsideLock(t *testing.T) {
+	registry, err := NewBusRegistry(&Config{Buses: map[string]Config{
+		"slow":  registryTestConfig("slow"),
+		"other": registryTestConfig("outside-lock"),
+	}})
+	if err != nil {
+		t.Fatalf("NewBusRegistry: %v", err)
+	}
+	defer registry.Close()
+
+	slow := make(chan error, 1)
+	go func() {
+		_, err := registry.Bus("slow")
+		slow <- err
+	}()
+	<-registryTestDialing
+
+	// 慢总线仍在连接时，其他总线与 Health 不受阻塞
+	if _, err := registry.Bus("other"); err != nil {
+		t.Fatalf("Bus(other) while another bus is dialing: %v", err)
+	}
+	if err := registry.Health(); err != nil {
+		t.Fatalf("Health while another bus is dialing: %v", err)
+	}
+
+	close(registryTestRelease)
+	if err := <-slow; err != nil {
+		t.Fatalf("Bus(slow): %v", err)
+	}
+}
+
+func TestBusRegistryAggregatesHealth(t *testing.T) {
+	registry, err := NewBusRegistry(&Config{Buses: map[string]Config{
+		"healthy":   registryTestConfig("health-ok"),
+		"unhealthy": registryTestConfig("unhealthy"),
+		"broken":    registryTestConfig("unreachable"),
+	}})
+	if err != nil {
+		t.Fatalf("NewBusRegistry: %v", err)
+	}
+	defer registry.Close()
+
+	if _, err := registry.Bus("broken"); err == nil || !strings.Contains(err.Error(), "bus broken") {
+		t.Fatalf("Bus(broken) = %v", err)
+	}
+	registry.MustBus("healthy")
+	registry.MustBus("unhealthy")
+
+	err = registry.Health()
+	if err == nil || !strings.Contains(err.Error(), "bus unhealthy: connection lost") || strings.Contains(err.Error(), "bus healthy:") {
+		t.Fatalf("Health = %v", err)
+	}
+}
+
+func TestBusRegistryReservesDefaultName(t *testing.T) {
+	config := registryTestConfig("reserved")
+	config.Buses = map[string]Config{DefaultBusName: registryTestConfig("reserved-2")}
+	if _, err := NewBusRegistry(&config); err == nil {
+		t.Fatalf("a named bus must not shadow the top-level provider")
+	}
+}
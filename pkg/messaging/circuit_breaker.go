@@ -0,0 +1,412 @@
+package messaging
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// ========== 滑动窗口熔断器 ==========
+
+// 熔断器状态（CircuitBreaker.State 的返回值）
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// ErrCircuitOpen 熔断器打开（或半开状态的探测名额已满），调用被拒绝
+var ErrCircuitOpen = errors.New("messaging: circuit breaker is open")
+
+// ErrPauseNotSupported 订阅者不支持暂停拉取
+var ErrPauseNotSupported = errors.New("messaging: subscriber does not support pause")
+
+// BreakerWindowType 滑动窗口类型
+type BreakerWindowType string
+
+const (
+	// WindowCountBased 统计最近 WindowSize 次调用
+	WindowCountBased BreakerWindowType = "count"
+	// WindowTimeBased 统计最近 WindowDuration 内的调用
+	WindowTimeBased BreakerWindowType = "time"
+)
+
+// timeWindowBuckets 时间窗口的分桶数，过期以桶为粒度
+const timeWindowBuckets = 10
+
+// BreakerConfig 滑动窗口熔断器配置
+type BreakerConfig struct {
+	// WindowType 窗口类型（默认 WindowCountBased）
+	WindowType BreakerWindowType
+
+	// WindowSize 计数窗口的调用数（默认 100）
+	WindowSize int
+
+	// WindowDuration 时间窗口的长度（默认 60s）
+	WindowDuration time.Duration
+
+	// MinimumCalls 窗口内至少有这么多次调用才计算失败率（默认 10）
+	MinimumCalls int
+
+	// FailureRateThreshold 失败率阈值，取值 (0, 1]（默认 0.5），达到时打开熔断器
+	FailureRateThreshold float64
+
+	// OpenTimeout 打开状态的持续时间（默认 30s），之后进入半开状态
+	OpenTimeout time.Duration
+
+	// HalfOpenProbes 半开状态放行的探测调用数（默认 3）
+	// 全部成功则关闭熔断器，任一失败则重新打开
+	HalfOpenProbes int
+
+	// IsFailure 判断调用结果是否计为失败（默认 err != nil）
+	IsFailure func(error) bool
+}
+
+// StateListener 熔断器状态变化监听器
+type StateListener func(from, to string)
+
+// SlidingWindowCircuitBreaker 基于滑动窗口失败率的熔断器，实现 CircuitBreaker 接口
+//
+// 状态转换：
+//   - closed → open：窗口内调用数达到 MinimumCalls 且失败率达到阈值
+//   - open → half-open：OpenTimeout 到期（由定时器触发，即使没有新的调用）
+//   - half-open → closed：HalfOpenProbes 次探测全部成功
+//   - half-open → open：任一探测失败
+//
+// 状态变化按发生顺序通知监听器，可用于暂停/恢复消费（见 PauseOnOpen）。
+// 并发安全。
+type SlidingWindowCircuitBreaker struct {
+	cfg BreakerConfig
+
+	mu         sync.Mutex
+	state      string
+	generation uint64 // 每次状态变化递增，丢弃跨状态完成的调用结果
+	window     breakerWindow
+	openedAt   time.Time
+	probes     int // 半开状态已放行的探测数
+	successes  int // 半开状态成功的探测数
+	events     []stateChange
+
+	notifyMu  sync.Mutex
+	listeners []StateListener
+}
+
+type stateChange struct {
+	from, to string
+}
+
+// NewSlidingWindowCircuitBreaker 创建滑动窗口熔断器
+//
+// 使用示例：
+//
+//	breaker := messaging.NewSlidingWindowCircuitBreaker(messaging.BreakerConfig{
+//	    WindowType:     messaging.WindowTimeBased,
+//	    WindowDuration: 30 * time.Second,
+//	    OpenTimeout:    10 * time.Second,
+//	})
+//	router.AddHandlerWithMiddleware("order.paid", "billing", handler,
+//	    messaging.CircuitBreakerMiddleware(breaker))
+//	err := messaging.PauseOnOpen(breaker, bus.Subscriber(), "order.paid", "billing")
+func NewSlidingWindowCircuitBreaker(cfg BreakerConfig) *SlidingWindowCircuitBreaker {
+	if cfg.WindowType == "" {
+		cfg.WindowType = WindowCountBased
+	}
+	if cfg.WindowSize <= 0 {
+		cfg.WindowSize = 100
+	}
+	if cfg.WindowDuration <= 0 {
+		cfg.WindowDuration = time.Minute
+	}
+	if cfg.MinimumCalls <= 0 {
+		cfg.MinimumCalls = 10
+	}
+	if cfg.FailureRateThreshold <= 0 || cfg.FailureRateThreshold > 1 {
+		cfg.FailureRateThreshold = 0.5
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 30 * time.Second
+	}
+	if cfg.HalfOpenProbes <= 0 {
+		cfg.HalfOpenProbes = 3
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = func(err error) bool { return err != nil }
+	}
+
+	cb := &SlidingWindowCircuitBreaker{cfg: cfg, state: CircuitClosed}
+	if cfg.WindowType == WindowTimeBased {
+		cb.window = newTimeWindow(cfg.WindowDuration)
+	} else {
+		cb.window = newCountWindow(cfg.WindowSize)
+	}
+	return cb
+}
+
+// OnStateChange 注册状态变化监听器
+// 监听器按状态变化的顺序串行调用，不应在监听器内调用 Call
+func (cb *SlidingWindowCircuitBreaker) OnStateChange(listener StateListener) {
+	cb.notifyMu.Lock()
+	defer cb.notifyMu.Unlock()
+	cb.listeners = append(cb.listeners, listener)
+}
+
+// Call 实现 CircuitBreaker 接口
+// 熔断器打开时不执行 fn，返回 ErrCircuitOpen；fn panic 时计为失败
+func (cb *SlidingWindowCircuitBreaker) Call(fn func() error) error {
+	generation, err := cb.acquire()
+	if err != nil {
+		return err
+	}
+
+	failed := true
+	defer func() {
+		cb.record(generation, failed)
+	}()
+	err = fn()
+	failed = cb.cfg.IsFailure(err)
+	return err
+}
+
+// State 实现 CircuitBreaker 接口
+func (cb *SlidingWindowCircuitBreaker) State() string {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.state
+}
+
+// acquire 判断是否放行调用，返回放行时的状态代数
+func (cb *SlidingWindowCircuitBreaker) acquire() (uint64, error) {
+	cb.mu.Lock()
+	defer cb.dispatch()
+	defer cb.mu.Unlock()
+
+	if cb.state == CircuitOpen {
+		// 定时器可能尚未触发
+		if time.Since(cb.openedAt) < cb.cfg.OpenTimeout {
+			return 0, ErrCircuitOpen
+		}
+		cb.transitionLocked(CircuitHalfOpen)
+	}
+	if cb.state == CircuitHalfOpen {
+		if cb.probes >= cb.cfg.HalfOpenProbes {
+			return 0, ErrCircuitOpen
+		}
+		cb.probes++
+	}
+	return cb.generation, nil
+}
+
+// record 记录调用结果
+func (cb *SlidingWindowCircuitBreaker) record(generation uint64, failed bool) {
+	cb.mu.Lock()
+	defer cb.dispatch()
+	defer cb.mu.Unlock()
+
+	if generation != cb.generation {
+		return
+	}
+
+	switch cb.state {
+	case CircuitClosed:
+		now := time.Now()
+		cb.window.record(failed, now)
+		calls, failures := cb.window.counts(now)
+		if calls >= cb.cfg.MinimumCalls && float64(failures) >= cb.cfg.FailureRateThreshold*float64(calls) {
+			cb.transitionLocked(CircuitOpen)
+		}
+	case CircuitHalfOpen:
+		if failed {
+			cb.transitionLocked(CircuitOpen)
+			return
+		}
+		cb.successes++
+		if cb.successes >= cb.cfg.HalfOpenProbes {
+			cb.transitionLocked(CircuitClosed)
+		}
+	}
+}
+
+// transitionLocked 切换状态并记录待通知的变化，调用方持有 mu
+func (cb *SlidingWindowCircuitBreaker) transitionLocked(to string) {
+	from := cb.state
+	cb.state = to
+	cb.generation++
+	cb.probes = 0
+	cb.successes = 0
+	cb.window.reset()
+
+	if to == CircuitOpen {
+		cb.openedAt = time.Now()
+		generation := cb.generation
+		time.AfterFunc(cb.cfg.OpenTimeout, func() {
+			cb.expire(generation)
+		})
+	}
+	cb.events = append(cb.events, stateChange{from: from, to: to})
+}
+
+// expire 打开状态到期后进入半开状态
+func (cb *SlidingWindowCircuitBreaker) expire(generation uint64) {
+	cb.mu.Lock()
+	defer cb.dispatch()
+	defer cb.mu.Unlock()
+
+	if cb.generation == generation && cb.state == CircuitOpen {
+		cb.transitionLocked(CircuitHalfOpen)
+	}
+}
+
+// dispatch 按顺序通知待处理的状态变化（不持有 mu）
+func (cb *SlidingWindowCircuitBreaker) dispatch() {
+	cb.notifyMu.Lock()
+	defer cb.notifyMu.Unlock()
+
+	for {
+		cb.mu.Lock()
+		events := cb.events
+		cb.events = nil
+		cb.mu.Unlock()
+		if len(events) == 0 {
+			return
+		}
+
+		for _, event := range events {
+			for _, listener := range cb.listeners {
+				listener(event.from, event.to)
+			}
+		}
+	}
+}
+
+// breakerWindow 滑动窗口：记录调用结果并统计窗口内的调用数与失败数
+type breakerWindow interface {
+	record(failed bool, now time.Time)
+	counts(now time.Time) (calls, failures int)
+	reset()
+}
+
+// countWindow 最近 N 次调用的环形缓冲
+type countWindow struct {
+	outcomes []bool
+	next     int
+	filled   int
+	failures int
+}
+
+func newCountWindow(size int) *countWindow {
+	return &countWindow{outcomes: make([]bool, size)}
+}
+
+func (w *countWindow) record(failed bool, _ time.Time) {
+	if w.filled == len(w.outcomes) {
+		if w.outcomes[w.next] {
+			w.failures--
+		}
+	} else {
+		w.filled++
+	}
+	w.outcomes[w.next] = failed
+	if failed {
+		w.failures++
+	}
+	w.next = (w.next + 1) % len(w.outcomes)
+}
+
+func (w *countWindow) counts(time.Time) (int, int) {
+	return w.filled, w.failures
+}
+
+func (w *countWindow) reset() {
+	w.next, w.filled, w.failures = 0, 0, 0
+}
+
+// timeWindow 按时间分桶的滑动窗口
+type timeWindow struct {
+	width   int64 // 每个桶的纳秒数
+	buckets [timeWindowBuckets]timeBucket
+}
+
+type timeBucket struct {
+	epoch    int64 // 桶的序号（时间 / 桶宽）
+	calls    int
+	failures int
+}
+
+func newTimeWindow(duration time.Duration) *timeWindow {
+	width := int64(duration) / timeWindowBuckets
+	if width <= 0 {
+		width = 1
+	}
+	return &timeWindow{width: width}
+}
+
+func (w *timeWindow) record(failed bool, now time.Time) {
+	epoch := now.UnixNano() / w.width
+	b := &w.buckets[epoch%timeWindowBuckets]
+	if b.epoch != epoch {
+		*b = timeBucket{epoch: epoch}
+	}
+	b.calls++
+	if failed {
+		b.failures++
+	}
+}
+
+func (w *timeWindow) counts(now time.Time) (calls, failures int) {
+	epoch := now.UnixNano() / w.width
+	for _, b := range w.buckets {
+		if b.epoch > epoch-timeWindowBuckets && b.epoch <= epoch {
+			calls += b.calls
+			failures += b.failures
+		}
+	}
+	return calls, failures
+}
+
+func (w *timeWindow) reset() {
+	w.buckets = [timeWindowBuckets]timeBucket{}
+}
+
+// ========== 熔断暂停消费 ==========
+
+// PausableSubscriber 支持暂停拉取的订阅者
+//
+// Pause 只停止从 broker 拉取新消息，不等待处理中的消息，可以在处理器内调用；
+// 暂停期间订阅保持注册，Resume 恢复拉取。对未暂停的订阅调用 Resume 不做任何操作。
+//   - nsq：ChangeMaxInFlight(0) / 恢复为配置的 MaxInFlight
+//   - rabbitmq：取消 consumer tag / 重新 Consume
+//   - redisstream：停止 / 重新启动消费循环
+type PausableSubscriber interface {
+	Pause(topic, channel string) error
+	Resume(topic, channel string) error
+}
+
+// PauseOnOpen 熔断器打开时暂停 topic/channel 的拉取，进入半开状态时恢复
+//
+// 熔断器打开后 CircuitBreakerMiddleware 拒绝的消息会被 Nack 重新入队，
+// 暂停拉取避免这些消息在熔断期间被反复投递；半开状态超出探测名额的消息仍会被拒绝重投。
+// 订阅者未实现 PausableSubscriber 时返回 ErrPauseNotSupported
+func PauseOnOpen(breaker *SlidingWindowCircuitBreaker, subscriber Subscriber, topic, channel string) error {
+	ps, ok := subscriber.(PausableSubscriber)
+	if !ok {
+		return fmt.Errorf("%w: %T", ErrPauseNotSupported, subscriber)
+	}
+
+	breaker.OnStateChange(func(from, to string) {
+		var err error
+		switch to {
+		case CircuitOpen:
+			err = ps.Pause(topic, channel)
+		case CircuitHalfOpen:
+			err = ps.Resume(topic, channel)
+		default:
+			return
+		}
+		if err != nil {
+			log.Printf("[messaging] circuit breaker %s -> %s: toggle %s:%s failed: %v", from, to, topic, channel, err)
+		}
+	})
+	return nil
+}
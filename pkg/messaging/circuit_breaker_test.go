@@ -0,0 +1,296 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errDownstream = errors.New("downstream unavailable")
+
+type fakePausableSubscriber struct {
+	fakeSubscriber
+	calls chan string
+}
+
+func (s *fakePausableSubscriber) Pause(topic, channel string) error {
+	s.calls <- "pause " + topic + ":" + channel
+	return nil
+}
+
+func (s *fakePausableSubscriber) Resume(topic, channel string) error {
+	s.calls <- "resume " + topic + ":" + channel
+	return nil
+}
+
+// stateRecorder 记录熔断器的状态变化
+type stateRecorder struct {
+	mu      sync.Mutex
+	changes []string
+}
+
+func (r *stateRecorder) listen(from, to string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.changes = append(r.changes, from+"->"+to)
+}
+
+func (r *stateRecorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.changes...)
+}
+
+func callN(cb CircuitBreaker, n int, err error) {
+	for i := 0; i < n; i++ {
+		_ = cb.Call(func() error { return err })
+	}
+}
+
+func waitState(t *testing.T, cb CircuitBreaker, want string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for cb.State() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("state = %s, want %s", cb.State(), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSlidingWindowBreakerOpensOnFailureRate(t *testing.T) {
+	cb := NewSlidingWindowCircuitBreaker(BreakerConfig{
+		WindowSize:           10,
+		MinimumCalls:         4,
+		FailureRateThreshold: 0.5,
+		OpenTimeout:          time.Hour,
+	})
+
+	callN(cb, 1, errDownstream)
+	callN(cb, 2, nil)
+	if cb.State() != CircuitClosed {
+		t.Fatalf("breaker opened before MinimumCalls")
+	}
+	callN(cb, 1, errDownstream) // 2/4 失败
+	if cb.State() != CircuitOpen {
+		t.Fatalf("state = %s, want open at 50%% failure rate", cb.State())
+	}
+
+	called := false
+	err := cb.Call(func() error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, ErrCircuitOpen) || called {
+		t.Fatalf("Call while open = %v, called=%v", err, called)
+	}
+}
+
+func TestSlidingWindowBreakerCountWindowSlides(t *testing.T) {
+	cb := NewSlidingWindowCircuitBreaker(BreakerConfig{
+		WindowSize:           4,
+		MinimumCalls:         4,
+		FailureRateThreshold: 0.75,
+	})
+
+	callN(cb, 2, errDownstream)
+	callN(cb, 4, nil) // 早期的失败滑出窗口
+	callN(cb, 2, errDownstream)
+	if cb.State() != CircuitClosed {
+		t.Fatalf("state = %s, want closed with 2/4 failures", cb.State())
+	}
+	callN(cb, 1, errDownstream)
+	if cb.State() != CircuitOpen {
+		t.Fatalf("state = %s, want open with 3/4 failures", cb.State())
+	}
+}
+
+func TestSlidingWindowBreakerTimeWindowExpires(t *testing.T) {
+	cb := NewSlidingWindowCircuitBreaker(BreakerConfig{
+		WindowType:     WindowTimeBased,
+		WindowDuration: 50 * time.Millisecond,
+		MinimumCalls:   3,
+	})
+
+	callN(cb, 2, errDownstream)
+	time.Sleep(70 * time.Millisecond)
+	callN(cb, 1, errDownstream)
+	if cb.State() != CircuitClosed {
+		t.Fatalf("failures outside the time window were counted")
+	}
+	callN(cb, 2, errDownstream)
+	if cb.State() != CircuitOpen {
+		t.Fatalf("state = %s, want open", cb.State())
+	}
+}
+
+func TestSlidingWindowBreakerHalfOpenProbes(t *testing.T) {
+	rec := &stateRecorder{}
+	cb := NewSlidingWindowCircuitBreaker(BreakerConfig{
+		WindowSize:     2,
+		MinimumCalls:   2,
+		OpenTimeout:    20 * time.Millisecond,
+		HalfOpenProbes: 2,
+	})
+	cb.OnStateChange(rec.listen)
+
+	callN(cb, 2, errDownstream)
+	waitState(t, cb, CircuitHalfOpen) // 定时器触发，无需新的调用
+
+	// 半开状态只放行 HalfOpenProbes 个探测
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = cb.Call(func() error {
+				<-release
+				return nil
+			})
+		}()
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		if err := cb.Call(func() error { return nil }); errors.Is(err, ErrCircuitOpen) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("extra calls were admitted while probes are in flight")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	if cb.State() != CircuitClosed {
+		t.Fatalf("state = %s, want closed after successful probes", cb.State())
+	}
+
+	callN(cb, 2, errDownstream)
+	waitState(t, cb, CircuitHalfOpen)
+	callN(cb, 1, errDownstream)
+	if cb.State() != CircuitOpen {
+		t.Fatalf("state = %s, want open after a failed probe", cb.State())
+	}
+
+	want := []string{
+		"closed->open", "open->half-open", "half-open->closed",
+		"closed->open", "open->half-open", "half-open->open",
+	}
+	got := rec.get()
+	if len(got) != len(want) {
+		t.Fatalf("state changes = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("state changes = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestSlidingWindowBreakerCountsPanicsAsFailures(t *testing.T) {
+	cb := NewSlidingWindowCircuitBreaker(BreakerConfig{WindowSize: 1, MinimumCalls: 1, OpenTimeout: time.Hour})
+
+	func() {
+		defer func() { _ = recover() }()
+		_ = cb.Call(func() error { panic("boom") })
+	}()
+	if cb.State() != CircuitOpen {
+		t.Fatalf("state = %s, want open after a panic", cb.State())
+	}
+}
+
+func TestPauseOnOpen(t *testing.T) {
+	cb := NewSlidingWindowCircuitBreaker(BreakerConfig{
+		WindowSize:   1,
+		MinimumCalls: 1,
+		OpenTimeout:  20 * time.Millisecond,
+	})
+	sub := &fakePausableSubscriber{calls: make(chan string, 4)}
+	if err := PauseOnOpen(cb, sub, "orders", "billing"); err != nil {
+		t.Fatalf("PauseOnOpen: %v", err)
+	}
+
+	handler := CircuitBreakerMiddleware(cb)(func(ctx context.Context, msg *Message) error {
+		return errDownstream
+	})
+	_ = handler(context.Background(), NewMessage("m1", nil))
+
+	for _, want := range []string{"pause orders:billing", "resume orders:billing"} {
+		select {
+		case got := <-sub.calls:
+			if got != want {
+				t.Fatalf("subscriber call = %q, want %q", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("missing subscriber call %q", want)
+		}
+	}
+
+	if err := PauseOnOpen(cb, &fakeSubscriber{}, "orders", "billing"); !errors.Is(err, ErrPauseNotSupported) {
+		t.Fatalf("error = %v, want ErrPauseNotSupported", err)
+	}
+}
+
+// switchBreaker 手动控制开关的熔断器
+type switchBreaker struct {
+	open bool
+}
+
+func (b *switchBreaker) Call(fn func() error) error {
+	if b.open {
+		return ErrCircuitOpen
+	}
+	return fn()
+}
+
+func (b *switchBreaker) State() string {
+	if b.open {
+		return CircuitOpen
+	}
+	return CircuitClosed
+}
+
+func TestDeadLetterIgnoresCircuitBreakerRejections(t *testing.T) {
+	dlq := &fakePublisher{}
+	breaker := &switchBreaker{open: true}
+	calls := 0
+	handler := DeadLetterMiddleware(DeadLetterPolicy{MaxAttempts: 2, Publisher: dlq})(
+		CircuitBreakerMiddleware(breaker)(func(ctx context.Context, msg *Message) error {
+			calls++
+			return errors.New("downstream failed")
+		}),
+	)
+
+	msg := NewMessage("msg-1", nil)
+	msg.Topic = "orders"
+	for attempt := uint16(1); attempt <= 5; attempt++ {
+		msg.Attempts = attempt
+		err := handler(context.Background(), msg)
+		if !errors.Is(err, ErrCircuitOpen) || !IsTransient(err) {
+			t.Fatalf("attempt %d: error = %v, want transient ErrCircuitOpen", attempt, err)
+		}
+	}
+	if calls != 0 || len(dlq.messages()) != 0 {
+		t.Fatalf("calls=%d dead letters=%d, rejected messages must not be dead lettered", calls, len(dlq.messages()))
+	}
+
+	// 熔断恢复后，broker 投递次数中的拒绝不计入失败
+	breaker.open = false
+	msg.Attempts = 6
+	if err := handler(context.Background(), msg); err == nil || IsTransient(err) {
+		t.Fatalf("first real failure = %v, want handler error", err)
+	}
+	if len(dlq.messages()) != 0 {
+		t.Fatalf("dead lettered after one real failure")
+	}
+	msg.Attempts = 7
+	if err := handler(context.Background(), msg); err != nil {
+		t.Fatalf("second real failure = %v, want dead lettered", err)
+	}
+	if dead := dlq.messages(); len(dead) != 1 || dead[0].msg.Metadata[MetadataDLQAttempts] != "2" {
+		t.Fatalf("dead letters = %+v, want one after 2 processed attempts", dead)
+	}
+}
@@ -0,0 +1,280 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ========== Claim Check（大消息体外置存储） ==========
+
+// Claim Check 写入的 Metadata 键
+const (
+	// MetadataClaimCheck 外置消息体在 BlobStore 中的引用
+	MetadataClaimCheck = "claim_check"
+
+	// MetadataClaimCheckSize 外置消息体的字节数
+	MetadataClaimCheckSize = "claim_check_size"
+)
+
+// DefaultClaimCheckThreshold 默认外置阈值（256KB）
+const DefaultClaimCheckThreshold = 256 << 10
+
+// DefaultClaimCheckTTL 外置消息体默认保留时间
+const DefaultClaimCheckTTL = 7 * 24 * time.Hour
+
+var (
+	// ErrBlobNotFound BlobStore 中不存在（或已过期）的引用
+	ErrBlobNotFound = errors.New("messaging: blob not found")
+
+	// ErrClaimCheckMissing 消息引用的外置消息体已不存在（过期或被删除），重投也无法恢复
+	ErrClaimCheckMissing = errors.New("messaging: claim-checked payload is missing")
+)
+
+// BlobStore 外置消息体存储
+// 实现：FileBlobStore（本地/共享文件系统）、redisblob.Store（redis/store.ValueStore）
+type BlobStore interface {
+	// Put 写入数据，ttl 到期后可被清理
+	Put(ctx context.Context, key string, data []byte, ttl time.Duration) error
+
+	// Get 读取数据，不存在或已过期时返回 ErrBlobNotFound
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Delete 删除数据，不存在时不报错
+	Delete(ctx context.Context, key string) error
+}
+
+// ClaimCheckConfig Claim Check 配置
+type ClaimCheckConfig struct {
+	// Store 外置存储（必填），发布方与消费方必须访问同一存储
+	Store BlobStore
+
+	// Threshold 超过该字节数的消息体外置（默认 256KB）
+	Threshold int
+
+	// TTL 外置消息体的保留时间（默认 7 天），应大于消息的最长投递周期（含重试与死信重投）
+	TTL time.Duration
+
+	// DeleteAfterProcessing 消费成功后立即删除外置消息体（默认 false，依赖 TTL 清理）
+	// 仅适用于只有一个消费组的 topic，否则其他消费组将无法取回
+	DeleteAfterProcessing bool
+}
+
+func (c ClaimCheckConfig) withDefaults() ClaimCheckConfig {
+	if c.Store == nil {
+		panic("messaging: claim check store is nil")
+	}
+	if c.Threshold <= 0 {
+		c.Threshold = DefaultClaimCheckThreshold
+	}
+	if c.TTL <= 0 {
+		c.TTL = DefaultClaimCheckTTL
+	}
+	return c
+}
+
+// ClaimCheckPublisherMiddleware 发布侧 Claim Check 中间件
+// 消息体超过阈值时写入 BlobStore，只发送引用（Metadata claim_check）；发布失败时删除已写入的数据。
+// 不修改传入的消息
+//
+// 使用示例：
+//
+//	blobs := messaging.NewFileBlobStore("/mnt/shared/claim-check")
+//	publisher := messaging.WrapPublisher(bus.Publisher(),
+//	    messaging.ClaimCheckPublisherMiddleware(messaging.ClaimCheckConfig{Store: blobs}))
+func ClaimCheckPublisherMiddleware(cfg ClaimCheckConfig) PublisherMiddleware {
+	cfg = cfg.withDefaults()
+
+	return func(next PublishFunc) PublishFunc {
+		return func(ctx context.Context, topic string, msg *Message) error {
+			if len(msg.Payload) <= cfg.Threshold {
+				return next(ctx, topic, msg)
+			}
+
+			key := uuid.NewString()
+			if err := cfg.Store.Put(ctx, key, msg.Payload, cfg.TTL); err != nil {
+				return fmt.Errorf("failed to store claim-checked payload: %w", err)
+			}
+
+			md := make(map[string]string, len(msg.Metadata)+2)
+			for k, v := range msg.Metadata {
+				md[k] = v
+			}
+			md[MetadataClaimCheck] = key
+			md[MetadataClaimCheckSize] = strconv.Itoa(len(msg.Payload))
+
+			err := next(ctx, topic, &Message{
+				UUID:      msg.UUID,
+				Metadata:  md,
+				Attempts:  msg.Attempts,
+				Timestamp: msg.Timestamp,
+				Topic:     msg.Topic,
+				Channel:   msg.Channel,
+			})
+			if err != nil {
+				if delErr := cfg.Store.Delete(context.WithoutCancel(ctx), key); delErr != nil {
+					log.Printf("[messaging] delete claim-checked payload %s failed: %v", key, delErr)
+				}
+			}
+			return err
+		}
+	}
+}
+
+// ClaimCheckMiddleware 消费侧 Claim Check 中间件
+// 消息携带引用时从 BlobStore 取回消息体并写回 Payload，处理器无感知；
+// 外置消息体已不存在时返回 ErrClaimCheckMissing 永久错误（见 Permanent），
+// 外层的 DeadLetterMiddleware 第一次失败即转入死信，RetryMiddleware 不重试
+func ClaimCheckMiddleware(cfg ClaimCheckConfig) Middleware {
+	cfg = cfg.withDefaults()
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *Message) error {
+			key := msg.Metadata[MetadataClaimCheck]
+			if key == "" {
+				return next(ctx, msg)
+			}
+
+			payload, err := cfg.Store.Get(ctx, key)
+			if errors.Is(err, ErrBlobNotFound) {
+				return Permanent(fmt.Errorf("%w: %s", ErrClaimCheckMissing, key))
+			}
+			if err != nil {
+				return fmt.Errorf("failed to load claim-checked payload %s: %w", key, err)
+			}
+			msg.Payload = payload
+
+			if err := next(ctx, msg); err != nil {
+				return err
+			}
+			if cfg.DeleteAfterProcessing {
+				if err := cfg.Store.Delete(ctx, key); err != nil {
+					log.Printf("[messaging] delete claim-checked payload %s failed: %v", key, err)
+				}
+			}
+			return nil
+		}
+	}
+}
+
+// FileBlobStore 基于文件系统的 BlobStore
+//
+// 每个引用一个文件，过期时间记录在文件的修改时间上：过期的文件读取时视为不存在，
+// 由 Prune 清理。多实例部署时目录应位于共享存储上。
+type FileBlobStore struct {
+	dir string
+}
+
+// NewFileBlobStore 创建文件系统 BlobStore（目录不存在时在首次写入时创建）
+func NewFileBlobStore(dir string) *FileBlobStore {
+	return &FileBlobStore{dir: dir}
+}
+
+// Put 实现 BlobStore 接口
+// 先写临时文件再重命名，读取方不会看到写了一半的数据
+func (s *FileBlobStore) Put(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	if err := os.Chtimes(tmp.Name(), expiresAt, expiresAt); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Get 实现 BlobStore 接口
+func (s *FileBlobStore) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.ModTime().Before(time.Now()) {
+		return nil, ErrBlobNotFound
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	return data, err
+}
+
+// Delete 实现 BlobStore 接口
+func (s *FileBlobStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Prune 删除已过期的文件，返回删除的数量；应定期调用
+func (s *FileBlobStore) Prune() (int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	pruned := 0
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".tmp-") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(now) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.dir, entry.Name())); err == nil {
+			pruned++
+		}
+	}
+	return pruned, nil
+}
+
+func (s *FileBlobStore) path(key string) (string, error) {
+	if key == "" || key != filepath.Base(key) || strings.HasPrefix(key, ".") {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.dir, key), nil
+}
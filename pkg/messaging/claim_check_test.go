@@ -0,0 +1,154 @@
+package messaging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestClaimCheckPublisherStoresLargePayloads(t *testing.T) {
+	blobs := NewFileBlobStore(t.TempDir())
+	pub := &fakePublisher{}
+	wrapped := WrapPublisher(pub, ClaimCheckPublisherMiddleware(ClaimCheckConfig{Store: blobs, Threshold: 8}))
+	ctx := context.Background()
+
+	small := NewMessage("small", []byte("tiny"))
+	large := NewMessage("large", []byte("a very large document"))
+	large.Metadata[MetadataContentType] = ContentTypeJSON
+	for _, msg := range []*Message{small, large} {
+		if err := wrapped.PublishMessage(ctx, "document.uploaded", msg); err != nil {
+			t.Fatalf("PublishMessage: %v", err)
+		}
+	}
+	if _, ok := large.Metadata[MetadataClaimCheck]; ok {
+		t.Fatalf("claim check mutated the caller's message")
+	}
+
+	sent := pub.messages()
+	if string(sent[0].msg.Payload) != "tiny" || sent[0].msg.Metadata[MetadataClaimCheck] != "" {
+		t.Fatalf("small payload should be sent inline: %+v", sent[0].msg)
+	}
+	ref := sent[1].msg
+	if len(ref.Payload) != 0 || ref.UUID != "large" || ref.Metadata[MetadataContentType] != ContentTypeJSON {
+		t.Fatalf("large payload should be replaced by a reference: %+v", ref)
+	}
+	if ref.Metadata[MetadataClaimCheckSize] != "21" {
+		t.Fatalf("claim check size = %q", ref.Metadata[MetadataClaimCheckSize])
+	}
+	stored, err := blobs.Get(ctx, ref.Metadata[MetadataClaimCheck])
+	if err != nil || string(stored) != "a very large document" {
+		t.Fatalf("stored blob = %q, %v", stored, err)
+	}
+}
+
+func TestClaimCheckPublisherDeletesBlobWhenPublishFails(t *testing.T) {
+	dir := t.TempDir()
+	pub := &fakePublisher{err: errors.New("broker down")}
+	wrapped := WrapPublisher(pub, ClaimCheckPublisherMiddleware(ClaimCheckConfig{Store: NewFileBlobStore(dir), Threshold: 1}))
+
+	if err := wrapped.PublishMessage(context.Background(), "document.uploaded", NewMessage("m1", []byte("payload"))); err == nil {
+		t.Fatalf("publish error should be returned")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("orphaned blobs left behind: %d", len(entries))
+	}
+}
+
+func TestClaimCheckMiddlewareRehydratesPayload(t *testing.T) {
+	blobs := NewFileBlobStore(t.TempDir())
+	cfg := ClaimCheckConfig{Store: blobs, Threshold: 1, DeleteAfterProcessing: true}
+	pub := &fakePublisher{}
+	if err := WrapPublisher(pub, ClaimCheckPublisherMiddleware(cfg)).
+		PublishMessage(context.Background(), "document.uploaded", NewMessage("m1", []byte("document"))); err != nil {
+		t.Fatalf("PublishMessage: %v", err)
+	}
+	msg := pub.messages()[0].msg
+
+	fail := true
+	var received []byte
+	handler := ClaimCheckMiddleware(cfg)(func(ctx context.Context, msg *Message) error {
+		received = msg.Payload
+		if fail {
+			return errors.New("index unavailable")
+		}
+		return nil
+	})
+
+	// 处理失败时保留外置消息体，重投仍可取回
+	if err := handler(context.Background(), msg); err == nil {
+		t.Fatalf("handler error should be returned")
+	}
+	fail = false
+	if err := handler(context.Background(), msg); err != nil {
+		t.Fatalf("redelivery: %v", err)
+	}
+	if string(received) != "document" {
+		t.Fatalf("rehydrated payload = %q", received)
+	}
+
+	if err := handler(context.Background(), msg); !errors.Is(err, ErrClaimCheckMissing) || !IsPermanent(err) {
+		t.Fatalf("deleted blob = %v, want permanent ErrClaimCheckMissing", err)
+	}
+}
+
+func TestClaimCheckMiddlewareDeadLettersMissingBlobOnFirstAttempt(t *testing.T) {
+	dlq := &fakePublisher{}
+	calls := 0
+	handler := DeadLetterMiddleware(DeadLetterPolicy{MaxAttempts: 5, Publisher: dlq})(
+		ClaimCheckMiddleware(ClaimCheckConfig{Store: NewFileBlobStore(t.TempDir())})(func(ctx context.Context, msg *Message) error {
+			calls++
+			return nil
+		}),
+	)
+
+	msg := NewMessage("m1", nil)
+	msg.Topic = "document.uploaded"
+	msg.Metadata[MetadataClaimCheck] = "expired-blob"
+	if err := handler(context.Background(), msg); err != nil {
+		t.Fatalf("error = %v, want nil after dead lettering", err)
+	}
+	published := dlq.messages()
+	if calls != 0 || len(published) != 1 || published[0].topic != "document.uploaded.dlq" {
+		t.Fatalf("calls=%d published=%+v, want missing blob dead lettered on first attempt", calls, published)
+	}
+}
+
+func TestFileBlobStoreExpiresAndPrunes(t *testing.T) {
+	dir := t.TempDir()
+	blobs := NewFileBlobStore(filepath.Join(dir, "blobs"))
+	ctx := context.Background()
+
+	if err := blobs.Put(ctx, "fresh", []byte("a"), time.Hour); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := blobs.Put(ctx, "stale", []byte("b"), time.Hour); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	past := time.Now().Add(-time.Minute)
+	if err := os.Chtimes(filepath.Join(dir, "blobs", "stale"), past, past); err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+
+	if _, err := blobs.Get(ctx, "stale"); !errors.Is(err, ErrBlobNotFound) {
+		t.Fatalf("expired Get = %v, want ErrBlobNotFound", err)
+	}
+	if data, err := blobs.Get(ctx, "fresh"); err != nil || !bytes.Equal(data, []byte("a")) {
+		t.Fatalf("fresh Get = %q, %v", data, err)
+	}
+	if pruned, err := blobs.Prune(); err != nil || pruned != 1 {
+		t.Fatalf("Prune = %d, %v", pruned, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "blobs", "fresh")); err != nil {
+		t.Fatalf("fresh blob pruned: %v", err)
+	}
+
+	for _, key := range []string{"", "../escape", "a/b", ".hidden"} {
+		if err := blobs.Put(ctx, key, nil, time.Hour); err == nil {
+			t.Fatalf("key %q should be rejected", key)
+		}
+	}
+}
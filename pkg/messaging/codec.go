@@ -0,0 +1,224 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+)
+
+// ========== 消息编解码 ==========
+
+// MetadataContentType 消息负载的内容类型
+const MetadataContentType = "content-type"
+
+// 内置的内容类型
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+	ContentTypeMsgpack  = "application/msgpack"
+)
+
+// DefaultContentType 消息未携带 content-type 时使用的内容类型（兼容 PublishJSON 发布的消息）
+const DefaultContentType = ContentTypeJSON
+
+// ErrUnknownContentType 没有为消息的 content-type 注册编解码器
+var ErrUnknownContentType = errors.New("messaging: unknown content type")
+
+// Codec 消息负载编解码器
+type Codec interface {
+	// ContentType 编解码器对应的内容类型（如 application/json）
+	ContentType() string
+
+	// Marshal 编码
+	Marshal(v interface{}) ([]byte, error)
+
+	// Unmarshal 解码到 v（v 为指针）
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	codecRegistry = map[string]Codec{
+		ContentTypeJSON:     jsonCodec{},
+		ContentTypeProtobuf: protobufCodec{},
+		ContentTypeMsgpack:  newMsgpackCodec(),
+	}
+	codecMu sync.RWMutex
+)
+
+// RegisterCodec 注册编解码器
+// 内容类型重复注册会 panic（与 RegisterProvider 一致）
+func RegisterCodec(c Codec) {
+	if c == nil {
+		panic("messaging: RegisterCodec codec is nil")
+	}
+	contentType := normalizeContentType(c.ContentType())
+
+	codecMu.Lock()
+	defer codecMu.Unlock()
+
+	if _, dup := codecRegistry[contentType]; dup {
+		panic("messaging: RegisterCodec called twice for content type " + contentType)
+	}
+	codecRegistry[contentType] = c
+}
+
+// LookupCodec 按内容类型查找编解码器（忽略大小写与参数，如 "; charset=utf-8"）
+// 空内容类型使用 DefaultContentType；未注册时返回 ErrUnknownContentType
+func LookupCodec(contentType string) (Codec, error) {
+	if contentType == "" {
+		contentType = DefaultContentType
+	}
+	normalized := normalizeContentType(contentType)
+
+	codecMu.RLock()
+	c, ok := codecRegistry[normalized]
+	codecMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownContentType, contentType)
+	}
+	return c, nil
+}
+
+func normalizeContentType(contentType string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType
+	}
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
+
+// NewTypedMessage 使用指定内容类型的编解码器编码 v，并在 Metadata 中记录 content-type
+// contentType 为空时使用 DefaultContentType
+func NewTypedMessage[T any](v T, contentType string) (*Message, error) {
+	c, err := LookupCodec(contentType)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := c.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("messaging: encode %T as %s: %w", v, c.ContentType(), err)
+	}
+	msg := NewMessage("", payload)
+	msg.Metadata[MetadataContentType] = c.ContentType()
+	return msg, nil
+}
+
+// PublishTyped 编码并发布消息
+// contentType 为空时使用 JSON
+//
+// 使用示例：
+//
+//	err := messaging.PublishTyped(ctx, publisher, "order.created", OrderCreated{ID: 1}, messaging.ContentTypeJSON)
+func PublishTyped[T any](ctx context.Context, publisher Publisher, topic string, v T, contentType string) error {
+	msg, err := NewTypedMessage(v, contentType)
+	if err != nil {
+		return err
+	}
+	return publisher.PublishMessage(ctx, topic, msg)
+}
+
+// DecodeMessage 按消息的 content-type 解码负载
+// content-type 未注册时返回 ErrUnknownContentType
+func DecodeMessage[T any](msg *Message) (T, error) {
+	var v T
+	c, err := LookupCodec(msg.Metadata[MetadataContentType])
+	if err != nil {
+		return v, err
+	}
+	if err := c.Unmarshal(msg.Payload, &v); err != nil {
+		return v, fmt.Errorf("messaging: decode message %s as %T (%s): %w", msg.UUID, v, c.ContentType(), err)
+	}
+	return v, nil
+}
+
+// TypedHandler 创建自动解码的处理器
+// 解码失败或 content-type 未注册时不调用 fn，返回永久错误（见 Permanent）：
+// 重投无法修复格式错误的消息，死信策略会立即将其转入死信主题
+//
+// 使用示例：
+//
+//	router.AddHandler("order.created", "billing", messaging.TypedHandler(
+//	    func(ctx context.Context, msg *messaging.Message, evt OrderCreated) error {
+//	        return billing.Charge(ctx, evt.ID)
+//	    }))
+func TypedHandler[T any](fn func(ctx context.Context, msg *Message, payload T) error) Handler {
+	return func(ctx context.Context, msg *Message) error {
+		payload, err := DecodeMessage[T](msg)
+		if err != nil {
+			return Permanent(err)
+		}
+		return fn(ctx, msg, payload)
+	}
+}
+
+// jsonCodec JSON 编解码器
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string { return ContentTypeJSON }
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// protobufCodec protobuf 编解码器，要求类型实现 proto.Message（通常为 *pb.Xxx）
+type protobufCodec struct{}
+
+func (protobufCodec) ContentType() string { return ContentTypeProtobuf }
+
+func (protobufCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%T does not implement proto.Message", v)
+	}
+	return proto.Marshal(m)
+}
+
+// Unmarshal 支持 proto.Message，以及泛型解码时的 **pb.Xxx（自动分配消息）
+func (protobufCodec) Unmarshal(data []byte, v interface{}) error {
+	if m, ok := v.(proto.Message); ok {
+		return proto.Unmarshal(data, m)
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() && rv.Elem().Kind() == reflect.Ptr {
+		elem := reflect.New(rv.Elem().Type().Elem())
+		if m, ok := elem.Interface().(proto.Message); ok {
+			if err := proto.Unmarshal(data, m); err != nil {
+				return err
+			}
+			rv.Elem().Set(elem)
+			return nil
+		}
+	}
+	return fmt.Errorf("%T does not implement proto.Message", v)
+}
+
+// msgpackCodec msgpack 编解码器（字段名遵循 codec/json 标签）
+type msgpackCodec struct {
+	handle *codec.MsgpackHandle
+}
+
+func newMsgpackCodec() msgpackCodec {
+	h := &codec.MsgpackHandle{WriteExt: true}
+	h.RawToString = true
+	return msgpackCodec{handle: h}
+}
+
+func (msgpackCodec) ContentType() string { return ContentTypeMsgpack }
+
+func (c msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf []byte
+	err := codec.NewEncoderBytes(&buf, c.handle).Encode(v)
+	return buf, err
+}
+
+func (c msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	return codec.NewDecoderBytes(data, c.handle).Decode(v)
+}
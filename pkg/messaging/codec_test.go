@@ -0,0 +1,108 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type orderCreated struct {
+	ID    int64  `json:"id"`
+	Buyer string `json:"buyer"`
+}
+
+func TestTypedRoundTripForBuiltinCodecs(t *testing.T) {
+	for _, contentType := range []string{ContentTypeJSON, ContentTypeMsgpack, ""} {
+		pub := &fakePublisher{}
+		want := orderCreated{ID: 42, Buyer: "alice"}
+		if err := PublishTyped(context.Background(), pub, "orders", want, contentType); err != nil {
+			t.Fatalf("%q: PublishTyped: %v", contentType, err)
+		}
+		msg := pub.messages()[0].msg
+
+		var got orderCreated
+		handler := TypedHandler(func(ctx context.Context, m *Message, evt orderCreated) error {
+			got = evt
+			return nil
+		})
+		if err := handler(context.Background(), msg); err != nil {
+			t.Fatalf("%q: handler: %v", contentType, err)
+		}
+		if got != want {
+			t.Fatalf("%q: decoded %+v, want %+v", contentType, got, want)
+		}
+	}
+}
+
+func TestTypedRoundTripProtobuf(t *testing.T) {
+	msg, err := NewTypedMessage(wrapperspb.String("hello"), ContentTypeProtobuf)
+	if err != nil {
+		t.Fatalf("NewTypedMessage: %v", err)
+	}
+	if msg.Metadata[MetadataContentType] != ContentTypeProtobuf {
+		t.Fatalf("content-type = %q", msg.Metadata[MetadataContentType])
+	}
+	got, err := DecodeMessage[*wrapperspb.StringValue](msg)
+	if err != nil {
+		t.Fatalf("DecodeMessage: %v", err)
+	}
+	if got.GetValue() != "hello" {
+		t.Fatalf("decoded %q", got.GetValue())
+	}
+
+	if _, err := NewTypedMessage(orderCreated{}, ContentTypeProtobuf); err == nil {
+		t.Fatalf("non-proto value should fail to encode as protobuf")
+	}
+}
+
+func TestTypedHandlerRejectsUnknownContentType(t *testing.T) {
+	msg := NewMessage("msg-1", []byte("<order/>"))
+	msg.Metadata[MetadataContentType] = "application/xml"
+
+	called := false
+	handler := TypedHandler(func(ctx context.Context, m *Message, evt orderCreated) error {
+		called = true
+		return nil
+	})
+	if err := handler(context.Background(), msg); !errors.Is(err, ErrUnknownContentType) || !IsPermanent(err) {
+		t.Fatalf("error = %v, want permanent ErrUnknownContentType", err)
+	}
+	if called {
+		t.Fatalf("handler should not be called for unknown content type")
+	}
+}
+
+func TestTypedHandlerDeadLettersMalformedMessageWithoutRetry(t *testing.T) {
+	pub := &fakePublisher{}
+	calls := 0
+	typed := TypedHandler(func(ctx context.Context, m *Message, evt orderCreated) error {
+		calls++
+		return nil
+	})
+	handler := DeadLetterMiddleware(DeadLetterPolicy{MaxAttempts: 5, Publisher: pub})(
+		RetryMiddleware(3, time.Hour)(typed), // 重试会阻塞测试，永久错误必须跳过
+	)
+
+	msg := NewMessage("msg-1", []byte("{not json"))
+	msg.Topic = "order.created"
+	if err := handler(context.Background(), msg); err != nil {
+		t.Fatalf("error = %v, want nil after dead lettering", err)
+	}
+	published := pub.messages()
+	if calls != 0 || len(published) != 1 || published[0].topic != "order.created.dlq" {
+		t.Fatalf("calls=%d published=%+v, want malformed message dead lettered on first attempt", calls, published)
+	}
+	if published[0].msg.Metadata[MetadataDLQAttempts] != "1" {
+		t.Fatalf("dead letter attempts = %q, want 1", published[0].msg.Metadata[MetadataDLQAttempts])
+	}
+}
+
+func TestLookupCodecIgnoresParameters(t *testing.T) {
+	c, err := LookupCodec("Application/JSON; charset=utf-8")
+	if err != nil || c.ContentType() != ContentTypeJSON {
+		t.Fatalf("LookupCodec = %v, %v", c, err)
+	}
+}
@@ -7,7 +7,7 @@ import (
 
 // Config 事件总线配置
 type Config struct {
-	// Provider 消息中间件提供者类型（nsq, rabbitmq）
+	// Provider 消息中间件提供者类型（nsq, rabbitmq, redisstream）
 	Provider Provider `json:"provider" yaml:"provider"`
 
 	// NSQ 配置
@@ -15,6 +15,13 @@ type Config struct {
 
 	// RabbitMQ 配置
 	RabbitMQ RabbitMQConfig `json:"rabbitmq" yaml:"rabbitmq"`
+
+	// RedisStream Redis Streams 配置
+	RedisStream RedisStreamConfig `json:"redis_stream" yaml:"redis_stream"`
+
+	// Buses 具名事件总线配置（如 NSQ 消费 + RabbitMQ 发布），由 BusRegistry 按需创建
+	// 每项是一份完整的单 Provider 配置，不能再嵌套 Buses
+	Buses map[string]Config `json:"buses" yaml:"buses"`
 }
 
 // NSQConfig NSQ 配置
@@ -92,13 +99,31 @@ type RabbitMQConfig struct {
 
 	// ========== 消息持久化配置 ==========
 
-	// Durable 是否持久化 exchange 和 queue（默认 true）
+	// Durable 是否持久化 exchange 和 queue（nil 表示默认 true，关闭时设为 Bool(false)）
 	// true: RabbitMQ 重启后，exchange 和 queue 不会丢失
-	Durable bool `json:"durable" yaml:"durable"`
+	Durable *bool `json:"durable" yaml:"durable"`
 
-	// PersistentMessages 消息是否持久化（默认 true）
+	// PersistentMessages 消息是否持久化（nil 表示默认 true，关闭时设为 Bool(false)）
 	// true: 消息会写入磁盘，RabbitMQ 重启后消息不会丢失
-	PersistentMessages bool `json:"persistent_messages" yaml:"persistent_messages"`
+	PersistentMessages *bool `json:"persistent_messages" yaml:"persistent_messages"`
+
+	// ========== 发布确认配置 ==========
+
+	// ConfirmMode publisher confirm 模式（默认 sync）
+	// none: 不等待 broker 确认（发出即成功）
+	// sync: 每次发布同步等待 broker ack/nack
+	// batch: 发布后立即返回，累计 ConfirmBatchSize 条或调用 Flush 时统一等待
+	ConfirmMode string `json:"confirm_mode" yaml:"confirm_mode"`
+
+	// ConfirmTimeout 等待 confirm 的超时时间（默认 5s）
+	ConfirmTimeout time.Duration `json:"confirm_timeout" yaml:"confirm_timeout"`
+
+	// ConfirmBatchSize batch 模式下的批次大小（默认 100）
+	ConfirmBatchSize int `json:"confirm_batch_size" yaml:"confirm_batch_size"`
+
+	// Mandatory 是否以 mandatory 方式发布（默认 false）
+	// true: 无法路由到任何队列的消息会被 broker 退回，并以错误形式返回给发布方
+	Mandatory bool `json:"mandatory" yaml:"mandatory"`
 
 	// ========== 超时配置 ==========
 
@@ -120,6 +145,14 @@ type RabbitMQConfig struct {
 	// MaxReconnectAttempts 最大重连次数（默认 0，无限重试）
 	MaxReconnectAttempts int `json:"max_reconnect_attempts" yaml:"max_reconnect_attempts"`
 
+	// OutagePolicy 连接中断期间的发布策略（默认 buffer）
+	// fail: 立即返回错误
+	// buffer: 挂起等待重连后再发布，等待时间受调用方 ctx 控制
+	OutagePolicy string `json:"outage_policy" yaml:"outage_policy"`
+
+	// OutageBufferSize buffer 策略下最多同时挂起的发布数量（默认 1000）
+	OutageBufferSize int `json:"outage_buffer_size" yaml:"outage_buffer_size"`
+
 	// ========== 高级配置 ==========
 
 	// ExchangeType Exchange 类型（默认 fanout）
@@ -129,6 +162,11 @@ type RabbitMQConfig struct {
 	// headers: 根据消息头路由
 	ExchangeType string `json:"exchange_type" yaml:"exchange_type"`
 
+	// DelayMode 延迟发布的实现方式（默认 ttl）
+	// ttl: 按延迟时长声明 TTL 队列，到期后经死信转发回原 exchange
+	// plugin: 使用 rabbitmq_delayed_message_exchange 插件（需在 broker 上启用）
+	DelayMode string `json:"delay_mode" yaml:"delay_mode"`
+
 	// AutoDelete 是否自动删除（默认 false）
 	// true: 当没有消费者时，自动删除 exchange 和 queue
 	AutoDelete bool `json:"auto_delete" yaml:"auto_delete"`
@@ -138,6 +176,45 @@ type RabbitMQConfig struct {
 	Exclusive bool `json:"exclusive" yaml:"exclusive"`
 }
 
+// RedisStreamConfig Redis Streams 配置
+// 连接由 redis/runtime 的 profile 提供，这里只描述流与消费组的行为
+type RedisStreamConfig struct {
+	// Profile redis/runtime 中的 profile 名称（为空时使用默认 profile）
+	Profile string `json:"profile" yaml:"profile"`
+
+	// StreamPrefix stream key 前缀，topic 会映射为 StreamPrefix + topic
+	StreamPrefix string `json:"stream_prefix" yaml:"stream_prefix"`
+
+	// ConsumerName 消费者名称（为空时使用 hostname-pid 自动生成）
+	// 同一消费组内每个实例必须唯一
+	ConsumerName string `json:"consumer_name" yaml:"consumer_name"`
+
+	// MaxLen 每个 stream 保留的最大条目数（默认 100000，负数表示不裁剪）
+	// 为 0 时 MaxLen 与 ApproxMaxLen 都使用默认值
+	MaxLen int64 `json:"max_len" yaml:"max_len"`
+
+	// ApproxMaxLen 是否使用近似裁剪（MAXLEN ~，默认 true，性能更好）
+	ApproxMaxLen bool `json:"approx_max_len" yaml:"approx_max_len"`
+
+	// BatchSize 每次 XREADGROUP 读取的最大条目数（默认 10）
+	BatchSize int64 `json:"batch_size" yaml:"batch_size"`
+
+	// BlockTimeout XREADGROUP 阻塞等待时间（默认 2s）
+	BlockTimeout time.Duration `json:"block_timeout" yaml:"block_timeout"`
+
+	// ClaimMinIdle pending 条目空闲多久后可被 XAUTOCLAIM 认领（默认 1m）
+	ClaimMinIdle time.Duration `json:"claim_min_idle" yaml:"claim_min_idle"`
+
+	// ClaimInterval 检查 stale pending 条目的间隔（默认 30s）
+	ClaimInterval time.Duration `json:"claim_interval" yaml:"claim_interval"`
+
+	// MaxAttempts 最大投递次数（默认 5，负数表示不限制）
+	MaxAttempts int `json:"max_attempts" yaml:"max_attempts"`
+
+	// StartID 新建消费组的起始位置（默认 $，只消费建组之后的消息；0 表示从头消费）
+	StartID string `json:"start_id" yaml:"start_id"`
+}
+
 // DefaultConfig 返回默认配置
 func DefaultConfig() *Config {
 	return &Config{
@@ -163,20 +240,186 @@ func DefaultConfig() *Config {
 			MaxChannels:          100,
 			PrefetchCount:        200,
 			PrefetchSize:         0,
-			Durable:              true,
-			PersistentMessages:   true,
+			Durable:              Bool(true),
+			PersistentMessages:   Bool(true),
+			ConfirmMode:          "sync",
+			ConfirmTimeout:       time.Second * 5,
+			ConfirmBatchSize:     100,
+			Mandatory:            false,
 			ConnectionTimeout:    time.Second * 10,
 			HeartbeatInterval:    time.Second * 10,
 			AutoReconnect:        true,
 			ReconnectDelay:       time.Second * 5,
 			MaxReconnectAttempts: 0, // 无限重试
+			OutagePolicy:         "buffer",
+			OutageBufferSize:     1000,
 			ExchangeType:         "fanout",
+			DelayMode:            "ttl",
 			AutoDelete:           false,
 			Exclusive:            false,
 		},
+		RedisStream: RedisStreamConfig{
+			MaxLen:        100000,
+			ApproxMaxLen:  true,
+			BatchSize:     10,
+			BlockTimeout:  time.Second * 2,
+			ClaimMinIdle:  time.Minute,
+			ClaimInterval: time.Second * 30,
+			MaxAttempts:   5,
+			StartID:       "$",
+		},
 	}
 }
 
+// withDefaults 按 Provider 分节，用 DefaultConfig() 补全未设置（零值）的字段
+// 用于 Buses 中的具名总线：YAML/Go 中只写了地址的总线不会以零值超时等参数创建
+//
+// 连接地址（RabbitMQ 的 URL、Host 等）由 BuildURL 处理，不在此补全；
+// 默认为 true 的开关（Durable、PersistentMessages）使用 *bool，nil 视为未设置；
+// AutoReconnect 为 bool，只在重连参数都未设置时使用默认值
+func (c Config) withDefaults() Config {
+	defaults := DefaultConfig()
+	c.NSQ = c.NSQ.withDefaults(defaults.NSQ)
+	c.RabbitMQ = c.RabbitMQ.withDefaults(defaults.RabbitMQ)
+	c.RedisStream = c.RedisStream.withDefaults(defaults.RedisStream)
+	return c
+}
+
+func (c NSQConfig) withDefaults(d NSQConfig) NSQConfig {
+	if len(c.LookupdAddrs) == 0 {
+		c.LookupdAddrs = append([]string(nil), d.LookupdAddrs...)
+	}
+	if c.NSQdAddr == "" {
+		c.NSQdAddr = d.NSQdAddr
+	}
+	if c.MaxAttempts == 0 {
+		c.MaxAttempts = d.MaxAttempts
+	}
+	if c.MaxInFlight == 0 {
+		c.MaxInFlight = d.MaxInFlight
+	}
+	if c.MsgTimeout == 0 {
+		c.MsgTimeout = d.MsgTimeout
+	}
+	if c.RequeueDelay == 0 {
+		c.RequeueDelay = d.RequeueDelay
+	}
+	if c.DialTimeout == 0 {
+		c.DialTimeout = d.DialTimeout
+	}
+	if c.ReadTimeout == 0 {
+		c.ReadTimeout = d.ReadTimeout
+	}
+	if c.WriteTimeout == 0 {
+		c.WriteTimeout = d.WriteTimeout
+	}
+	return c
+}
+
+func (c RabbitMQConfig) withDefaults(d RabbitMQConfig) RabbitMQConfig {
+	if !c.AutoReconnect && c.ReconnectDelay == 0 && c.MaxReconnectAttempts == 0 {
+		c.AutoReconnect = d.AutoReconnect
+	}
+	if c.Durable == nil && d.Durable != nil {
+		c.Durable = Bool(*d.Durable)
+	}
+	if c.PersistentMessages == nil && d.PersistentMessages != nil {
+		c.PersistentMessages = Bool(*d.PersistentMessages)
+	}
+	if c.MaxChannels == 0 {
+		c.MaxChannels = d.MaxChannels
+	}
+	if c.PrefetchCount == 0 {
+		c.PrefetchCount = d.PrefetchCount
+	}
+	if c.ConfirmMode == "" {
+		c.ConfirmMode = d.ConfirmMode
+	}
+	if c.ConfirmTimeout == 0 {
+		c.ConfirmTimeout = d.ConfirmTimeout
+	}
+	if c.ConfirmBatchSize == 0 {
+		c.ConfirmBatchSize = d.ConfirmBatchSize
+	}
+	if c.ConnectionTimeout == 0 {
+		c.ConnectionTimeout = d.ConnectionTimeout
+	}
+	if c.HeartbeatInterval == 0 {
+		c.HeartbeatInterval = d.HeartbeatInterval
+	}
+	if c.ReconnectDelay == 0 {
+		c.ReconnectDelay = d.ReconnectDelay
+	}
+	if c.OutagePolicy == "" {
+		c.OutagePolicy = d.OutagePolicy
+	}
+	if c.OutageBufferSize == 0 {
+		c.OutageBufferSize = d.OutageBufferSize
+	}
+	if c.ExchangeType == "" {
+		c.ExchangeType = d.ExchangeType
+	}
+	if c.DelayMode == "" {
+		c.DelayMode = d.DelayMode
+	}
+	return c
+}
+
+func (c RedisStreamConfig) withDefaults(d RedisStreamConfig) RedisStreamConfig {
+	if c.MaxLen == 0 {
+		c.MaxLen = d.MaxLen
+		c.ApproxMaxLen = d.ApproxMaxLen
+	}
+	if c.BatchSize == 0 {
+		c.BatchSize = d.BatchSize
+	}
+	if c.BlockTimeout == 0 {
+		c.BlockTimeout = d.BlockTimeout
+	}
+	if c.ClaimMinIdle == 0 {
+		c.ClaimMinIdle = d.ClaimMinIdle
+	}
+	if c.ClaimInterval == 0 {
+		c.ClaimInterval = d.ClaimInterval
+	}
+	if c.MaxAttempts == 0 {
+		c.MaxAttempts = d.MaxAttempts
+	}
+	if c.StartID == "" {
+		c.StartID = d.StartID
+	}
+	return c
+}
+
+// Validate 校验配置：Provider 必须已注册（空 Provider 仅在配置了 Buses 时允许），
+// Buses 中每个总线的 Provider 也必须已注册。不会建立任何连接
+func (c *Config) Validate() error {
+	if c.Provider == "" {
+		if len(c.Buses) == 0 {
+			return fmt.Errorf("messaging provider is empty")
+		}
+	} else if !isRegisteredProvider(c.Provider) {
+		return fmt.Errorf("unsupported messaging provider: %s (available: %v)", c.Provider, GetRegisteredProviders())
+	}
+
+	for name, bus := range c.Buses {
+		if name == "" {
+			return fmt.Errorf("bus name cannot be empty")
+		}
+		if len(bus.Buses) > 0 {
+			return fmt.Errorf("bus %s: nested buses are not allowed", name)
+		}
+		if bus.Provider == "" {
+			return fmt.Errorf("bus %s: messaging provider is empty", name)
+		}
+		if !isRegisteredProvider(bus.Provider) {
+			return fmt.Errorf("bus %s: unsupported messaging provider: %s (available: %v)",
+				name, bus.Provider, GetRegisteredProviders())
+		}
+	}
+	return nil
+}
+
 // DefaultNSQConfig 返回默认 NSQ 配置
 func DefaultNSQConfig() NSQConfig {
 	return DefaultConfig().NSQ
@@ -187,6 +430,26 @@ func DefaultRabbitMQConfig() RabbitMQConfig {
 	return DefaultConfig().RabbitMQ
 }
 
+// DefaultRedisStreamConfig 返回默认 Redis Streams 配置
+func DefaultRedisStreamConfig() RedisStreamConfig {
+	return DefaultConfig().RedisStream
+}
+
+// Bool 返回 v 的指针，用于设置 *bool 配置项（如 RabbitMQConfig.Durable）
+func Bool(v bool) *bool {
+	return &v
+}
+
+// IsDurable 是否持久化 exchange 和 queue（Durable 未设置时为 true）
+func (c *RabbitMQConfig) IsDurable() bool {
+	return c.Durable == nil || *c.Durable
+}
+
+// IsPersistentMessages 消息是否持久化（PersistentMessages 未设置时为 true）
+func (c *RabbitMQConfig) IsPersistentMessages() bool {
+	return c.PersistentMessages == nil || *c.PersistentMessages
+}
+
 // BuildURL 构建 RabbitMQ 连接 URL
 // 如果已经设置了 URL，直接返回；否则根据独立配置项构建
 func (c *RabbitMQConfig) BuildURL() string {
@@ -0,0 +1,75 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeDeduplicationStore struct {
+	states   map[string]DeduplicationState
+	released []string
+	err      error
+}
+
+func (s *fakeDeduplicationStore) Claim(ctx context.Context, uuid string) (DeduplicationState, string, error) {
+	if s.err != nil {
+		return DeduplicationInProgress, "", s.err
+	}
+	if state, ok := s.states[uuid]; ok {
+		return state, "", nil
+	}
+	s.states[uuid] = DeduplicationInProgress
+	return DeduplicationClaimed, "token-" + uuid, nil
+}
+
+func (s *fakeDeduplicationStore) Complete(ctx context.Context, uuid, token string, ttl time.Duration) error {
+	s.states[uuid] = DeduplicationDone
+	return nil
+}
+
+func (s *fakeDeduplicationStore) Release(ctx context.Context, uuid, token string) error {
+	delete(s.states, uuid)
+	s.released = append(s.released, uuid+"/"+token)
+	return nil
+}
+
+func TestDeduplicationMiddleware(t *testing.T) {
+	store := &fakeDeduplicationStore{states: make(map[string]DeduplicationState)}
+	handlerErr := errors.New("boom")
+	calls := 0
+	fail := true
+	handler := DeduplicationMiddleware(store, time.Hour)(func(ctx context.Context, msg *Message) error {
+		calls++
+		if fail {
+			return handlerErr
+		}
+		return nil
+	})
+
+	if err := handler(context.Background(), NewMessage("m1", nil)); !errors.Is(err, handlerErr) {
+		t.Fatalf("error = %v, want handler error", err)
+	}
+	if len(store.released) != 1 || store.released[0] != "m1/token-m1" {
+		t.Fatalf("failed message should release its claim with the claim token: %v", store.released)
+	}
+
+	fail = false
+	if err := handler(context.Background(), NewMessage("m1", nil)); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if err := handler(context.Background(), NewMessage("m1", nil)); err != nil || calls != 2 {
+		t.Fatalf("duplicate: err=%v calls=%d, want skipped", err, calls)
+	}
+
+	store.states["m2"] = DeduplicationInProgress
+	if err := handler(context.Background(), NewMessage("m2", nil)); !errors.Is(err, ErrMessageInProgress) {
+		t.Fatalf("error = %v, want ErrMessageInProgress", err)
+	}
+
+	store.err = errors.New("redis down")
+	if err := handler(context.Background(), NewMessage("m3", nil)); !errors.Is(err, store.err) || calls != 2 {
+		t.Fatalf("store outage: err=%v calls=%d", err, calls)
+	}
+}
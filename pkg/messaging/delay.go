@@ -0,0 +1,127 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ========== 延迟发布 ==========
+
+// ErrDelayNotSupported 发布者不支持延迟发布
+var ErrDelayNotSupported = errors.New("messaging: delayed publish is not supported by publisher")
+
+// DelayedPublisher 支持延迟发布的发布者
+//
+// 各提供者的实现：
+//   - nsq：DPUB（nsqd 内存中延迟，受 max-req-timeout 限制）
+//   - rabbitmq：按延迟时长的 TTL 队列 + 死信 exchange，或 delayed-message 插件
+//
+// 调用方通过 SupportsDelay 或类型断言检测能力
+type DelayedPublisher interface {
+	// PublishDelayed 在 delay 之后投递消息；delay <= 0 时立即发布
+	PublishDelayed(ctx context.Context, topic string, msg *Message, delay time.Duration) error
+}
+
+// SupportsDelay 发布者是否支持延迟发布
+func SupportsDelay(publisher Publisher) bool {
+	_, ok := publisher.(DelayedPublisher)
+	return ok
+}
+
+// PublishDelayed 延迟发布消息
+// delay <= 0 时等价于 PublishMessage；发布者不支持延迟时返回 ErrDelayNotSupported
+//
+// 使用示例：
+//
+//	msg := messaging.NewMessage(uuid, payload)
+//	err := messaging.PublishDelayed(ctx, publisher, "order.timeout", msg, 30*time.Minute)
+func PublishDelayed(ctx context.Context, publisher Publisher, topic string, msg *Message, delay time.Duration) error {
+	if dp, ok := publisher.(DelayedPublisher); ok {
+		return dp.PublishDelayed(ctx, topic, msg, delay)
+	}
+	if delay <= 0 {
+		return publisher.PublishMessage(ctx, topic, msg)
+	}
+	return fmt.Errorf("%w: %T", ErrDelayNotSupported, publisher)
+}
+
+// timerDelayPublisher 基于进程内定时器的延迟发布
+type timerDelayPublisher struct {
+	Publisher
+
+	mu      sync.Mutex
+	timers  map[*time.Timer]struct{}
+	wg      sync.WaitGroup
+	closed  bool
+	onError func(topic string, msg *Message, err error)
+}
+
+// WithTimerDelay 为不支持延迟的发布者提供基于定时器的延迟发布
+//
+// 延迟消息只保存在进程内存中：进程退出或 Close 时尚未到期的消息会被丢弃，
+// 仅适用于测试、本地开发或可容忍丢失的场景。发布者已支持延迟时原样返回。
+// onError: 到期发布失败时的回调（可为 nil）
+func WithTimerDelay(publisher Publisher, onError func(topic string, msg *Message, err error)) Publisher {
+	if SupportsDelay(publisher) {
+		return publisher
+	}
+	return &timerDelayPublisher{
+		Publisher: publisher,
+		timers:    make(map[*time.Timer]struct{}),
+		onError:   onError,
+	}
+}
+
+// PublishDelayed 实现 DelayedPublisher 接口
+func (p *timerDelayPublisher) PublishDelayed(ctx context.Context, topic string, msg *Message, delay time.Duration) error {
+	if delay <= 0 {
+		return p.PublishMessage(ctx, topic, msg)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return errors.New("messaging: publisher is closed")
+	}
+
+	p.wg.Add(1)
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		defer p.wg.Done()
+
+		p.mu.Lock()
+		delete(p.timers, timer)
+		p.mu.Unlock()
+
+		// 原始 ctx 可能早已结束，到期发布使用独立的 context
+		if err := p.PublishMessage(context.WithoutCancel(ctx), topic, msg); err != nil && p.onError != nil {
+			p.onError(topic, msg, err)
+		}
+	})
+	p.timers[timer] = struct{}{}
+	return nil
+}
+
+// PublishBatch 保留底层发布者的批量发布能力
+func (p *timerDelayPublisher) PublishBatch(ctx context.Context, topic string, msgs []*Message) error {
+	return PublishBatch(ctx, p.Publisher, topic, msgs)
+}
+
+// Close 取消尚未到期的延迟消息，等待正在发布的消息完成后关闭底层发布者
+func (p *timerDelayPublisher) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	for timer := range p.timers {
+		if timer.Stop() {
+			p.wg.Done()
+		}
+		delete(p.timers, timer)
+	}
+	p.mu.Unlock()
+
+	p.wg.Wait()
+	return p.Publisher.Close()
+}
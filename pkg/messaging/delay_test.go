@@ -0,0 +1,71 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPublishDelayedRequiresCapability(t *testing.T) {
+	pub := &fakePublisher{}
+	if SupportsDelay(pub) {
+		t.Fatalf("fake publisher should not support delay")
+	}
+
+	msg := NewMessage("msg-1", []byte("payload"))
+	if err := PublishDelayed(context.Background(), pub, "jobs", msg, time.Second); !errors.Is(err, ErrDelayNotSupported) {
+		t.Fatalf("error = %v, want ErrDelayNotSupported", err)
+	}
+	if err := PublishDelayed(context.Background(), pub, "jobs", msg, 0); err != nil {
+		t.Fatalf("zero delay should publish immediately: %v", err)
+	}
+	if len(pub.messages()) != 1 {
+		t.Fatalf("published %d messages, want 1", len(pub.messages()))
+	}
+}
+
+func TestWithTimerDelayPublishesAfterDelay(t *testing.T) {
+	pub := &fakePublisher{}
+	delayed := WithTimerDelay(pub, nil)
+	if !SupportsDelay(delayed) {
+		t.Fatalf("timer publisher should support delay")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	msg := NewMessage("msg-1", []byte("payload"))
+	if err := PublishDelayed(ctx, delayed, "jobs", msg, 20*time.Millisecond); err != nil {
+		t.Fatalf("publish delayed: %v", err)
+	}
+	cancel()
+	if len(pub.messages()) != 0 {
+		t.Fatalf("message published before delay elapsed")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(pub.messages()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	published := pub.messages()
+	if len(published) != 1 || published[0].topic != "jobs" || published[0].msg.UUID != "msg-1" {
+		t.Fatalf("published = %+v", published)
+	}
+}
+
+func TestWithTimerDelayCloseDropsPending(t *testing.T) {
+	pub := &fakePublisher{}
+	delayed := WithTimerDelay(pub, nil)
+
+	if err := PublishDelayed(context.Background(), delayed, "jobs", NewMessage("msg-1", nil), time.Hour); err != nil {
+		t.Fatalf("publish delayed: %v", err)
+	}
+	if err := delayed.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if err := PublishDelayed(context.Background(), delayed, "jobs", NewMessage("msg-2", nil), time.Second); err == nil {
+		t.Fatalf("publish after close should fail")
+	}
+	if len(pub.messages()) != 0 {
+		t.Fatalf("pending delayed message should be dropped on close")
+	}
+}
@@ -0,0 +1,315 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ========== 死信队列（DLQ） ==========
+
+// 死信消息携带的 Metadata 键
+const (
+	// MetadataDLQLastError 最后一次处理失败的错误信息
+	MetadataDLQLastError = "dlq_last_error"
+
+	// MetadataDLQAttempts 转入死信队列前的处理次数
+	MetadataDLQAttempts = "dlq_attempts"
+
+	// MetadataDLQOriginalTopic 原始主题
+	MetadataDLQOriginalTopic = "dlq_original_topic"
+
+	// MetadataDLQOriginalChannel 原始通道
+	MetadataDLQOriginalChannel = "dlq_original_channel"
+
+	// MetadataDLQFirstFailureAt 首次失败时间（RFC3339Nano）
+	MetadataDLQFirstFailureAt = "dlq_first_failure_at"
+
+	// MetadataDLQLastFailureAt 最后一次失败时间（RFC3339Nano）
+	MetadataDLQLastFailureAt = "dlq_last_failure_at"
+
+	// MetadataDLQRedriveCount 消息被重新投递回原主题的次数
+	MetadataDLQRedriveCount = "dlq_redrive_count"
+)
+
+// DeadLetterTopicSuffix 默认死信主题后缀
+const DeadLetterTopicSuffix = ".dlq"
+
+// DeadLetterTopic 返回默认死信主题名称（<topic>.dlq）
+func DeadLetterTopic(topic string) string {
+	return topic + DeadLetterTopicSuffix
+}
+
+// ErrPermanent 永久错误标记：重试不会成功（如消息格式错误），应直接转入死信主题
+var ErrPermanent = errors.New("messaging: permanent failure")
+
+// permanentError 同时匹配 ErrPermanent 与原错误
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() []error { return []error{ErrPermanent, e.err} }
+
+// Permanent 将错误标记为永久错误（err 为 nil 时返回 nil）
+// DeadLetterMiddleware 遇到永久错误时不等待 MaxAttempts，立即转入死信主题；RetryMiddleware 不重试。
+// 没有死信策略时，消息仍按处理失败 Nack
+func Permanent(err error) error {
+	if err == nil || IsPermanent(err) {
+		return err
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent 判断错误是否被标记为永久错误
+func IsPermanent(err error) bool {
+	return errors.Is(err, ErrPermanent)
+}
+
+// ErrTransient 临时错误标记：消息未被处理（如熔断器拒绝），不计入死信失败次数
+var ErrTransient = errors.New("messaging: transient failure")
+
+// transientError 同时匹配 ErrTransient 与原错误
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+
+func (e *transientError) Unwrap() []error { return []error{ErrTransient, e.err} }
+
+// Transient 将错误标记为临时错误（err 为 nil 时返回 nil）
+// DeadLetterMiddleware 遇到临时错误时不记录失败，原样返回（消息 Nack 重投）。
+// CircuitBreakerMiddleware 拒绝的消息使用该标记
+func Transient(err error) error {
+	if err == nil || IsTransient(err) {
+		return err
+	}
+	return &transientError{err: err}
+}
+
+// IsTransient 判断错误是否被标记为临时错误
+func IsTransient(err error) bool {
+	return errors.Is(err, ErrTransient)
+}
+
+// DeadLetterPolicy 死信策略
+// 与具体消息中间件无关：消息处理失败达到 MaxAttempts 次后，
+// 通过 Publisher 发布到死信主题，并确认原消息
+type DeadLetterPolicy struct {
+	// MaxAttempts 最大处理次数（必须大于 0）
+	MaxAttempts uint16
+
+	// ConsumerMaxAttempts 订阅者自身的最大投递次数（0 表示不限制），如 NSQConfig.MaxAttempts
+	// 超过该次数的消息会被订阅者直接丢弃而不经过处理器，因此 MaxAttempts 不能大于它；
+	// Router.AddHandlerWithDeadLetter 会从实现 DeliveryLimiter 的订阅者自动获取
+	ConsumerMaxAttempts uint16
+
+	// Publisher 死信消息发布者
+	Publisher Publisher
+
+	// TopicFunc 死信主题命名函数（默认 DeadLetterTopic）
+	TopicFunc func(topic string) string
+}
+
+// Validate 校验死信策略
+func (p DeadLetterPolicy) Validate() error {
+	if p.MaxAttempts == 0 {
+		return fmt.Errorf("dead letter max attempts must be positive")
+	}
+	if p.Publisher == nil {
+		return fmt.Errorf("dead letter publisher is nil")
+	}
+	if p.ConsumerMaxAttempts > 0 && p.MaxAttempts > p.ConsumerMaxAttempts {
+		return fmt.Errorf("dead letter max attempts %d exceeds consumer max attempts %d, messages would be dropped before dead lettering",
+			p.MaxAttempts, p.ConsumerMaxAttempts)
+	}
+	return nil
+}
+
+// DeliveryLimiter 订阅者的投递次数上限（可选能力）
+// 达到上限后订阅者直接丢弃消息（如 NSQ 的 MaxAttempts），返回 0 表示不限制
+type DeliveryLimiter interface {
+	MaxDeliveryAttempts() uint16
+}
+
+func (p DeadLetterPolicy) topic(topic string) string {
+	if p.TopicFunc != nil {
+		return p.TopicFunc(topic)
+	}
+	return DeadLetterTopic(topic)
+}
+
+// DeadLetterMiddleware 死信中间件
+// 处理失败时记录失败次数与时间，达到 MaxAttempts 后发布到死信主题并返回 nil（确认原消息）；
+// 永久错误（见 Permanent）不重试，第一次失败即转入死信主题；
+// 临时错误（见 Transient，如熔断器拒绝）不计入失败次数。
+// 死信发布失败时返回原错误，消息按中间件自身的语义重新投递。
+//
+// 失败次数取 Message.Attempts 与进程内计数的较大值，
+// 因此对不提供投递次数的 Provider（如 RabbitMQ 经典队列）同样生效。
+func DeadLetterMiddleware(policy DeadLetterPolicy) Middleware {
+	if err := policy.Validate(); err != nil {
+		panic("messaging: invalid dead letter policy: " + err.Error())
+	}
+	tracker := newFailureTracker()
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *Message) error {
+			err := next(ctx, msg)
+			key := failureKey(msg)
+			if err == nil {
+				tracker.forget(key)
+				return nil
+			}
+			if IsTransient(err) && !IsPermanent(err) {
+				tracker.skip(key, time.Now())
+				return err
+			}
+
+			now := time.Now()
+			record := tracker.record(key, msg.Attempts, now)
+			if record.attempts < int(policy.MaxAttempts) && !IsPermanent(err) {
+				return err
+			}
+
+			dead := buildDeadLetter(msg, err, record, now)
+			if pubErr := policy.Publisher.PublishMessage(ctx, policy.topic(msg.Topic), dead); pubErr != nil {
+				return errors.Join(err, fmt.Errorf("failed to publish dead letter: %w", pubErr))
+			}
+			tracker.forget(key)
+			return nil
+		}
+	}
+}
+
+// buildDeadLetter 构造死信消息，保留原 UUID、Payload 与 Metadata
+func buildDeadLetter(msg *Message, cause error, record failureRecord, now time.Time) *Message {
+	dead := NewMessage(msg.UUID, msg.Payload)
+	for k, v := range msg.Metadata {
+		dead.Metadata[k] = v
+	}
+	dead.Metadata[MetadataDLQLastError] = cause.Error()
+	dead.Metadata[MetadataDLQAttempts] = strconv.Itoa(record.attempts)
+	dead.Metadata[MetadataDLQOriginalTopic] = msg.Topic
+	dead.Metadata[MetadataDLQOriginalChannel] = msg.Channel
+	dead.Metadata[MetadataDLQFirstFailureAt] = record.firstFailure.UTC().Format(time.RFC3339Nano)
+	dead.Metadata[MetadataDLQLastFailureAt] = now.UTC().Format(time.RFC3339Nano)
+	return dead
+}
+
+// RedriveMessage 将死信消息重新发布回原主题
+// 会清除 dlq_* 诊断信息，并累加 dlq_redrive_count
+func RedriveMessage(ctx context.Context, publisher Publisher, msg *Message) error {
+	if msg == nil {
+		return fmt.Errorf("message is nil")
+	}
+	topic := msg.Metadata[MetadataDLQOriginalTopic]
+	if topic == "" {
+		return fmt.Errorf("message %s has no %s metadata", msg.UUID, MetadataDLQOriginalTopic)
+	}
+
+	redriven := NewMessage(msg.UUID, msg.Payload)
+	for k, v := range msg.Metadata {
+		if strings.HasPrefix(k, "dlq_") {
+			continue
+		}
+		redriven.Metadata[k] = v
+	}
+	count, _ := strconv.Atoi(msg.Metadata[MetadataDLQRedriveCount])
+	redriven.Metadata[MetadataDLQRedriveCount] = strconv.Itoa(count + 1)
+
+	return publisher.PublishMessage(ctx, topic, redriven)
+}
+
+// NewRedriveHandler 创建死信重投处理器
+// 订阅死信主题即可将其中的消息逐条送回原主题：
+//
+//	subscriber.Subscribe(messaging.DeadLetterTopic("order.created"), "redrive", messaging.NewRedriveHandler(publisher))
+func NewRedriveHandler(publisher Publisher) Handler {
+	return func(ctx context.Context, msg *Message) error {
+		return RedriveMessage(ctx, publisher, msg)
+	}
+}
+
+// ---------- 进程内失败计数 ----------
+
+// failureTrackerTTL 失败记录的最长保留时间，避免永不再投递的消息占用内存
+const failureTrackerTTL = 24 * time.Hour
+
+type failureRecord struct {
+	attempts     int
+	skipped      int // 临时错误的投递次数，不计入 broker 提供的投递次数
+	firstFailure time.Time
+	lastFailure  time.Time
+}
+
+type failureTracker struct {
+	mu        sync.Mutex
+	records   map[string]failureRecord
+	lastPrune time.Time
+}
+
+func newFailureTracker() *failureTracker {
+	return &failureTracker{records: make(map[string]failureRecord)}
+}
+
+// record 记录一次失败，返回更新后的记录
+func (t *failureTracker) record(key string, brokerAttempts uint16, now time.Time) failureRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pruneLocked(now)
+
+	rec := t.records[key]
+	if rec.attempts == 0 {
+		rec.firstFailure = now
+	}
+	rec.attempts++
+	if processed := int(brokerAttempts) - rec.skipped; processed > rec.attempts {
+		rec.attempts = processed
+	}
+	rec.lastFailure = now
+	t.records[key] = rec
+	return rec
+}
+
+// skip 记录一次临时错误（消息未被处理），之后从 broker 投递次数中扣除
+func (t *failureTracker) skip(key string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pruneLocked(now)
+
+	rec := t.records[key]
+	rec.skipped++
+	rec.lastFailure = now
+	t.records[key] = rec
+}
+
+func (t *failureTracker) forget(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.records, key)
+}
+
+func (t *failureTracker) pruneLocked(now time.Time) {
+	if now.Sub(t.lastPrune) < time.Minute {
+		return
+	}
+	t.lastPrune = now
+	for key, rec := range t.records {
+		if now.Sub(rec.lastFailure) > failureTrackerTTL {
+			delete(t.records, key)
+		}
+	}
+}
+
+func failureKey(msg *Message) string {
+	return msg.Topic + "\x00" + msg.Channel + "\x00" + msg.UUID
+}
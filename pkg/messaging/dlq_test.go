@@ -0,0 +1,190 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+type publishedMessage struct {
+	topic string
+	msg   *Message
+}
+
+type fakePublisher struct {
+	mu        sync.Mutex
+	published []publishedMessage
+	err       error
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, topic string, body []byte) error {
+	return p.PublishMessage(ctx, topic, NewMessage("", body))
+}
+
+func (p *fakePublisher) PublishMessage(ctx context.Context, topic string, msg *Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, publishedMessage{topic: topic, msg: msg})
+	return nil
+}
+
+func (p *fakePublisher) Close() error { return nil }
+
+func (p *fakePublisher) messages() []publishedMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]publishedMessage(nil), p.published...)
+}
+
+func TestDeadLetterMiddlewarePublishesAfterMaxAttempts(t *testing.T) {
+	pub := &fakePublisher{}
+	handlerErr := errors.New("boom")
+	handler := DeadLetterMiddleware(DeadLetterPolicy{MaxAttempts: 3, Publisher: pub})(
+		func(ctx context.Context, msg *Message) error { return handlerErr },
+	)
+
+	newDelivery := func() *Message {
+		msg := NewMessage("msg-1", []byte("payload"))
+		msg.Metadata["trace_id"] = "trace-1"
+		msg.Topic = "order.created"
+		msg.Channel = "billing"
+		return msg
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := handler(context.Background(), newDelivery()); !errors.Is(err, handlerErr) {
+			t.Fatalf("attempt %d error = %v, want handler error", i+1, err)
+		}
+	}
+	if len(pub.messages()) != 0 {
+		t.Fatalf("dead letter published before max attempts")
+	}
+
+	if err := handler(context.Background(), newDelivery()); err != nil {
+		t.Fatalf("final attempt error = %v, want nil after dead lettering", err)
+	}
+	published := pub.messages()
+	if len(published) != 1 {
+		t.Fatalf("published %d dead letters, want 1", len(published))
+	}
+	dead := published[0]
+	if dead.topic != "order.created.dlq" {
+		t.Fatalf("dead letter topic = %q", dead.topic)
+	}
+	md := dead.msg.Metadata
+	if md[MetadataDLQLastError] != "boom" || md[MetadataDLQAttempts] != "3" {
+		t.Fatalf("dead letter error/attempts = %q/%q", md[MetadataDLQLastError], md[MetadataDLQAttempts])
+	}
+	if md[MetadataDLQOriginalTopic] != "order.created" || md[MetadataDLQOriginalChannel] != "billing" {
+		t.Fatalf("dead letter origin = %q/%q", md[MetadataDLQOriginalTopic], md[MetadataDLQOriginalChannel])
+	}
+	if md[MetadataDLQFirstFailureAt] == "" || md[MetadataDLQLastFailureAt] == "" {
+		t.Fatalf("dead letter failure times missing: %v", md)
+	}
+	if md["trace_id"] != "trace-1" || dead.msg.UUID != "msg-1" {
+		t.Fatalf("dead letter should keep original uuid and metadata: %+v", dead.msg)
+	}
+}
+
+func TestDeadLetterMiddlewareUsesBrokerAttempts(t *testing.T) {
+	pub := &fakePublisher{}
+	handler := DeadLetterMiddleware(DeadLetterPolicy{MaxAttempts: 5, Publisher: pub})(
+		func(ctx context.Context, msg *Message) error { return errors.New("boom") },
+	)
+
+	msg := NewMessage("msg-1", nil)
+	msg.Topic = "jobs"
+	msg.Attempts = 5
+	if err := handler(context.Background(), msg); err != nil {
+		t.Fatalf("error = %v, want nil", err)
+	}
+	if len(pub.messages()) != 1 {
+		t.Fatalf("expected dead letter when broker attempts reach max")
+	}
+}
+
+func TestDeadLetterMiddlewareReturnsErrorWhenPublishFails(t *testing.T) {
+	handlerErr := errors.New("boom")
+	pub := &fakePublisher{err: errors.New("broker down")}
+	handler := DeadLetterMiddleware(DeadLetterPolicy{MaxAttempts: 1, Publisher: pub})(
+		func(ctx context.Context, msg *Message) error { return handlerErr },
+	)
+
+	err := handler(context.Background(), NewMessage("msg-1", nil))
+	if !errors.Is(err, handlerErr) || !errors.Is(err, pub.err) {
+		t.Fatalf("error = %v, want handler and publish errors", err)
+	}
+}
+
+func TestRedriveMessageRestoresOriginalTopic(t *testing.T) {
+	pub := &fakePublisher{}
+	dead := NewMessage("msg-1", []byte("payload"))
+	dead.Metadata["trace_id"] = "trace-1"
+	dead.Metadata[MetadataDLQOriginalTopic] = "order.created"
+	dead.Metadata[MetadataDLQLastError] = "boom"
+	dead.Metadata[MetadataDLQRedriveCount] = "1"
+
+	if err := NewRedriveHandler(pub)(context.Background(), dead); err != nil {
+		t.Fatalf("redrive: %v", err)
+	}
+	published := pub.messages()
+	if len(published) != 1 || published[0].topic != "order.created" {
+		t.Fatalf("published = %+v", published)
+	}
+	md := published[0].msg.Metadata
+	if _, ok := md[MetadataDLQLastError]; ok {
+		t.Fatalf("redriven message should not keep dlq diagnostics: %v", md)
+	}
+	if md[MetadataDLQRedriveCount] != "2" || md["trace_id"] != "trace-1" {
+		t.Fatalf("redriven metadata = %v", md)
+	}
+
+	if err := RedriveMessage(context.Background(), pub, NewMessage("x", nil)); err == nil {
+		t.Fatalf("redrive without original topic should fail")
+	}
+}
+
+type fakeLimitedSubscriber struct {
+	fakeSubscriber
+	maxAttempts uint16
+}
+
+func (s *fakeLimitedSubscriber) MaxDeliveryAttempts() uint16 { return s.maxAttempts }
+
+func TestDeadLetterPolicyRejectsMaxAttemptsAboveConsumerLimit(t *testing.T) {
+	pub := &fakePublisher{}
+	if err := (DeadLetterPolicy{MaxAttempts: 5, ConsumerMaxAttempts: 5, Publisher: pub}).Validate(); err != nil {
+		t.Fatalf("max attempts equal to consumer limit should be valid: %v", err)
+	}
+	if err := (DeadLetterPolicy{MaxAttempts: 6, ConsumerMaxAttempts: 5, Publisher: pub}).Validate(); err == nil {
+		t.Fatalf("max attempts above consumer limit should be rejected")
+	}
+
+	router := NewRouter(&fakeLimitedSubscriber{maxAttempts: 5})
+	if err := router.TryAddHandlerWithDeadLetter("jobs", "worker", func(ctx context.Context, msg *Message) error { return nil },
+		DeadLetterPolicy{MaxAttempts: 10, Publisher: pub}); err == nil {
+		t.Fatalf("TryAddHandlerWithDeadLetter should return an error for a policy above the subscriber's delivery limit")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("router should reject a policy above the subscriber's delivery limit")
+		}
+	}()
+	router.AddHandlerWithDeadLetter("jobs", "worker", func(ctx context.Context, msg *Message) error { return nil },
+		DeadLetterPolicy{MaxAttempts: 10, Publisher: pub})
+}
+
+func TestPermanentErrorMatchesCauseAndMarker(t *testing.T) {
+	cause := errors.New("bad payload")
+	err := Permanent(cause)
+	if !errors.Is(err, cause) || !IsPermanent(err) || err.Error() != "bad payload" {
+		t.Fatalf("Permanent(cause) = %v, want wrapped permanent cause", err)
+	}
+	if Permanent(nil) != nil || IsPermanent(cause) {
+		t.Fatalf("only marked errors should be permanent")
+	}
+}
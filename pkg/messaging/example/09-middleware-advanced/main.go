@@ -185,14 +185,20 @@ func (cb *SimpleCircuitBreaker) State() string {
 }
 
 // SimpleDeduplicationStore 简单的去重存储实现
+// 生产环境使用 redisdedup.Store
 type SimpleDeduplicationStore struct {
-	mu   sync.RWMutex
-	seen map[string]time.Time
+	mu     sync.Mutex
+	seen   map[string]time.Time // 认领/已处理标记的过期时间
+	done   map[string]bool
+	tokens map[string]string // 当前认领的令牌
+	next   int
 }
 
 func NewSimpleDeduplicationStore() *SimpleDeduplicationStore {
 	store := &SimpleDeduplicationStore{
-		seen: make(map[string]time.Time),
+		seen:   make(map[string]time.Time),
+		done:   make(map[string]bool),
+		tokens: make(map[string]string),
 	}
 	// 启动定期清理
 	go func() {
@@ -204,17 +210,43 @@ func NewSimpleDeduplicationStore() *SimpleDeduplicationStore {
 	return store
 }
 
-func (s *SimpleDeduplicationStore) Exists(uuid string) bool {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	_, exists := s.seen[uuid]
-	return exists
+func (s *SimpleDeduplicationStore) Claim(ctx context.Context, uuid string) (messaging.DeduplicationState, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if expiry, exists := s.seen[uuid]; exists && time.Now().Before(expiry) {
+		if s.done[uuid] {
+			return messaging.DeduplicationDone, "", nil
+		}
+		return messaging.DeduplicationInProgress, "", nil
+	}
+	s.next++
+	token := strconv.Itoa(s.next)
+	s.seen[uuid] = time.Now().Add(time.Minute) // 处理超时
+	s.tokens[uuid] = token
+	delete(s.done, uuid)
+	return messaging.DeduplicationClaimed, token, nil
 }
 
-func (s *SimpleDeduplicationStore) Mark(uuid string, ttl time.Duration) error {
+func (s *SimpleDeduplicationStore) Complete(ctx context.Context, uuid, token string, ttl time.Duration) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if s.tokens[uuid] != token || s.done[uuid] {
+		return messaging.ErrDeduplicationClaimLost
+	}
 	s.seen[uuid] = time.Now().Add(ttl)
+	s.done[uuid] = true
+	return nil
+}
+
+func (s *SimpleDeduplicationStore) Release(ctx context.Context, uuid, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tokens[uuid] != token || s.done[uuid] {
+		return messaging.ErrDeduplicationClaimLost
+	}
+	delete(s.seen, uuid)
+	delete(s.done, uuid)
+	delete(s.tokens, uuid)
 	return nil
 }
 
@@ -225,6 +257,8 @@ func (s *SimpleDeduplicationStore) cleanup() {
 	for uuid, expiry := range s.seen {
 		if now.After(expiry) {
 			delete(s.seen, uuid)
+			delete(s.done, uuid)
+			delete(s.tokens, uuid)
 		}
 	}
 }
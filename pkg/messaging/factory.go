@@ -14,6 +14,9 @@ const (
 
 	// ProviderRabbitMQ RabbitMQ 消息队列
 	ProviderRabbitMQ Provider = "rabbitmq"
+
+	// ProviderRedisStream Redis Streams 轻量消息队列
+	ProviderRedisStream Provider = "redisstream"
 )
 
 // EventBusFactory 事件总线工厂函数
@@ -76,6 +79,13 @@ func NewEventBus(config *Config) (EventBus, error) {
 	return factory(config)
 }
 
+func isRegisteredProvider(provider Provider) bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	_, exists := factoryRegistry[provider]
+	return exists
+}
+
 // GetRegisteredProviders 获取已注册的提供者列表
 func GetRegisteredProviders() []Provider {
 	registryMu.RLock()
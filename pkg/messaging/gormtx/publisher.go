@@ -0,0 +1,150 @@
+// Package gormtx 提供事务提交后发布消息的 messaging.Publisher 装饰器
+//
+// 在 gormuow.UnitOfWork.WithinTransaction 内发布的消息不会立即发出，
+// 而是通过 gormuow.AfterCommit 排队，事务提交后按发布顺序依次发出；事务回滚时丢弃。
+// 不在事务内时直接发布。
+//
+// 注意：
+//   - 只使用 gormuow.WithTx 注入的外部事务不会执行提交后钩子，此时消息不会发出
+//   - 提交后发布失败无法回滚业务数据，需要通过 ErrorHandler 记录并补偿；需要严格一致时使用 Outbox
+//   - 装饰器只实现 Publisher 接口，延迟、优先级等可选能力不会透传
+//
+// 使用示例：
+//
+//	uow := gormuow.NewUnitOfWork(db, gormuow.WithAfterCommitErrorHandler(reportHookError))
+//	publisher := gormtx.NewPublisher(bus.Publisher(),
+//	    gormtx.WithErrorHandler(func(ctx context.Context, topic string, msg *messaging.Message, err error) {
+//	        compensation.Save(ctx, topic, msg)
+//	    }),
+//	)
+//
+//	err := uow.WithinTransaction(ctx, func(txCtx context.Context) error {
+//	    if err := repo.Save(txCtx, order); err != nil {
+//	        return err
+//	    }
+//	    return publisher.Publish(txCtx, "order.created", payload) // 提交后发出
+//	})
+package gormtx
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/FangcunMount/component-base/pkg/messaging"
+	gormuow "github.com/FangcunMount/component-base/pkg/uow/gorm"
+)
+
+// ErrorHandler 提交后发布失败的回调
+type ErrorHandler func(ctx context.Context, topic string, msg *messaging.Message, err error)
+
+// Metrics 事务发布指标收集器
+type Metrics interface {
+	// IncrementDeferred 增加排队等待提交的消息计数
+	IncrementDeferred(topic string)
+
+	// IncrementPublishedAfterCommit 增加提交后发布成功计数
+	IncrementPublishedAfterCommit(topic string)
+
+	// IncrementFailedAfterCommit 增加提交后发布失败计数
+	IncrementFailedAfterCommit(topic string)
+}
+
+// Option Publisher 配置项
+type Option func(*Publisher)
+
+// WithErrorHandler 设置提交后发布失败的回调（默认写日志）
+func WithErrorHandler(handler ErrorHandler) Option {
+	return func(p *Publisher) {
+		p.onError = handler
+	}
+}
+
+// WithMetrics 设置指标收集器
+func WithMetrics(metrics Metrics) Option {
+	return func(p *Publisher) {
+		p.metrics = metrics
+	}
+}
+
+// Publisher 事务提交后发布消息的装饰器，实现 messaging.Publisher
+type Publisher struct {
+	next    messaging.Publisher
+	onError ErrorHandler
+	metrics Metrics
+}
+
+// NewPublisher 创建事务提交后发布的装饰器
+func NewPublisher(next messaging.Publisher, opts ...Option) *Publisher {
+	p := &Publisher{next: next}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(p)
+		}
+	}
+	if p.onError == nil {
+		p.onError = func(_ context.Context, topic string, msg *messaging.Message, err error) {
+			log.Printf("[messaging] publish after commit failed: topic=%s, uuid=%s, err=%v", topic, msg.UUID, err)
+		}
+	}
+	return p
+}
+
+// Publish 实现 messaging.Publisher 接口
+func (p *Publisher) Publish(ctx context.Context, topic string, body []byte) error {
+	return p.PublishMessage(ctx, topic, messaging.NewMessage("", body))
+}
+
+// PublishMessage 实现 messaging.Publisher 接口
+// 事务内只排队（复制消息，调用方之后修改 msg 不影响发出的内容），不在事务内时直接发布
+func (p *Publisher) PublishMessage(ctx context.Context, topic string, msg *messaging.Message) error {
+	if _, ok := gormuow.TxFromContext(ctx); !ok {
+		return p.next.PublishMessage(ctx, topic, msg)
+	}
+
+	if msg == nil {
+		return fmt.Errorf("message cannot be nil")
+	}
+	queued := copyMessage(msg)
+	if err := gormuow.AfterCommit(ctx, func(commitCtx context.Context) error {
+		return p.publishAfterCommit(commitCtx, topic, queued)
+	}); err != nil {
+		return err
+	}
+	if p.metrics != nil {
+		p.metrics.IncrementDeferred(topic)
+	}
+	return nil
+}
+
+// Close 关闭被装饰的发布者
+func (p *Publisher) Close() error {
+	return p.next.Close()
+}
+
+func (p *Publisher) publishAfterCommit(ctx context.Context, topic string, msg *messaging.Message) error {
+	if err := p.next.PublishMessage(ctx, topic, msg); err != nil {
+		if p.metrics != nil {
+			p.metrics.IncrementFailedAfterCommit(topic)
+		}
+		p.onError(ctx, topic, msg, err)
+		return fmt.Errorf("publish %s after commit: %w", topic, err)
+	}
+	if p.metrics != nil {
+		p.metrics.IncrementPublishedAfterCommit(topic)
+	}
+	return nil
+}
+
+// copyMessage 复制消息内容（不含确认状态）
+func copyMessage(msg *messaging.Message) *messaging.Message {
+	md := make(map[string]string, len(msg.Metadata))
+	for k, v := range msg.Metadata {
+		md[k] = v
+	}
+	return &messaging.Message{
+		UUID:     msg.UUID,
+		Metadata: md,
+		Payload:  append([]byte(nil), msg.Payload...),
+	}
+}
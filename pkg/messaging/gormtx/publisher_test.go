@@ -0,0 +1,211 @@
+package gormtx
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gmysql "gorm.io/driver/mysql"
+	"gorm.io/gorm"
+
+	"github.com/FangcunMount/component-base/pkg/messaging"
+	"github.com/FangcunMount/component-base/pkg/messaging/messagingtest"
+	gormuow "github.com/FangcunMount/component-base/pkg/uow/gorm"
+)
+
+type countingMetrics struct {
+	mu        sync.Mutex
+	deferred  map[string]int
+	published map[string]int
+	failed    map[string]int
+}
+
+func newCountingMetrics() *countingMetrics {
+	return &countingMetrics{
+		deferred:  make(map[string]int),
+		published: make(map[string]int),
+		failed:    make(map[string]int),
+	}
+}
+
+func (m *countingMetrics) IncrementDeferred(topic string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deferred[topic]++
+}
+
+func (m *countingMetrics) IncrementPublishedAfterCommit(topic string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.published[topic]++
+}
+
+func (m *countingMetrics) IncrementFailedAfterCommit(topic string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failed[topic]++
+}
+
+func newMockGORM(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	db, err := gorm.Open(gmysql.New(gmysql.Config{
+		Conn:                      sqlDB,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("gorm.Open: %v", err)
+	}
+	return db, mock
+}
+
+func TestPublishWithoutTransactionPublishesImmediately(t *testing.T) {
+	inner := messagingtest.NewPublisher()
+	metrics := newCountingMetrics()
+	pub := NewPublisher(inner, WithMetrics(metrics))
+
+	if err := pub.Publish(context.Background(), "order.created", []byte("1")); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	inner.AssertPublishedCount(t, "order.created", 1)
+	if metrics.deferred["order.created"] != 0 {
+		t.Fatalf("deferred = %d, want 0 outside a transaction", metrics.deferred["order.created"])
+	}
+}
+
+func TestPublishWithinTransactionWaitsForCommit(t *testing.T) {
+	db, mock := newMockGORM(t)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	inner := messagingtest.NewPublisher()
+	metrics := newCountingMetrics()
+	pub := NewPublisher(inner, WithMetrics(metrics))
+
+	err := gormuow.NewUnitOfWork(db).WithinTransaction(context.Background(), func(txCtx context.Context) error {
+		msg := messaging.NewMessage("m-1", []byte("first"))
+		msg.Metadata["k"] = "v"
+		if err := pub.PublishMessage(txCtx, "order.created", msg); err != nil {
+			return err
+		}
+		// 排队后修改不影响发出的内容
+		msg.Payload[0] = 'X'
+		msg.Metadata["k"] = "changed"
+		if err := pub.Publish(txCtx, "order.created", []byte("second")); err != nil {
+			return err
+		}
+		inner.AssertNotPublished(t, "order.created")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithinTransaction() error = %v", err)
+	}
+
+	msgs := inner.AssertPublishedCount(t, "order.created", 2)
+	messagingtest.AssertPayload(t, msgs[0], []byte("first"))
+	messagingtest.AssertMetadata(t, msgs[0], "k", "v")
+	messagingtest.AssertPayload(t, msgs[1], []byte("second"))
+	if metrics.deferred["order.created"] != 2 || metrics.published["order.created"] != 2 {
+		t.Fatalf("metrics = %+v, want 2 deferred and 2 published", metrics)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("sql expectations: %v", err)
+	}
+}
+
+func TestPublishWithinTransactionDiscardedOnRollback(t *testing.T) {
+	db, mock := newMockGORM(t)
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	inner := messagingtest.NewPublisher()
+	pub := NewPublisher(inner)
+
+	fnErr := errors.New("business failed")
+	err := gormuow.NewUnitOfWork(db).WithinTransaction(context.Background(), func(txCtx context.Context) error {
+		if err := pub.Publish(txCtx, "order.created", []byte("1")); err != nil {
+			return err
+		}
+		return fnErr
+	})
+	if !errors.Is(err, fnErr) {
+		t.Fatalf("WithinTransaction() error = %v, want %v", err, fnErr)
+	}
+	inner.AssertNotPublished(t, "order.created")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("sql expectations: %v", err)
+	}
+}
+
+func TestPublishAfterCommitFailureIsReported(t *testing.T) {
+	db, mock := newMockGORM(t)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	publishErr := errors.New("broker down")
+	inner := messagingtest.NewPublisher()
+	inner.FailWith(publishErr)
+	metrics := newCountingMetrics()
+
+	var handled []string
+	pub := NewPublisher(inner,
+		WithMetrics(metrics),
+		WithErrorHandler(func(_ context.Context, topic string, msg *messaging.Message, err error) {
+			if !errors.Is(err, publishErr) {
+				t.Errorf("handler err = %v, want %v", err, publishErr)
+			}
+			handled = append(handled, topic+"/"+msg.UUID)
+		}),
+	)
+
+	var hookErrs []error
+	uow := gormuow.NewUnitOfWork(db, gormuow.WithAfterCommitErrorHandler(func(_ context.Context, err error) {
+		hookErrs = append(hookErrs, err)
+	}))
+	err := uow.WithinTransaction(context.Background(), func(txCtx context.Context) error {
+		return pub.PublishMessage(txCtx, "order.created", messaging.NewMessage("m-1", []byte("1")))
+	})
+	if err != nil {
+		t.Fatalf("WithinTransaction() error = %v, want nil after commit", err)
+	}
+
+	if len(handled) != 1 || handled[0] != "order.created/m-1" {
+		t.Fatalf("handled = %v, want [order.created/m-1]", handled)
+	}
+	if len(hookErrs) != 1 || !errors.Is(hookErrs[0], publishErr) {
+		t.Fatalf("hook errors = %v, want [%v]", hookErrs, publishErr)
+	}
+	if metrics.failed["order.created"] != 1 || metrics.published["order.created"] != 0 {
+		t.Fatalf("metrics = %+v, want 1 failed and 0 published", metrics)
+	}
+}
+
+func TestPublishExternalTransactionRequiresUnitOfWork(t *testing.T) {
+	db, _ := newMockGORM(t)
+	inner := messagingtest.NewPublisher()
+	pub := NewPublisher(inner)
+
+	// WithTx 注入的事务同样会排队，但钩子只由 WithinTransaction 执行
+	ctx := gormuow.WithTx(context.Background(), db)
+	if err := pub.Publish(ctx, "order.created", []byte("1")); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	inner.AssertNotPublished(t, "order.created")
+}
+
+func TestCloseClosesInnerPublisher(t *testing.T) {
+	inner := messagingtest.NewPublisher()
+	if err := NewPublisher(inner).Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if !inner.Closed() {
+		t.Fatal("inner publisher should be closed")
+	}
+}
@@ -8,7 +8,8 @@ import (
 // Helper 提供便捷的消息发布方法
 
 // PublishJSON 发布 JSON 消息
-// 将对象序列化为 JSON 后发布
+// 将对象序列化为 JSON 后发布（不携带 content-type，消费方按默认的 JSON 解码）；
+// 需要选择编码格式时使用 PublishTyped
 func PublishJSON(ctx context.Context, publisher Publisher, topic string, data interface{}) error {
 	body, err := json.Marshal(data)
 	if err != nil {
@@ -41,6 +42,7 @@ func NewSimpleHandler(fn func([]byte) error) Handler {
 
 // NewJSONHandler 创建 JSON 处理器
 // 自动解析 JSON 消息并调用业务处理函数
+// Deprecated: 使用 TypedHandler 代替（按 content-type 解码为具体类型）
 func NewJSONHandler(fn func(ctx context.Context, data interface{}) error, dataType interface{}) Handler {
 	return func(ctx context.Context, msg *Message) error {
 		// 创建新的实例
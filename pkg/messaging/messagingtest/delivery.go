@@ -0,0 +1,125 @@
+package messagingtest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/FangcunMount/component-base/pkg/messaging"
+)
+
+// Delivery 一次投递的结果
+type Delivery struct {
+	// Topic / Channel 投递的主题与通道
+	Topic   string
+	Channel string
+
+	// Message 交给处理器的消息（投递时的副本）
+	Message *messaging.Message
+
+	// Err 处理器返回的错误
+	Err error
+
+	state *deliveryState
+}
+
+// Acked 消息是否已确认（处理器自行 Ack 或成功返回后自动 Ack）
+func (d Delivery) Acked() bool {
+	d.state.mu.Lock()
+	defer d.state.mu.Unlock()
+	return d.state.acked
+}
+
+// Nacked 消息是否已拒绝（处理器自行 Nack 或返回错误后自动 Nack）
+func (d Delivery) Nacked() bool {
+	d.state.mu.Lock()
+	defer d.state.mu.Unlock()
+	return d.state.nacked
+}
+
+// Deferred 处理器是否接管了确认（messaging.Message.Defer）
+func (d Delivery) Deferred() bool {
+	return d.Message.IsDeferred()
+}
+
+// deliveryState 记录确认结果；处理器返回且（未接管确认或已确认/拒绝）时视为处理完成
+type deliveryState struct {
+	mu         sync.Mutex
+	acked      bool
+	nacked     bool
+	returned   bool
+	deferred   bool
+	completed  bool
+	onComplete func()
+}
+
+func (s *deliveryState) settle(ack bool) {
+	s.mu.Lock()
+	if ack {
+		s.acked = true
+	} else {
+		s.nacked = true
+	}
+	s.complete()
+}
+
+func (s *deliveryState) handlerReturned(deferred bool) {
+	s.mu.Lock()
+	s.returned = true
+	s.deferred = deferred
+	s.complete()
+}
+
+// complete 在持有 s.mu 时调用，负责解锁；回调在锁外执行
+func (s *deliveryState) complete() {
+	done := !s.completed && s.returned && (!s.deferred || s.acked || s.nacked)
+	if done {
+		s.completed = true
+	}
+	s.mu.Unlock()
+	if done && s.onComplete != nil {
+		s.onComplete()
+	}
+}
+
+// Deliver 将消息（的副本）交给处理器并按 provider 的语义确认：
+// 处理器未自行确认时，成功返回则 Ack、返回错误则 Nack；调用 Defer 的消息由处理器稍后确认。
+// Attempts 为 0 时按首次投递设为 1
+func Deliver(ctx context.Context, handler messaging.Handler, msg *messaging.Message) Delivery {
+	return *deliver(ctx, handler, msg, nil, nil)
+}
+
+// deliver 投递消息；record 在处理器返回后、处理完成回调之前调用
+func deliver(ctx context.Context, handler messaging.Handler, msg *messaging.Message, record func(*Delivery), onComplete func()) *Delivery {
+	m := copyMessage(msg)
+	if m.Attempts == 0 {
+		m.Attempts = 1
+	}
+	d := &Delivery{
+		Topic:   m.Topic,
+		Channel: m.Channel,
+		Message: m,
+		state:   &deliveryState{onComplete: onComplete},
+	}
+	m.SetAckFunc(func() error {
+		d.state.settle(true)
+		return nil
+	})
+	m.SetNackFunc(func() error {
+		d.state.settle(false)
+		return nil
+	})
+
+	d.Err = handler(ctx, m)
+	if !m.IsDeferred() && !m.IsSettled() {
+		if d.Err != nil {
+			_ = m.Nack()
+		} else {
+			_ = m.Ack()
+		}
+	}
+	if record != nil {
+		record(d)
+	}
+	d.state.handlerReturned(m.IsDeferred())
+	return d
+}
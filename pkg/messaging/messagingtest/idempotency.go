@@ -0,0 +1,71 @@
+package messagingtest
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/FangcunMount/component-base/pkg/messaging"
+)
+
+// DeliverDuplicates 将同一条消息（相同 UUID）依次投递 n 次，Attempts 从 1 递增，模拟 broker 重投
+func DeliverDuplicates(ctx context.Context, handler messaging.Handler, msg *messaging.Message, n int) []Delivery {
+	deliveries := make([]Delivery, n)
+	for i := range deliveries {
+		dup := copyMessage(msg)
+		dup.Attempts = uint16(i + 1)
+		deliveries[i] = Deliver(ctx, handler, dup)
+	}
+	return deliveries
+}
+
+// DeliverConcurrently 将同一条消息并发投递 n 次，模拟多个消费者同时收到重复消息
+func DeliverConcurrently(ctx context.Context, handler messaging.Handler, msg *messaging.Message, n int) []Delivery {
+	deliveries := make([]Delivery, n)
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			deliveries[i] = Deliver(ctx, handler, msg)
+		}(i)
+	}
+	wg.Wait()
+	return deliveries
+}
+
+// AssertIdempotent 断言处理器幂等：首次投递后记录 state()，再重复投递 duplicates 次，
+// 要求每次投递都成功确认，且 state() 与首次投递后一致（reflect.DeepEqual）
+// state 返回处理器产生的副作用快照（如写入的记录、发布的消息数），须返回副本而非可变引用
+//
+// 使用示例：
+//
+//	handler := messaging.DeduplicationMiddleware(dedup, time.Hour)(svc.HandleOrderPaid)
+//	messagingtest.AssertIdempotent(t, handler, msg, 3, func() interface{} {
+//	    return len(pub.MessagesOn("shipment.created"))
+//	})
+func AssertIdempotent(t testing.TB, handler messaging.Handler, msg *messaging.Message, duplicates int, state func() interface{}) {
+	t.Helper()
+
+	first := copyMessage(msg)
+	first.Attempts = 1
+	d := Deliver(context.Background(), handler, first)
+	if d.Err != nil || !d.Acked() {
+		t.Fatalf("first delivery of %s failed: err=%v acked=%v", msg.UUID, d.Err, d.Acked())
+	}
+	want := state()
+
+	for i := 1; i <= duplicates; i++ {
+		dup := copyMessage(msg)
+		dup.Attempts = uint16(i + 1)
+		d := Deliver(context.Background(), handler, dup)
+		if d.Err != nil || !d.Acked() {
+			t.Fatalf("duplicate delivery #%d of %s failed: err=%v acked=%v", i, msg.UUID, d.Err, d.Acked())
+		}
+	}
+	if got := state(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("handler is not idempotent for %s: state after first delivery %v, after %d duplicates %v",
+			msg.UUID, want, duplicates, got)
+	}
+}
@@ -0,0 +1,232 @@
+// Package messagingtest 提供 messaging 的测试工具
+//
+//   - Publisher：记录发布的消息，提供 topic / Metadata / Payload 断言与 JSON 解码
+//   - Subscriber：按脚本把消息投递给处理器，按 provider 的语义自动 Ack/Nack 并记录结果
+//   - RunRouter：运行 Router 直到脚本中的消息全部处理完成
+//   - AssertIdempotent：重复投递同一条消息，检查处理器是否幂等
+//
+// 使用示例：
+//
+//	pub := messagingtest.NewPublisher()
+//	svc := NewOrderService(pub)
+//	svc.Pay(ctx, orderID)
+//
+//	msg := pub.AssertPublished(t, "order.paid")
+//	messagingtest.AssertMetadata(t, msg, messaging.MetadataContentType, messaging.ContentTypeJSON)
+//	event := messagingtest.DecodeJSON[OrderPaid](t, msg)
+package messagingtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/FangcunMount/component-base/pkg/messaging"
+)
+
+// ErrPublisherClosed 发布者已关闭
+var ErrPublisherClosed = errors.New("messagingtest: publisher is closed")
+
+// Published 一次发布记录
+type Published struct {
+	Topic   string
+	Message *messaging.Message
+}
+
+// Publisher 记录发布消息的 messaging.Publisher，并发安全
+// 记录的是发布时刻的副本，调用方之后修改消息不影响断言
+type Publisher struct {
+	mu        sync.Mutex
+	published []Published
+	err       error
+	closed    bool
+}
+
+// NewPublisher 创建记录发布者
+func NewPublisher() *Publisher {
+	return &Publisher{}
+}
+
+// Publish 实现 messaging.Publisher 接口
+func (p *Publisher) Publish(ctx context.Context, topic string, body []byte) error {
+	return p.PublishMessage(ctx, topic, messaging.NewMessage("", body))
+}
+
+// PublishMessage 实现 messaging.Publisher 接口
+func (p *Publisher) PublishMessage(ctx context.Context, topic string, msg *messaging.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return ErrPublisherClosed
+	}
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, Published{Topic: topic, Message: copyMessage(msg)})
+	return nil
+}
+
+// Close 实现 messaging.Publisher 接口
+func (p *Publisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	return nil
+}
+
+// Closed 是否已调用 Close
+func (p *Publisher) Closed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+// FailWith 之后的发布都返回 err（nil 恢复正常），用于测试发布失败的处理
+func (p *Publisher) FailWith(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+// Reset 清空发布记录
+func (p *Publisher) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.published = nil
+}
+
+// Messages 返回所有发布记录（按发布顺序）
+func (p *Publisher) Messages() []Published {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Published(nil), p.published...)
+}
+
+// MessagesOn 返回发布到 topic 的消息（按发布顺序）
+func (p *Publisher) MessagesOn(topic string) []*messaging.Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var msgs []*messaging.Message
+	for _, pub := range p.published {
+		if pub.Topic == topic {
+			msgs = append(msgs, pub.Message)
+		}
+	}
+	return msgs
+}
+
+// AssertPublished 断言 topic 上至少发布过一条消息，返回最后一条
+func (p *Publisher) AssertPublished(t testing.TB, topic string) *messaging.Message {
+	t.Helper()
+	msgs := p.MessagesOn(topic)
+	if len(msgs) == 0 {
+		t.Fatalf("no message published to %s (published topics: %v)", topic, p.topics())
+	}
+	return msgs[len(msgs)-1]
+}
+
+// AssertPublishedCount 断言 topic 上恰好发布了 n 条消息，返回这些消息
+func (p *Publisher) AssertPublishedCount(t testing.TB, topic string, n int) []*messaging.Message {
+	t.Helper()
+	msgs := p.MessagesOn(topic)
+	if len(msgs) != n {
+		t.Fatalf("published %d messages to %s, want %d", len(msgs), topic, n)
+	}
+	return msgs
+}
+
+// AssertNotPublished 断言 topic 上没有发布任何消息
+func (p *Publisher) AssertNotPublished(t testing.TB, topic string) {
+	t.Helper()
+	if msgs := p.MessagesOn(topic); len(msgs) != 0 {
+		t.Fatalf("published %d messages to %s, want none", len(msgs), topic)
+	}
+}
+
+func (p *Publisher) topics() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	seen := make(map[string]bool)
+	var topics []string
+	for _, pub := range p.published {
+		if !seen[pub.Topic] {
+			seen[pub.Topic] = true
+			topics = append(topics, pub.Topic)
+		}
+	}
+	return topics
+}
+
+// AssertMetadata 断言消息的 Metadata[key] 等于 want
+func AssertMetadata(t testing.TB, msg *messaging.Message, key, want string) {
+	t.Helper()
+	got, ok := msg.Metadata[key]
+	if !ok {
+		t.Fatalf("message %s has no metadata %q", msg.UUID, key)
+	}
+	if got != want {
+		t.Fatalf("message %s metadata %q = %q, want %q", msg.UUID, key, got, want)
+	}
+}
+
+// AssertPayload 断言消息的 Payload 等于 want
+func AssertPayload(t testing.TB, msg *messaging.Message, want []byte) {
+	t.Helper()
+	if !bytes.Equal(msg.Payload, want) {
+		t.Fatalf("message %s payload = %q, want %q", msg.UUID, msg.Payload, want)
+	}
+}
+
+// AssertJSONPayload 断言消息的 JSON Payload 与 want 序列化后的 JSON 语义相等（忽略字段顺序与空白）
+func AssertJSONPayload(t testing.TB, msg *messaging.Message, want interface{}) {
+	t.Helper()
+	wantJSON, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("marshal expected payload: %v", err)
+	}
+
+	var got, expected interface{}
+	if err := json.Unmarshal(msg.Payload, &got); err != nil {
+		t.Fatalf("message %s payload is not JSON: %v (payload %q)", msg.UUID, err, msg.Payload)
+	}
+	if err := json.Unmarshal(wantJSON, &expected); err != nil {
+		t.Fatalf("unmarshal expected payload: %v", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("message %s payload = %s, want %s", msg.UUID, msg.Payload, wantJSON)
+	}
+}
+
+// DecodeJSON 将消息的 JSON Payload 解码为 T，失败时终止测试
+func DecodeJSON[T any](t testing.TB, msg *messaging.Message) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(msg.Payload, &v); err != nil {
+		t.Fatalf("decode message %s payload as %T: %v (payload %q)", msg.UUID, v, err, msg.Payload)
+	}
+	return v
+}
+
+// copyMessage 复制消息内容（不含确认状态）
+func copyMessage(msg *messaging.Message) *messaging.Message {
+	md := make(map[string]string, len(msg.Metadata))
+	for k, v := range msg.Metadata {
+		md[k] = v
+	}
+	return &messaging.Message{
+		UUID:      msg.UUID,
+		Metadata:  md,
+		Payload:   append([]byte(nil), msg.Payload...),
+		Attempts:  msg.Attempts,
+		Timestamp: msg.Timestamp,
+		Topic:     msg.Topic,
+		Channel:   msg.Channel,
+	}
+}
@@ -0,0 +1,116 @@
+package messagingtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/FangcunMount/component-base/pkg/messaging"
+)
+
+// recordingT 记录断言失败的 testing.TB；Fatalf 与真实实现一样终止当前 goroutine
+type recordingT struct {
+	testing.TB
+	failure string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Fatalf(format string, args ...interface{}) {
+	t.failure = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+// expectFailure 运行断言，返回失败信息（未失败时为空）
+func expectFailure(t *testing.T, assert func(tb testing.TB)) string {
+	t.Helper()
+	rt := &recordingT{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert(rt)
+	}()
+	<-done
+	return rt.failure
+}
+
+type orderPaid struct {
+	OrderID string `json:"order_id"`
+	Amount  int    `json:"amount"`
+}
+
+func TestPublisherRecordsMessages(t *testing.T) {
+	pub := NewPublisher()
+	ctx := context.Background()
+
+	if err := messaging.PublishJSON(ctx, pub, "order.paid", orderPaid{OrderID: "o-1", Amount: 100}); err != nil {
+		t.Fatalf("PublishJSON: %v", err)
+	}
+	msg := messaging.NewMessage("m-2", []byte(`{"amount":200,"order_id":"o-2"}`))
+	msg.Metadata[messaging.MetadataContentType] = messaging.ContentTypeJSON
+	if err := pub.PublishMessage(ctx, "order.paid", msg); err != nil {
+		t.Fatalf("PublishMessage: %v", err)
+	}
+	msg.Metadata[messaging.MetadataContentType] = "mutated"
+	if err := pub.Publish(ctx, "audit.log", []byte("raw")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	pub.AssertPublishedCount(t, "order.paid", 2)
+	last := pub.AssertPublished(t, "order.paid")
+	AssertMetadata(t, last, messaging.MetadataContentType, messaging.ContentTypeJSON)
+	AssertJSONPayload(t, last, orderPaid{OrderID: "o-2", Amount: 200})
+	if event := DecodeJSON[orderPaid](t, last); event.Amount != 200 {
+		t.Fatalf("decoded = %+v", event)
+	}
+	AssertPayload(t, pub.AssertPublished(t, "audit.log"), []byte("raw"))
+	pub.AssertNotPublished(t, "order.refunded")
+
+	if got := len(pub.Messages()); got != 3 {
+		t.Fatalf("Messages = %d", got)
+	}
+	pub.Reset()
+	pub.AssertNotPublished(t, "order.paid")
+}
+
+func TestPublisherAssertionsFail(t *testing.T) {
+	pub := NewPublisher()
+	_ = pub.Publish(context.Background(), "order.paid", []byte(`{"order_id":"o-1","amount":100}`))
+	msg := pub.AssertPublished(t, "order.paid")
+
+	tests := map[string]func(tb testing.TB){
+		"missing topic": func(tb testing.TB) { pub.AssertPublished(tb, "order.refunded") },
+		"count":         func(tb testing.TB) { pub.AssertPublishedCount(tb, "order.paid", 2) },
+		"not published": func(tb testing.TB) { pub.AssertNotPublished(tb, "order.paid") },
+		"metadata":      func(tb testing.TB) { AssertMetadata(tb, msg, "tenant", "t-1") },
+		"payload":       func(tb testing.TB) { AssertPayload(tb, msg, []byte("other")) },
+		"json":          func(tb testing.TB) { AssertJSONPayload(tb, msg, orderPaid{OrderID: "o-1", Amount: 1}) },
+		"decode":        func(tb testing.TB) { DecodeJSON[[]string](tb, msg) },
+	}
+	for name, assert := range tests {
+		if failure := expectFailure(t, assert); failure == "" {
+			t.Fatalf("%s: assertion should fail", name)
+		}
+	}
+}
+
+func TestPublisherFailures(t *testing.T) {
+	pub := NewPublisher()
+	brokerDown := errors.New("broker down")
+	pub.FailWith(brokerDown)
+	if err := pub.Publish(context.Background(), "order.paid", nil); !errors.Is(err, brokerDown) {
+		t.Fatalf("Publish = %v, want injected error", err)
+	}
+	pub.FailWith(nil)
+	pub.AssertNotPublished(t, "order.paid")
+
+	_ = pub.Close()
+	if !pub.Closed() {
+		t.Fatalf("Closed = false")
+	}
+	if err := pub.Publish(context.Background(), "order.paid", nil); !errors.Is(err, ErrPublisherClosed) {
+		t.Fatalf("Publish after Close = %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package messagingtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/FangcunMount/component-base/pkg/messaging"
+)
+
+// DefaultRunTimeout RunRouter 等待处理完成的超时
+const DefaultRunTimeout = 5 * time.Second
+
+// RunRouter 运行路由器直到脚本中的消息全部处理完成，然后停止路由器并返回投递记录
+// router 必须使用 sub 创建（messaging.NewRouter(sub)）；订阅失败或超时（DefaultRunTimeout）时终止测试
+//
+// 使用示例：
+//
+//	sub := messagingtest.NewSubscriber()
+//	sub.Enqueue("order.paid", messaging.NewMessage("o-1", payload))
+//	router := messaging.NewRouter(sub)
+//	router.AddHandler("order.paid", "fulfillment", handler)
+//
+//	for _, d := range messagingtest.RunRouter(t, router, sub) {
+//	    if !d.Acked() { t.Errorf("%s not acked: %v", d.Message.UUID, d.Err) }
+//	}
+func RunRouter(t testing.TB, router *messaging.Router, sub *Subscriber) []Delivery {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultRunTimeout)
+	defer cancel()
+	defer router.Stop()
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- router.Run(ctx) }()
+
+	handlers := len(router.Stats())
+	for {
+		subscriptions, pending, changed := sub.progress()
+		if subscriptions >= handlers && pending == 0 {
+			break
+		}
+		select {
+		case err := <-runErr:
+			t.Fatalf("router stopped before processing completed: %v", err)
+		case <-changed:
+		case <-ctx.Done():
+			t.Fatalf("router did not complete within %s: %d/%d handlers subscribed, %d deliveries pending",
+				DefaultRunTimeout, subscriptions, handlers, pending)
+		}
+	}
+
+	router.Stop()
+	if err := <-runErr; err != nil {
+		t.Fatalf("router run: %v", err)
+	}
+	return sub.Deliveries()
+}
@@ -0,0 +1,227 @@
+package messagingtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/FangcunMount/component-base/pkg/messaging"
+)
+
+// ErrSubscriberStopped 订阅者已停止
+var ErrSubscriberStopped = errors.New("messagingtest: subscriber is stopped")
+
+// Subscriber 按脚本投递消息的 messaging.Subscriber，并发安全
+//
+// Enqueue 的消息投递给该 topic 的每个订阅（与 NSQ channel / 消费组一致，每个订阅各收到一份），
+// 订阅之前入队的消息在订阅时投递。同一订阅内按入队顺序逐条投递，记录每次投递的 Ack/Nack 与错误。
+type Subscriber struct {
+	mu         sync.Mutex
+	script     map[string][]*messaging.Message
+	subs       map[string]*subscription
+	deliveries []*Delivery
+	pending    int
+	changed    chan struct{}
+	stopped    bool
+}
+
+type subscription struct {
+	topic   string
+	channel string
+	handler messaging.Handler
+	queue   []*messaging.Message
+	running bool
+	removed bool
+	wg      sync.WaitGroup
+}
+
+// NewSubscriber 创建脚本订阅者
+func NewSubscriber() *Subscriber {
+	return &Subscriber{
+		script:  make(map[string][]*messaging.Message),
+		subs:    make(map[string]*subscription),
+		changed: make(chan struct{}),
+	}
+}
+
+// Enqueue 向 topic 追加待投递的消息
+func (s *Subscriber) Enqueue(topic string, msgs ...*messaging.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.script[topic] = append(s.script[topic], msgs...)
+	for _, sub := range s.subs {
+		if sub.topic == topic {
+			s.scheduleLocked(sub, msgs)
+		}
+	}
+}
+
+// Subscribe 实现 messaging.Subscriber 接口
+func (s *Subscriber) Subscribe(topic, channel string, handler messaging.Handler) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return ErrSubscriberStopped
+	}
+	key := topic + ":" + channel
+	if _, exists := s.subs[key]; exists {
+		return fmt.Errorf("already subscribed to %s", key)
+	}
+	sub := &subscription{topic: topic, channel: channel, handler: handler}
+	s.subs[key] = sub
+	s.scheduleLocked(sub, s.script[topic])
+	s.notifyLocked()
+	return nil
+}
+
+// SubscribeWithMiddleware 实现 messaging.Subscriber 接口
+func (s *Subscriber) SubscribeWithMiddleware(topic, channel string, handler messaging.Handler, middlewares ...messaging.Middleware) error {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return s.Subscribe(topic, channel, handler)
+}
+
+// Unsubscribe 实现 messaging.Unsubscriber 接口
+// 未投递的消息被丢弃，等待处理中的消息完成
+func (s *Subscriber) Unsubscribe(topic, channel string) error {
+	key := topic + ":" + channel
+
+	s.mu.Lock()
+	sub, ok := s.subs[key]
+	if !ok {
+		s.mu.Unlock()
+		return nil
+	}
+	delete(s.subs, key)
+	s.dropLocked(sub)
+	s.mu.Unlock()
+
+	sub.wg.Wait()
+	return nil
+}
+
+// Stop 实现 messaging.Subscriber 接口
+// 停止投递，未投递的消息被丢弃
+func (s *Subscriber) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stopped = true
+	for _, sub := range s.subs {
+		s.dropLocked(sub)
+	}
+}
+
+// Close 实现 messaging.Subscriber 接口
+func (s *Subscriber) Close() error {
+	s.Stop()
+	return nil
+}
+
+// Wait 等待所有已入队的消息处理完成（接管确认的消息需已确认或拒绝）
+func (s *Subscriber) Wait(ctx context.Context) error {
+	for {
+		s.mu.Lock()
+		pending, changed := s.pending, s.changed
+		s.mu.Unlock()
+
+		if pending == 0 {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return fmt.Errorf("%d deliveries pending: %w", pending, ctx.Err())
+		}
+	}
+}
+
+// Deliveries 返回已完成处理器调用的投递记录（按处理器返回的顺序）
+func (s *Subscriber) Deliveries() []Delivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deliveries := make([]Delivery, len(s.deliveries))
+	for i, d := range s.deliveries {
+		deliveries[i] = *d
+	}
+	return deliveries
+}
+
+// Subscribed 是否存在 topic/channel 的订阅
+func (s *Subscriber) Subscribed(topic, channel string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.subs[topic+":"+channel]
+	return ok
+}
+
+// progress 返回订阅数、未完成的投递数与状态变化通知
+func (s *Subscriber) progress() (subscriptions, pending int, changed <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subs), s.pending, s.changed
+}
+
+func (s *Subscriber) scheduleLocked(sub *subscription, msgs []*messaging.Message) {
+	if s.stopped || sub.removed || len(msgs) == 0 {
+		return
+	}
+	sub.queue = append(sub.queue, msgs...)
+	s.pending += len(msgs)
+	if !sub.running {
+		sub.running = true
+		sub.wg.Add(1)
+		go s.run(sub)
+	}
+}
+
+func (s *Subscriber) dropLocked(sub *subscription) {
+	sub.removed = true
+	s.pending -= len(sub.queue)
+	sub.queue = nil
+	s.notifyLocked()
+}
+
+// run 逐条投递订阅队列中的消息
+func (s *Subscriber) run(sub *subscription) {
+	defer sub.wg.Done()
+
+	for {
+		s.mu.Lock()
+		if sub.removed || len(sub.queue) == 0 {
+			sub.running = false
+			s.mu.Unlock()
+			return
+		}
+		msg := sub.queue[0]
+		sub.queue = sub.queue[1:]
+		s.mu.Unlock()
+
+		m := copyMessage(msg)
+		m.Topic, m.Channel = sub.topic, sub.channel
+		deliver(context.Background(), sub.handler, m, s.record, s.completed)
+	}
+}
+
+func (s *Subscriber) record(d *Delivery) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries = append(s.deliveries, d)
+}
+
+func (s *Subscriber) completed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending--
+	s.notifyLocked()
+}
+
+func (s *Subscriber) notifyLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
@@ -0,0 +1,151 @@
+package messagingtest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/FangcunMount/component-base/pkg/messaging"
+)
+
+func TestDeliverSettlesLikeProviders(t *testing.T) {
+	failed := errors.New("failed")
+	tests := map[string]struct {
+		handler  messaging.Handler
+		acked    bool
+		nacked   bool
+		err      error
+		deferred bool
+	}{
+		"success": {handler: func(ctx context.Context, msg *messaging.Message) error { return nil }, acked: true},
+		"error":   {handler: func(ctx context.Context, msg *messaging.Message) error { return failed }, nacked: true, err: failed},
+		"manual nack": {handler: func(ctx context.Context, msg *messaging.Message) error {
+			return msg.Nack()
+		}, nacked: true},
+		"deferred": {handler: func(ctx context.Context, msg *messaging.Message) error {
+			msg.Defer()
+			return nil
+		}, deferred: true},
+	}
+	for name, tt := range tests {
+		d := Deliver(context.Background(), tt.handler, messaging.NewMessage("m-1", nil))
+		if d.Acked() != tt.acked || d.Nacked() != tt.nacked || !errors.Is(d.Err, tt.err) || d.Deferred() != tt.deferred {
+			t.Fatalf("%s: acked=%v nacked=%v err=%v deferred=%v", name, d.Acked(), d.Nacked(), d.Err, d.Deferred())
+		}
+		if d.Message.Attempts != 1 {
+			t.Fatalf("%s: attempts = %d, want 1", name, d.Message.Attempts)
+		}
+	}
+}
+
+func TestRunRouterProcessesScriptedMessages(t *testing.T) {
+	sub := NewSubscriber()
+	sub.Enqueue("order.paid",
+		messaging.NewMessage("o-1", []byte("ok")),
+		messaging.NewMessage("o-2", []byte("fail")),
+	)
+
+	var mu sync.Mutex
+	calls := 0
+	handler := func(ctx context.Context, msg *messaging.Message) error {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		if string(msg.Payload) == "fail" {
+			return errors.New("rejected")
+		}
+		return nil
+	}
+	router := messaging.NewRouter(sub)
+	router.AddHandler("order.paid", "fulfillment", handler)
+	router.AddHandler("order.paid", "billing", handler)
+
+	// 每个 channel 各收到一份
+	deliveries := RunRouter(t, router, sub)
+	if len(deliveries) != 4 || calls != 4 {
+		t.Fatalf("deliveries = %d, handler calls = %d, want 4", len(deliveries), calls)
+	}
+	for _, d := range deliveries {
+		ok := d.Message.UUID == "o-1"
+		if d.Acked() != ok || d.Nacked() == ok || d.Topic != "order.paid" || (d.Err == nil) != ok {
+			t.Fatalf("delivery %s/%s: acked=%v nacked=%v err=%v", d.Channel, d.Message.UUID, d.Acked(), d.Nacked(), d.Err)
+		}
+	}
+
+	// 路由器停止后不再投递
+	sub.Enqueue("order.paid", messaging.NewMessage("o-3", nil))
+	if err := sub.Wait(context.Background()); err != nil || len(sub.Deliveries()) != 4 {
+		t.Fatalf("stopped subscriber delivered new messages")
+	}
+}
+
+func TestRunRouterWaitsForDeferredAcks(t *testing.T) {
+	sub := NewSubscriber()
+	for _, id := range []string{"a", "b", "c"} {
+		sub.Enqueue("metrics.sample", messaging.NewMessage(id, nil))
+	}
+	batch := messaging.NewBatchConsumer(messaging.BatchConfig{Size: 10, FlushInterval: 20 * time.Millisecond},
+		func(ctx context.Context, msgs []*messaging.Message) error { return nil })
+	defer batch.Close()
+	router := messaging.NewRouter(sub)
+	router.AddHandler("metrics.sample", "aggregator", batch.Handler())
+
+	deliveries := RunRouter(t, router, sub)
+	if len(deliveries) != 3 {
+		t.Fatalf("deliveries = %d", len(deliveries))
+	}
+	for _, d := range deliveries {
+		if !d.Deferred() || !d.Acked() {
+			t.Fatalf("delivery %s: deferred=%v acked=%v", d.Message.UUID, d.Deferred(), d.Acked())
+		}
+	}
+}
+
+func TestAssertIdempotent(t *testing.T) {
+	processed := make(map[string]bool)
+	idempotent := func(ctx context.Context, msg *messaging.Message) error {
+		processed[msg.UUID] = true
+		return nil
+	}
+	AssertIdempotent(t, idempotent, messaging.NewMessage("o-1", nil), 2, func() interface{} { return len(processed) })
+
+	counter := 0
+	naive := func(ctx context.Context, msg *messaging.Message) error {
+		counter++
+		return nil
+	}
+	failure := expectFailure(t, func(tb testing.TB) {
+		AssertIdempotent(tb, naive, messaging.NewMessage("o-1", nil), 2, func() interface{} { return counter })
+	})
+	if failure == "" {
+		t.Fatalf("non-idempotent handler should fail the assertion")
+	}
+
+	duplicates := DeliverDuplicates(context.Background(), naive, messaging.NewMessage("o-2", nil), 3)
+	if last := duplicates[2].Message; last.Attempts != 3 || last.UUID != "o-2" {
+		t.Fatalf("third duplicate = %+v", last)
+	}
+
+	var mu sync.Mutex
+	first := 0
+	claimOnce := func(ctx context.Context, msg *messaging.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if first > 0 {
+			return errors.New("in progress")
+		}
+		first++
+		return nil
+	}
+	acked := 0
+	for _, d := range DeliverConcurrently(context.Background(), claimOnce, messaging.NewMessage("o-3", nil), 5) {
+		if d.Acked() {
+			acked++
+		}
+	}
+	if acked != 1 {
+		t.Fatalf("acked concurrent duplicates = %d, want 1", acked)
+	}
+}
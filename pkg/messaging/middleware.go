@@ -2,6 +2,7 @@ package messaging
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -41,7 +42,7 @@ func LoggerMiddleware(logger *log.Logger) Middleware {
 // ========== 重试中间件 ==========
 
 // RetryMiddleware 重试中间件
-// 在消息处理失败时自动重试
+// 在消息处理失败时自动重试（永久错误不重试，见 Permanent）
 // maxRetries: 最大重试次数
 // delay: 每次重试的延迟时间（指数退避）
 func RetryMiddleware(maxRetries int, delay time.Duration) Middleware {
@@ -55,6 +56,11 @@ func RetryMiddleware(maxRetries int, delay time.Duration) Middleware {
 					return nil
 				}
 
+				// 永久错误重试无意义
+				if IsPermanent(err) {
+					return err
+				}
+
 				// 最后一次重试失败，直接返回错误
 				if i == maxRetries {
 					break
@@ -175,8 +181,9 @@ func TracingMiddleware() Middleware {
 				msg.Metadata["trace_id"] = traceID
 			}
 
-			// 将 trace_id 注入到 context
+			// 将 trace_id 注入到 context（兼容旧的字符串 key，同时恢复 pkg/log 追踪上下文）
 			ctx = context.WithValue(ctx, "trace_id", traceID)
+			ctx = ExtractTraceContext(ctx, msg)
 
 			return next(ctx, msg)
 		}
@@ -185,35 +192,82 @@ func TracingMiddleware() Middleware {
 
 // ========== 去重中间件 ==========
 
+// DeduplicationState 认领消息的结果
+type DeduplicationState int
+
+const (
+	// DeduplicationClaimed 认领成功，由当前消费者处理
+	DeduplicationClaimed DeduplicationState = iota
+
+	// DeduplicationDone 消息已处理完成
+	DeduplicationDone
+
+	// DeduplicationInProgress 消息正由其他消费者处理
+	DeduplicationInProgress
+)
+
+// ErrMessageInProgress 消息正由其他消费者处理，稍后重投时再判断
+var ErrMessageInProgress = errors.New("messaging: message is being processed by another consumer")
+
+// ErrDeduplicationClaimLost 认领已过期并被其他消费者重新认领，完成/释放不再生效
+var ErrDeduplicationClaimLost = errors.New("messaging: deduplication claim expired and was taken over")
+
 // DeduplicationStore 去重存储接口
+// 以“认领 → 完成/释放”保证同一消息只被一个消费者处理，实现见 redisdedup 包
 type DeduplicationStore interface {
-	// Exists 检查消息是否已处理
-	Exists(uuid string) bool
+	// Claim 原子地认领消息；认领在处理超时（由实现决定）后自动失效
+	// 认领成功时返回本次认领的令牌，Complete/Release 只对持有该令牌的认领生效
+	Claim(ctx context.Context, uuid string) (DeduplicationState, string, error)
+
+	// Complete 标记消息已处理，在 ttl 内的重复消息会被跳过
+	// 认领已被其他消费者接管时返回 ErrDeduplicationClaimLost，且不覆盖对方的认领
+	Complete(ctx context.Context, uuid, token string, ttl time.Duration) error
 
-	// Mark 标记消息已处理
-	Mark(uuid string, ttl time.Duration) error
+	// Release 释放认领（处理失败），允许重投的消息再次处理
+	// 认领已被其他消费者接管时返回 ErrDeduplicationClaimLost，且不删除对方的认领
+	Release(ctx context.Context, uuid, token string) error
 }
 
 // DeduplicationMiddleware 创建去重中间件
-// 防止重复处理相同的消息
+// 防止重复处理相同的消息：
+//   - 已处理的消息直接跳过（确认）
+//   - 正在被其他消费者处理的消息返回 ErrMessageInProgress（Nack，稍后重投）
+//   - 去重存储不可用时返回错误（Nack），不会在无法去重时处理消息
+//
+// ttl: 已处理标记的保留时间
 func DeduplicationMiddleware(store DeduplicationStore, ttl time.Duration) Middleware {
 	return func(next Handler) Handler {
 		return func(ctx context.Context, msg *Message) error {
-			// 检查消息是否已处理
-			if store.Exists(msg.UUID) {
+			state, token, err := store.Claim(ctx, msg.UUID)
+			if err != nil {
+				return fmt.Errorf("claim message %s for deduplication: %w", msg.UUID, err)
+			}
+			switch state {
+			case DeduplicationDone:
 				// 已处理，直接跳过
 				return nil
+			case DeduplicationInProgress:
+				return fmt.Errorf("%w: %s", ErrMessageInProgress, msg.UUID)
 			}
 
 			// 处理消息
-			err := next(ctx, msg)
+			err = next(ctx, msg)
 
-			// 只有成功处理后才标记
-			if err == nil {
-				store.Mark(msg.UUID, ttl)
+			// 去重存储的后续操作不受处理器 ctx 取消的影响
+			storeCtx := context.WithoutCancel(ctx)
+			if err != nil {
+				if releaseErr := store.Release(storeCtx, msg.UUID, token); releaseErr != nil {
+					log.Printf("[messaging] release deduplication claim for message %s failed: %v", msg.UUID, releaseErr)
+				}
+				return err
 			}
 
-			return err
+			// 消息已处理成功，标记失败只记录日志，避免重投导致重复处理
+			// 认领已被接管时由接管的消费者负责标记
+			if markErr := store.Complete(storeCtx, msg.UUID, token, ttl); markErr != nil {
+				log.Printf("[messaging] mark message %s as processed failed: %v", msg.UUID, markErr)
+			}
+			return nil
 		}
 	}
 }
@@ -328,17 +382,27 @@ type CircuitBreaker interface {
 
 // CircuitBreakerMiddleware 熔断器中间件
 // 防止级联故障，当错误率超过阈值时自动熔断
+// 熔断期间被拒绝的消息会 Nack 重新入队，可通过 PauseOnOpen 在熔断期间暂停拉取。
+// 拒绝（处理器未执行）标记为临时错误（见 Transient），DeadLetterMiddleware 不计入失败次数，
+// 下游故障期间健康的消息不会因熔断被转入死信主题
 func CircuitBreakerMiddleware(breaker CircuitBreaker) Middleware {
 	return func(next Handler) Handler {
 		return func(ctx context.Context, msg *Message) error {
-			return breaker.Call(func() error {
+			called := false
+			err := breaker.Call(func() error {
+				called = true
 				return next(ctx, msg)
 			})
+			if err != nil && !called {
+				return Transient(err)
+			}
+			return err
 		}
 	}
 }
 
-// SimpleCircuitBreaker 简单熔断器实现
+// SimpleCircuitBreaker 简单熔断器实现（连续失败计数，非并发安全）
+// 需要失败率窗口、半开探测或状态监听时使用 SlidingWindowCircuitBreaker
 type SimpleCircuitBreaker struct {
 	maxFailures  int           // 最大失败次数
 	timeout      time.Duration // 熔断超时时间
@@ -354,18 +418,18 @@ func NewSimpleCircuitBreaker(maxFailures int, timeout time.Duration) *SimpleCirc
 	return &SimpleCircuitBreaker{
 		maxFailures: maxFailures,
 		timeout:     timeout,
-		state:       "closed",
+		state:       CircuitClosed,
 	}
 }
 
 // Call 执行调用（带熔断保护）
 func (cb *SimpleCircuitBreaker) Call(fn func() error) error {
 	// 检查是否可以尝试恢复
-	if cb.state == "open" {
+	if cb.state == CircuitOpen {
 		if time.Since(cb.lastFailTime) > cb.timeout {
-			cb.state = "half-open"
+			cb.state = CircuitHalfOpen
 		} else {
-			return fmt.Errorf("熔断器开启中，拒绝处理")
+			return fmt.Errorf("熔断器开启中，拒绝处理: %w", ErrCircuitOpen)
 		}
 	}
 
@@ -377,7 +441,7 @@ func (cb *SimpleCircuitBreaker) Call(fn func() error) error {
 		cb.lastFailTime = time.Now()
 
 		if cb.failures >= cb.maxFailures {
-			cb.state = "open"
+			cb.state = CircuitOpen
 			return fmt.Errorf("熔断器触发: %w", err)
 		}
 
@@ -385,8 +449,8 @@ func (cb *SimpleCircuitBreaker) Call(fn func() error) error {
 	}
 
 	// 成功，重置失败计数
-	if cb.state == "half-open" {
-		cb.state = "closed"
+	if cb.state == CircuitHalfOpen {
+		cb.state = CircuitClosed
 	}
 	cb.failures = 0
 
@@ -412,35 +476,18 @@ type BatchProcessor interface {
 // BatchMiddleware 批处理中间件
 // 将多个消息合并处理，提高吞吐量
 // batchSize: 批次大小
-// flushInterval: 刷新间隔
+// flushInterval: 批次第一条消息到达后的最长等待时间
+//
+// 基于 BatchConsumer：消息在 batchHandler 成功后才确认，失败时整批 Nack；不会调用 next。
+// 需要只拒绝失败的消息或在关闭时刷新缓冲，直接使用 NewBatchConsumer
 func BatchMiddleware(batchSize int, flushInterval time.Duration, batchHandler func([]*Message) error) Middleware {
-	batch := make([]*Message, 0, batchSize)
-	var lastFlush time.Time
-
-	flush := func() error {
-		if len(batch) == 0 {
-			return nil
-		}
-
-		err := batchHandler(batch)
-		batch = batch[:0]
-		lastFlush = time.Now()
-		return err
-	}
+	consumer := NewBatchConsumer(BatchConfig{Size: batchSize, FlushInterval: flushInterval},
+		func(ctx context.Context, msgs []*Message) error {
+			return batchHandler(msgs)
+		})
 
 	return func(next Handler) Handler {
-		return func(ctx context.Context, msg *Message) error {
-			batch = append(batch, msg)
-
-			// 检查是否达到批次大小或超时
-			if len(batch) >= batchSize || time.Since(lastFlush) > flushInterval {
-				if err := flush(); err != nil {
-					return err
-				}
-			}
-
-			return nil
-		}
+		return consumer.Handler()
 	}
 }
 
@@ -464,13 +511,15 @@ func FilterMiddleware(predicate func(*Message) bool) Middleware {
 // ========== 优先级中间件 ==========
 
 // PriorityMiddleware 优先级中间件
-// 根据消息优先级排序处理
+// 只将优先级写入消息的 Metadata（供后续处理器读取），不影响处理顺序
+//
+// Deprecated: 按优先级处理请使用 PublishWithPriority 发布、PriorityConsumer 消费
 func PriorityMiddleware(getPriority func(*Message) int) Middleware {
 	return func(next Handler) Handler {
 		return func(ctx context.Context, msg *Message) error {
 			// 将优先级存入 Metadata
 			priority := getPriority(msg)
-			msg.Metadata["priority"] = fmt.Sprintf("%d", priority)
+			msg.Metadata[MetadataPriority] = fmt.Sprintf("%d", priority)
 
 			return next(ctx, msg)
 		}
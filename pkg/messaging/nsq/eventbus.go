@@ -1,7 +1,9 @@
 package nsq
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/FangcunMount/component-base/pkg/messaging"
 	"github.com/nsqio/go-nsq"
@@ -17,6 +19,7 @@ type eventBus struct {
 	publisher  messaging.Publisher
 	subscriber messaging.Subscriber
 	router     *messaging.Router
+	topics     *TopicCreator // 通过 nsqd HTTP 接口删除临时 topic
 }
 
 // NewEventBus 创建 NSQ 事件总线
@@ -44,6 +47,7 @@ func NewEventBus(nsqdAddr string, lookupdAddrs []string, cfg *nsq.Config) (messa
 	bus := &eventBus{
 		publisher:  publisher,
 		subscriber: subscriber,
+		topics:     NewTopicCreator(nsqdAddr, slog.Default()),
 	}
 	bus.router = messaging.NewRouter(subscriber)
 
@@ -84,6 +88,12 @@ func (b *eventBus) Router() *messaging.Router {
 	return b.router
 }
 
+// DeleteTopic 实现 messaging.TopicDeleter 接口
+// 通过 nsqd HTTP 接口删除 topic，channel 随 topic 一并删除
+func (b *eventBus) DeleteTopic(ctx context.Context, topic, channel string) error {
+	return b.topics.DeleteTopic(ctx, topic)
+}
+
 // Health 健康检查
 func (b *eventBus) Health() error {
 	// 尝试 ping NSQ producer
@@ -0,0 +1,36 @@
+package nsq
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/FangcunMount/component-base/pkg/messaging"
+)
+
+func TestBusRegistryBuildsNSQBusWithDefaults(t *testing.T) {
+	// 与 README 示例一样只配置地址；端口 1 上没有 nsqd，应在连接阶段失败而不是配置校验阶段
+	registry, err := messaging.NewBusRegistry(&messaging.Config{Buses: map[string]messaging.Config{
+		"inbound": {
+			Provider: messaging.ProviderNSQ,
+			NSQ: messaging.NSQConfig{
+				NSQdAddr:     "127.0.0.1:1",
+				LookupdAddrs: []string{"127.0.0.1:1"},
+			},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("NewBusRegistry: %v", err)
+	}
+	defer registry.Close()
+
+	_, err = registry.Bus("inbound")
+	if err == nil {
+		t.Fatal("Bus() should fail without a running nsqd")
+	}
+	if strings.Contains(err.Error(), "invalid") {
+		t.Fatalf("Bus() error = %v, want a connection error after defaults are applied", err)
+	}
+	if !strings.Contains(err.Error(), "failed to ping NSQ") {
+		t.Fatalf("Bus() error = %v, want ping failure", err)
+	}
+}
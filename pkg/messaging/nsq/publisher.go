@@ -41,24 +41,31 @@ func NewPublisher(addr string, cfg *nsq.Config) (messaging.Publisher, error) {
 }
 
 // Publish 发布消息
+// 原始字节不经过 Metadata 信封，不携带追踪信息；需要传播追踪上下文时使用 PublishMessage
 func (p *publisher) Publish(ctx context.Context, topic string, body []byte) error {
-	// 这里可以注入 trace / metric / 日志
-	// 例如：span := trace.SpanFromContext(ctx)
-
-	if err := p.producer.Publish(topic, body); err != nil {
-		return fmt.Errorf("failed to publish message to topic %s: %w", topic, err)
-	}
-
-	return nil
+	return p.publish(topic, body)
 }
 
-// PublishMessage 发布消息对象（支持 Metadata）
+// PublishMessage 发布消息对象（支持 Metadata，ctx 中的追踪信息会写入 Metadata）
 func (p *publisher) PublishMessage(ctx context.Context, topic string, msg *messaging.Message) error {
-	payload, err := messaging.EncodeMessagePayload(msg)
+	payload, err := encodeMessage(ctx, msg)
 	if err != nil {
 		return err
 	}
-	return p.Publish(ctx, topic, payload)
+	return p.publish(topic, payload)
+}
+
+// encodeMessage 注入 ctx 中的追踪信息，并编码为 Metadata 信封
+func encodeMessage(ctx context.Context, msg *messaging.Message) ([]byte, error) {
+	return messaging.EncodeMessagePayload(messaging.InjectTraceContext(ctx, msg))
+}
+
+// publish 发布已编码的消息体
+func (p *publisher) publish(topic string, payload []byte) error {
+	if err := p.producer.Publish(topic, payload); err != nil {
+		return fmt.Errorf("failed to publish message to topic %s: %w", topic, err)
+	}
+	return nil
 }
 
 // PublishAsync 异步发布消息
@@ -66,17 +73,66 @@ func (p *publisher) PublishAsync(topic string, body []byte, doneChan chan *nsq.P
 	return p.producer.PublishAsync(topic, body, doneChan, args...)
 }
 
-// MultiPublish 批量发布消息
+// MultiPublish 批量发布消息（与 Publish 一样发送原始字节）
 func (p *publisher) MultiPublish(ctx context.Context, topic string, bodies [][]byte) error {
-	if err := p.producer.MultiPublish(topic, bodies); err != nil {
+	return p.multiPublish(topic, bodies)
+}
+
+// multiPublish 批量发布已编码的消息体
+func (p *publisher) multiPublish(topic string, payloads [][]byte) error {
+	if err := p.producer.MultiPublish(topic, payloads); err != nil {
 		return fmt.Errorf("failed to multi-publish messages to topic %s: %w", topic, err)
 	}
 	return nil
 }
 
-// DeferredPublish 延迟发布消息
+// PublishBatch 实现 messaging.BatchPublisher 接口（MPUB）
+// 每条消息都使用 Metadata 信封编码；MPUB 整批原子提交，失败时所有已编码的消息都记为失败
+func (p *publisher) PublishBatch(ctx context.Context, topic string, msgs []*messaging.Message) error {
+	errs := make([]error, len(msgs))
+	bodies := make([][]byte, 0, len(msgs))
+	indexes := make([]int, 0, len(msgs))
+	for i, msg := range msgs {
+		payload, err := encodeMessage(ctx, msg)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		bodies = append(bodies, payload)
+		indexes = append(indexes, i)
+	}
+
+	if len(bodies) > 0 {
+		if err := p.multiPublish(topic, bodies); err != nil {
+			for _, i := range indexes {
+				errs[i] = err
+			}
+		}
+	}
+	return messaging.NewBatchPublishError(errs)
+}
+
+// PublishDelayed 实现 messaging.DelayedPublisher 接口（DPUB）
+// 延迟由 nsqd 在内存中维护，上限为 nsqd 的 --max-req-timeout（默认 1h）
+func (p *publisher) PublishDelayed(ctx context.Context, topic string, msg *messaging.Message, delay time.Duration) error {
+	payload, err := encodeMessage(ctx, msg)
+	if err != nil {
+		return err
+	}
+	if delay <= 0 {
+		return p.publish(topic, payload)
+	}
+	return p.deferredPublish(topic, delay, payload)
+}
+
+// DeferredPublish 延迟发布消息（与 Publish 一样发送原始字节）
 func (p *publisher) DeferredPublish(ctx context.Context, topic string, delay time.Duration, body []byte) error {
-	if err := p.producer.DeferredPublish(topic, delay, body); err != nil {
+	return p.deferredPublish(topic, delay, body)
+}
+
+// deferredPublish 延迟发布已编码的消息体
+func (p *publisher) deferredPublish(topic string, delay time.Duration, payload []byte) error {
+	if err := p.producer.DeferredPublish(topic, delay, payload); err != nil {
 		return fmt.Errorf("failed to deferred-publish message to topic %s: %w", topic, err)
 	}
 	return nil
@@ -0,0 +1,28 @@
+package nsq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/FangcunMount/component-base/pkg/log"
+	"github.com/FangcunMount/component-base/pkg/messaging"
+)
+
+func TestEncodeMessageInjectsTraceContextIntoEnvelope(t *testing.T) {
+	ctx := log.WithTraceContext(context.Background(), "trace-1", "span-1", "req-1")
+
+	payload, err := encodeMessage(ctx, messaging.NewMessage("msg-1", []byte("hello")))
+	if err != nil {
+		t.Fatalf("encodeMessage() error = %v", err)
+	}
+	msg, ok, err := messaging.DecodeMessagePayload(payload)
+	if err != nil || !ok {
+		t.Fatalf("DecodeMessagePayload() ok=%v err=%v, want envelope", ok, err)
+	}
+	if string(msg.Payload) != "hello" || msg.UUID != "msg-1" {
+		t.Fatalf("message = %q/%q, want msg-1/hello", msg.UUID, msg.Payload)
+	}
+	if msg.Metadata[messaging.MetadataTraceID] != "trace-1" || msg.Metadata[messaging.MetadataRequestID] != "req-1" {
+		t.Fatalf("metadata = %v, want trace and request IDs", msg.Metadata)
+	}
+}
@@ -0,0 +1,82 @@
+package nsq
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FangcunMount/component-base/pkg/messaging"
+)
+
+const nsqdStatsJSON = `{
+  "version": "1.2.1",
+  "health": "OK",
+  "topics": [
+    {
+      "topic_name": "order.paid",
+      "depth": 0,
+      "channels": [
+        {"channel_name": "billing", "depth": 120, "in_flight_count": 8, "deferred_count": 2,
+         "requeue_count": 15, "timeout_count": 3, "clients": [{"hostname": "a"}, {"hostname": "b"}]},
+        {"channel_name": "fulfillment", "depth": 0, "in_flight_count": 0, "client_count": 4, "clients": []}
+      ]
+    },
+    {"topic_name": "order.paid.dlq", "depth": 7, "channels": []},
+    {"topic_name": "user.created", "depth": 0, "channels": [{"channel_name": "mail", "depth": 1}]}
+  ]
+}`
+
+func TestStatsReaderQueueSnapshot(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/stats" || r.URL.Query().Get("format") != "json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(nsqdStatsJSON))
+	}))
+	defer server.Close()
+
+	now := time.Unix(1700000000, 0)
+	reader := NewStatsReader(strings.TrimPrefix(server.URL, "http://"), server.Client(), "order.paid", "order.paid.dlq")
+	snapshot, err := reader.QueueSnapshot(context.Background(), now)
+	if err != nil {
+		t.Fatalf("QueueSnapshot: %v", err)
+	}
+
+	want := []messaging.QueueStats{
+		{Topic: "order.paid", Channel: "billing", Depth: 120, InFlight: 8, Deferred: 2, Requeued: 15, TimedOut: 3, Consumers: 2},
+		{Topic: "order.paid", Channel: "fulfillment", Consumers: 4},
+		{Topic: "order.paid.dlq", Depth: 7},
+	}
+	if snapshot.Broker != "nsq" || !snapshot.GeneratedAt.Equal(now) || len(snapshot.Queues) != len(want) {
+		t.Fatalf("snapshot = %+v", snapshot)
+	}
+	for i := range want {
+		if snapshot.Queues[i] != want[i] {
+			t.Fatalf("queue %d = %+v, want %+v", i, snapshot.Queues[i], want[i])
+		}
+	}
+}
+
+func TestStatsReaderLegacyFormatAndErrors(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(`{"status_code":200,"status_txt":"OK","data":{"topics":[{"topic_name":"t","channels":[{"channel_name":"c","depth":3}]}]}}`))
+	}))
+	defer server.Close()
+
+	reader := NewStatsReader(strings.TrimPrefix(server.URL, "http://"), server.Client())
+	snapshot, err := reader.QueueSnapshot(context.Background(), time.Now())
+	if err != nil || len(snapshot.Queues) != 1 || snapshot.Queues[0].Depth != 3 {
+		t.Fatalf("legacy snapshot = %+v, %v", snapshot, err)
+	}
+
+	status = http.StatusInternalServerError
+	if _, err := reader.QueueSnapshot(context.Background(), time.Now()); err == nil {
+		t.Fatalf("non-200 response should fail")
+	}
+}
@@ -11,13 +11,19 @@ import (
 
 // subscriber NSQ 订阅者实现
 type subscriber struct {
-	consumers []*nsq.Consumer
+	consumers []*topicConsumer
 	config    *nsq.Config
 	lookupd   []string
 	mu        sync.RWMutex
 	stopped   bool
 }
 
+// topicConsumer 订阅对应的 NSQ consumer
+type topicConsumer struct {
+	key string
+	*nsq.Consumer
+}
+
 // NewSubscriber 创建 NSQ 订阅者
 // lookupdAddrs: NSQLookupd 地址列表
 // cfg: NSQ 配置
@@ -31,15 +37,36 @@ func NewSubscriber(lookupdAddrs []string, cfg *nsq.Config) (messaging.Subscriber
 	}
 
 	return &subscriber{
-		consumers: make([]*nsq.Consumer, 0),
+		consumers: make([]*topicConsumer, 0),
 		config:    cfg,
 		lookupd:   lookupdAddrs,
 		stopped:   false,
 	}, nil
 }
 
+// MaxDeliveryAttempts 实现 messaging.DeliveryLimiter 接口
+// 超过 MaxAttempts 的消息会被 go-nsq 直接确认（FIN）而不调用处理器
+func (s *subscriber) MaxDeliveryAttempts() uint16 {
+	return s.config.MaxAttempts
+}
+
 // Subscribe 订阅主题
+// 处理器并发度与 MaxInFlight 保持一致，确保可以并行处理消息
 func (s *subscriber) Subscribe(topic, channel string, handler messaging.Handler) error {
+	concurrency := s.config.MaxInFlight
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return s.subscribe(topic, channel, handler, concurrency)
+}
+
+// SubscribeSerial 实现 messaging.SerialSubscriber 接口
+// 单 goroutine 按到达顺序调用处理器；MaxInFlight 仍限制未确认的消息数
+func (s *subscriber) SubscribeSerial(topic, channel string, handler messaging.Handler) error {
+	return s.subscribe(topic, channel, handler, 1)
+}
+
+func (s *subscriber) subscribe(topic, channel string, handler messaging.Handler, concurrency int) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -53,12 +80,6 @@ func (s *subscriber) Subscribe(topic, channel string, handler messaging.Handler)
 		return fmt.Errorf("failed to create NSQ consumer: %w", err)
 	}
 
-	// 并发度与 MaxInFlight 保持一致，确保可以并行处理消息
-	concurrency := s.config.MaxInFlight
-	if concurrency < 1 {
-		concurrency = 1
-	}
-
 	// 添加消息处理器（并发）
 	consumer.AddConcurrentHandlers(nsq.HandlerFunc(func(message *nsq.Message) error {
 		// 将 NSQ 的 Message 转换为领域层的 Message
@@ -94,11 +115,17 @@ func (s *subscriber) Subscribe(topic, channel string, handler messaging.Handler)
 			return nil
 		})
 
-		// 创建 context（可以注入 trace、timeout 等）
-		ctx := context.Background()
+		// 从 Metadata 恢复追踪上下文
+		ctx := messaging.ExtractTraceContext(context.Background(), domainMsg)
 
 		// 调用业务层的 handler
-		if err := handler(ctx, domainMsg); err != nil {
+		err = handler(ctx, domainMsg)
+		if domainMsg.IsDeferred() {
+			// 处理器接管确认（如批量消费），关闭 go-nsq 的自动 FIN/REQ
+			message.DisableAutoResponse()
+			return nil
+		}
+		if err != nil {
 			// 如果处理失败，自动 Nack（重新入队）
 			if !domainMsg.IsSettled() {
 				domainMsg.Nack()
@@ -120,7 +147,7 @@ func (s *subscriber) Subscribe(topic, channel string, handler messaging.Handler)
 	}
 
 	// 保存 consumer 引用
-	s.consumers = append(s.consumers, consumer)
+	s.consumers = append(s.consumers, &topicConsumer{key: topic + ":" + channel, Consumer: consumer})
 
 	return nil
 }
@@ -136,6 +163,65 @@ func (s *subscriber) SubscribeWithMiddleware(topic, channel string, handler mess
 	return s.Subscribe(topic, channel, handler)
 }
 
+// Unsubscribe 实现 messaging.Unsubscriber 接口
+// 停止该 topic/channel 上的所有 consumer，并等待处理中的消息完成
+func (s *subscriber) Unsubscribe(topic, channel string) error {
+	key := topic + ":" + channel
+
+	s.mu.Lock()
+	var removed []*topicConsumer
+	kept := s.consumers[:0]
+	for _, c := range s.consumers {
+		if c.key == key {
+			removed = append(removed, c)
+		} else {
+			kept = append(kept, c)
+		}
+	}
+	s.consumers = kept
+	s.mu.Unlock()
+
+	if len(removed) == 0 {
+		return fmt.Errorf("not subscribed to %s", key)
+	}
+	for _, c := range removed {
+		c.Stop()
+	}
+	for _, c := range removed {
+		<-c.StopChan
+	}
+	return nil
+}
+
+// Pause 实现 messaging.PausableSubscriber 接口
+// 将该 topic/channel 的 MaxInFlight 调为 0，nsqd 停止推送新消息；处理中的消息不受影响
+func (s *subscriber) Pause(topic, channel string) error {
+	return s.changeMaxInFlight(topic+":"+channel, 0)
+}
+
+// Resume 实现 messaging.PausableSubscriber 接口
+// 恢复为配置的 MaxInFlight
+func (s *subscriber) Resume(topic, channel string) error {
+	return s.changeMaxInFlight(topic+":"+channel, s.config.MaxInFlight)
+}
+
+func (s *subscriber) changeMaxInFlight(key string, maxInFlight int) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	found := false
+	for _, c := range s.consumers {
+		if c.key == key {
+			c.ChangeMaxInFlight(maxInFlight)
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("not subscribed to %s", key)
+	}
+	return nil
+}
+
 // Stop 停止所有订阅
 func (s *subscriber) Stop() {
 	s.mu.Lock()
@@ -1,6 +1,7 @@
 package nsq
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log/slog"
@@ -37,9 +38,14 @@ func NewTopicCreator(nsqdAddr string, logger *slog.Logger) *TopicCreator {
 
 // CreateTopic 创建单个 topic
 func (t *TopicCreator) CreateTopic(topic string) error {
+	return t.EnsureTopic(context.Background(), topic)
+}
+
+// EnsureTopic 创建 topic（已存在时直接成功）
+func (t *TopicCreator) EnsureTopic(ctx context.Context, topic string) error {
 	endpoint := fmt.Sprintf("http://%s/topic/create?topic=%s", t.nsqdAddr, url.QueryEscape(topic))
 
-	resp, err := t.httpClient.Post(endpoint, "application/json", nil)
+	resp, err := t.post(ctx, endpoint)
 	if err != nil {
 		return fmt.Errorf("failed to create topic %s: %w", topic, err)
 	}
@@ -108,10 +114,16 @@ func (t *TopicCreator) EnsureTopics(topics []string) error {
 
 // CreateChannel 创建 channel（可选，channel 会在订阅时自动创建）
 func (t *TopicCreator) CreateChannel(topic, channel string) error {
+	return t.EnsureChannel(context.Background(), topic, channel)
+}
+
+// EnsureChannel 创建 channel（topic 不存在时一并创建，已存在时直接成功）
+// 预先创建 channel 后，消费者上线前发布的消息会保留在 channel 中，不会只投递给先上线的 channel
+func (t *TopicCreator) EnsureChannel(ctx context.Context, topic, channel string) error {
 	endpoint := fmt.Sprintf("http://%s/channel/create?topic=%s&channel=%s",
 		t.nsqdAddr, url.QueryEscape(topic), url.QueryEscape(channel))
 
-	resp, err := t.httpClient.Post(endpoint, "application/json", nil)
+	resp, err := t.post(ctx, endpoint)
 	if err != nil {
 		return fmt.Errorf("failed to create channel %s/%s: %w", topic, channel, err)
 	}
@@ -128,3 +140,35 @@ func (t *TopicCreator) CreateChannel(topic, channel string) error {
 	body, _ := io.ReadAll(resp.Body)
 	return fmt.Errorf("failed to create channel %s/%s: status=%d, body=%s", topic, channel, resp.StatusCode, string(body))
 }
+
+// DeleteTopic 删除 topic 及其所有 channel（不存在时直接成功）
+// 未消费的消息会一并丢弃，只用于临时 topic（如 Requester 的响应 topic）
+func (t *TopicCreator) DeleteTopic(ctx context.Context, topic string) error {
+	endpoint := fmt.Sprintf("http://%s/topic/delete?topic=%s", t.nsqdAddr, url.QueryEscape(topic))
+
+	resp, err := t.post(ctx, endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to delete topic %s: %w", topic, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNotFound {
+		t.logger.Debug("Topic deleted or not found",
+			slog.String("topic", topic),
+			slog.Int("status", resp.StatusCode),
+		)
+		return nil
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	return fmt.Errorf("failed to delete topic %s: status=%d, body=%s", topic, resp.StatusCode, string(body))
+}
+
+func (t *TopicCreator) post(ctx context.Context, endpoint string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return t.httpClient.Do(req)
+}
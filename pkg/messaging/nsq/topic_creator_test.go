@@ -0,0 +1,40 @@
+package nsq
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTopicCreatorDeleteTopic(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.RequestURI())
+		switch r.URL.Query().Get("topic") {
+		case "missing":
+			http.Error(w, `{"message":"TOPIC_NOT_FOUND"}`, http.StatusNotFound)
+		case "broken":
+			http.Error(w, `{"message":"INTERNAL_ERROR"}`, http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	creator := NewTopicCreator(strings.TrimPrefix(server.URL, "http://"), slog.Default())
+	ctx := context.Background()
+
+	if err := creator.DeleteTopic(ctx, "reply.abc"); err != nil {
+		t.Fatalf("DeleteTopic: %v", err)
+	}
+	if err := creator.DeleteTopic(ctx, "missing"); err != nil {
+		t.Fatalf("DeleteTopic of a missing topic: %v", err)
+	}
+	if err := creator.DeleteTopic(ctx, "broken"); err == nil {
+		t.Fatal("DeleteTopic should fail on a server error")
+	}
+	if paths[0] != "POST /topic/delete?topic=reply.abc" {
+		t.Fatalf("request = %q, want POST /topic/delete?topic=reply.abc", paths[0])
+	}
+}
@@ -0,0 +1,186 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sync"
+)
+
+// ========== 按键有序处理 ==========
+
+// MetadataAggregateID 聚合 ID（与 eventcodec.MetadataFromEvent 写入的键一致），OrderedProcessor 默认的分区键
+const MetadataAggregateID = "aggregate_id"
+
+// ErrOrderedProcessorClosed 有序处理器已关闭，消息未被接收（已 Nack）
+var ErrOrderedProcessorClosed = errors.New("messaging: ordered processor is closed")
+
+// SerialSubscriber 支持串行投递的订阅者：处理器同一时刻只处理一条消息，按到达顺序调用
+//
+// nsq 默认以 MaxInFlight 并发调用处理器，实现此接口以单 goroutine 投递；
+// rabbitmq、redisstream 的每个订阅本身即串行投递，无需实现
+type SerialSubscriber interface {
+	SubscribeSerial(topic, channel string, handler Handler) error
+}
+
+// OrderedConfig 有序处理配置
+type OrderedConfig struct {
+	// Key 提取分区键（默认取 Metadata 中的 aggregate_id）
+	// 键为空的消息按 UUID 分散到各 worker，不保证顺序
+	Key func(*Message) string
+
+	// Workers 串行 worker 数，即跨键的并行度（默认 16）
+	Workers int
+
+	// QueueSize 每个 worker 的队列长度（默认 64），队列满时投递阻塞，形成背压
+	QueueSize int
+}
+
+// KeyFromMetadata 以 Metadata 中指定键的值作为分区键
+func KeyFromMetadata(key string) func(*Message) string {
+	return func(msg *Message) string {
+		return msg.Metadata[key]
+	}
+}
+
+// OrderedProcessor 按键有序处理器
+//
+// 将消息按分区键哈希到固定数量的串行 worker：同一键的消息按投递顺序逐条处理，
+// 不同键之间并行。消息进入队列后由处理器接管确认（Message.Defer），
+// worker 在处理器返回后 Ack（成功）或 Nack（失败）。
+//
+// 顺序只在投递本身有序时成立，应通过 SubscribeOrdered 或 Router.AddOrderedHandler 订阅。
+// 消息 Nack 重投后，同一键的后续消息可能先被处理；处理器内部不应再 Defer 消息（如 BatchConsumer）。
+// 一个处理器可以被多个订阅共享。
+type OrderedProcessor struct {
+	cfg    OrderedConfig
+	queues []chan orderedJob
+	wg     sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+type orderedJob struct {
+	ctx  context.Context
+	msg  *Message
+	next Handler
+}
+
+// NewOrderedProcessor 创建有序处理器并启动 worker
+//
+// 使用示例：
+//
+//	ordered := messaging.NewOrderedProcessor(messaging.OrderedConfig{Workers: 32})
+//	defer ordered.Close()
+//	router.AddOrderedHandler("order.events", "projector", ordered, projector.Handle)
+func NewOrderedProcessor(cfg OrderedConfig) *OrderedProcessor {
+	if cfg.Key == nil {
+		cfg.Key = KeyFromMetadata(MetadataAggregateID)
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 16
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 64
+	}
+
+	p := &OrderedProcessor{
+		cfg:    cfg,
+		queues: make([]chan orderedJob, cfg.Workers),
+	}
+	for i := range p.queues {
+		p.queues[i] = make(chan orderedJob, cfg.QueueSize)
+		p.wg.Add(1)
+		go p.work(p.queues[i])
+	}
+	return p
+}
+
+// Handler 返回分派处理器：消息进入所属 worker 的队列后立即返回
+// 队列满时阻塞到有空位或 ctx 结束（此时 Nack 并返回 ctx 错误）
+func (p *OrderedProcessor) Handler(next Handler) Handler {
+	return func(ctx context.Context, msg *Message) error {
+		queue := p.queues[p.partition(msg)]
+
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+		if p.closed {
+			return ErrOrderedProcessorClosed
+		}
+
+		msg.Defer()
+		select {
+		case queue <- orderedJob{ctx: context.WithoutCancel(ctx), msg: msg, next: next}:
+			return nil
+		case <-ctx.Done():
+			_ = msg.Nack()
+			return ctx.Err()
+		}
+	}
+}
+
+// Middleware 以中间件形式使用（需配合串行投递，见 SerialSubscriber）
+func (p *OrderedProcessor) Middleware() Middleware {
+	return p.Handler
+}
+
+// Close 停止接收新消息，等待队列中的消息处理完成
+func (p *OrderedProcessor) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.mu.Unlock()
+
+	p.wg.Wait()
+	return nil
+}
+
+func (p *OrderedProcessor) partition(msg *Message) int {
+	key := p.cfg.Key(msg)
+	if key == "" {
+		key = msg.UUID
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(p.queues)))
+}
+
+// work 串行处理一个队列，并按处理结果确认消息
+func (p *OrderedProcessor) work(queue <-chan orderedJob) {
+	defer p.wg.Done()
+
+	for job := range queue {
+		err := job.next(job.ctx, job.msg)
+		if job.msg.IsSettled() {
+			continue
+		}
+		if err != nil {
+			_ = job.msg.Nack()
+		} else {
+			_ = job.msg.Ack()
+		}
+	}
+}
+
+// SubscribeOrdered 按键有序订阅
+// 应用中间件后交给 processor 分派；订阅者实现 SerialSubscriber 时以串行方式订阅
+func SubscribeOrdered(subscriber Subscriber, topic, channel string, processor *OrderedProcessor, handler Handler, middlewares ...Middleware) error {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return subscribeSerial(subscriber, topic, channel, processor.Handler(handler))
+}
+
+func subscribeSerial(subscriber Subscriber, topic, channel string, handler Handler) error {
+	if ss, ok := subscriber.(SerialSubscriber); ok {
+		return ss.SubscribeSerial(topic, channel, handler)
+	}
+	return subscriber.Subscribe(topic, channel, handler)
+}
@@ -0,0 +1,181 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeSerialSubscriber struct {
+	fakeSubscriber
+	serial []string
+}
+
+func (s *fakeSerialSubscriber) SubscribeSerial(topic, channel string, handler Handler) error {
+	s.mu.Lock()
+	s.serial = append(s.serial, topic+":"+channel)
+	s.mu.Unlock()
+	return s.Subscribe(topic, channel, handler)
+}
+
+func orderedMessage(rec *settleRecorder, key string, seq int) *Message {
+	msg := rec.message(fmt.Sprintf("%s-%d", key, seq))
+	msg.Metadata[MetadataAggregateID] = key
+	return msg
+}
+
+func TestOrderedProcessorPreservesOrderPerKey(t *testing.T) {
+	rec := &settleRecorder{}
+	var mu sync.Mutex
+	seen := make(map[string][]string)
+
+	p := NewOrderedProcessor(OrderedConfig{Workers: 4, QueueSize: 8})
+	handler := p.Handler(func(ctx context.Context, msg *Message) error {
+		time.Sleep(time.Duration(len(msg.UUID)%3) * time.Millisecond)
+		mu.Lock()
+		key := msg.Metadata[MetadataAggregateID]
+		seen[key] = append(seen[key], msg.UUID)
+		mu.Unlock()
+		return nil
+	})
+
+	keys := []string{"order-1", "order-2", "order-3"}
+	for seq := 0; seq < 30; seq++ {
+		for _, key := range keys {
+			msg := orderedMessage(rec, key, seq)
+			if err := handler(context.Background(), msg); err != nil {
+				t.Fatalf("dispatch: %v", err)
+			}
+			if !msg.IsDeferred() {
+				t.Fatalf("dispatched message should be deferred")
+			}
+		}
+	}
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	for _, key := range keys {
+		if len(seen[key]) != 30 {
+			t.Fatalf("%s processed %d messages, want 30", key, len(seen[key]))
+		}
+		for seq, id := range seen[key] {
+			if want := fmt.Sprintf("%s-%d", key, seq); id != want {
+				t.Fatalf("%s message %d = %s, want %s", key, seq, id, want)
+			}
+		}
+	}
+	if acked, nacked := rec.counts(); acked != 90 || nacked != 0 {
+		t.Fatalf("acked=%d nacked=%d, want 90/0", acked, nacked)
+	}
+}
+
+func TestOrderedProcessorRunsKeysInParallel(t *testing.T) {
+	p := NewOrderedProcessor(OrderedConfig{Workers: 2})
+	defer p.Close()
+
+	// 找到落在不同 worker 上的两个键
+	first := NewMessage("a", nil)
+	first.Metadata[MetadataAggregateID] = "key-0"
+	var second *Message
+	for i := 1; second == nil; i++ {
+		msg := NewMessage("b", nil)
+		msg.Metadata[MetadataAggregateID] = fmt.Sprintf("key-%d", i)
+		if p.partition(msg) != p.partition(first) {
+			second = msg
+		}
+	}
+
+	release := make(chan struct{})
+	done := make(chan struct{})
+	handler := p.Handler(func(ctx context.Context, msg *Message) error {
+		if msg == first {
+			<-release
+			return nil
+		}
+		close(done)
+		return nil
+	})
+
+	_ = handler(context.Background(), first)
+	_ = handler(context.Background(), second)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("a blocked key stalled another key")
+	}
+	close(release)
+}
+
+func TestOrderedProcessorNacksFailuresAndAppliesBackpressure(t *testing.T) {
+	rec := &settleRecorder{}
+	p := NewOrderedProcessor(OrderedConfig{Workers: 1, QueueSize: 1})
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	handler := p.Handler(func(ctx context.Context, msg *Message) error {
+		started <- struct{}{}
+		<-release
+		return errors.New("projection failed")
+	})
+
+	_ = handler(context.Background(), orderedMessage(rec, "order-1", 0))
+	<-started
+	_ = handler(context.Background(), orderedMessage(rec, "order-1", 1)) // 占满队列
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	blocked := orderedMessage(rec, "order-1", 2)
+	if err := handler(ctx, blocked); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("dispatch on full queue = %v, want deadline exceeded", err)
+	}
+	if !blocked.IsSettled() {
+		t.Fatalf("message rejected by backpressure should be nacked")
+	}
+
+	close(release)
+	<-started
+	_ = p.Close()
+	if acked, nacked := rec.counts(); acked != 0 || nacked != 3 {
+		t.Fatalf("acked=%d nacked=%d, want 0/3", acked, nacked)
+	}
+
+	late := orderedMessage(rec, "order-1", 3)
+	if err := handler(context.Background(), late); !errors.Is(err, ErrOrderedProcessorClosed) || late.IsDeferred() {
+		t.Fatalf("dispatch after Close = %v, deferred=%v", err, late.IsDeferred())
+	}
+}
+
+func TestSubscribeOrderedUsesSerialSubscriber(t *testing.T) {
+	p := NewOrderedProcessor(OrderedConfig{})
+	defer p.Close()
+	handler := func(ctx context.Context, msg *Message) error { return nil }
+
+	serial := &fakeSerialSubscriber{}
+	if err := SubscribeOrdered(serial, "orders", "projector", p, handler); err != nil {
+		t.Fatalf("SubscribeOrdered: %v", err)
+	}
+	if len(serial.serial) != 1 {
+		t.Fatalf("serial subscriptions = %v", serial.serial)
+	}
+
+	router := NewRouter(serial)
+	if err := router.AddOrderedHandler("orders", "audit", p, handler); err != nil {
+		t.Fatalf("AddOrderedHandler: %v", err)
+	}
+	startRouter(t, router, &serial.fakeSubscriber, "orders:audit")
+	if len(serial.serial) != 2 || serial.serial[1] != "orders:audit" {
+		t.Fatalf("router should subscribe ordered handlers serially: %v", serial.serial)
+	}
+
+	plain := &fakeSubscriber{}
+	if err := SubscribeOrdered(plain, "orders", "projector", p, handler); err != nil {
+		t.Fatalf("SubscribeOrdered on plain subscriber: %v", err)
+	}
+	if len(plain.subscribed) != 1 {
+		t.Fatalf("subscribed = %v", plain.subscribed)
+	}
+}
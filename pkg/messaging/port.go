@@ -39,6 +39,19 @@ type Subscriber interface {
 	Close() error
 }
 
+// Unsubscriber 支持取消单个订阅的订阅者
+// Router 在运行期间移除处理器时使用；nsq、rabbitmq、redisstream 均已实现
+type Unsubscriber interface {
+	// Unsubscribe 停止指定 topic/channel 的消费，等待其处理中的消息完成
+	Unsubscribe(topic, channel string) error
+}
+
+// TopicDeleter 删除 topic 与 channel 在 broker 端的资源（可选能力，由 EventBus 实现）
+// 只用于进程私有的临时 topic（如 Requester 的响应 topic），删除前应先取消订阅
+type TopicDeleter interface {
+	DeleteTopic(ctx context.Context, topic, channel string) error
+}
+
 // Handler 消息处理函数
 // 业务层通过实现此函数来处理接收到的消息
 type Handler func(ctx context.Context, msg *Message) error
@@ -84,6 +97,9 @@ type Message struct {
 
 	settleMu sync.Mutex
 	settled  bool
+	acked    bool
+	deferred bool
+	onSettle []func(acked bool) // 确认后回调（路由器跟踪 Defer 的消息）
 }
 
 // NewMessage 创建新消息
@@ -98,16 +114,16 @@ func NewMessage(uuid string, payload []byte) *Message {
 // Ack 确认消息处理成功
 // 调用后，消息不会被重新投递
 func (m *Message) Ack() error {
-	return m.settle(m.ack)
+	return m.settle(m.ack, true)
 }
 
 // Nack 拒绝消息，触发重试
 // 调用后，消息会被重新投递（如果未超过最大重试次数）
 func (m *Message) Nack() error {
-	return m.settle(m.nack)
+	return m.settle(m.nack, false)
 }
 
-func (m *Message) settle(run func() error) error {
+func (m *Message) settle(run func() error, acked bool) error {
 	if m == nil {
 		return nil
 	}
@@ -117,12 +133,32 @@ func (m *Message) settle(run func() error) error {
 		return nil
 	}
 	m.settled = true
+	m.acked = acked
+	hooks := m.onSettle
+	m.onSettle = nil
 	m.settleMu.Unlock()
 
+	var err error
 	if run != nil {
-		return run()
+		err = run()
+	}
+	for _, hook := range hooks {
+		hook(acked)
+	}
+	return err
+}
+
+// whenSettled 在消息 Ack/Nack 后调用 fn（acked 表示是否为 Ack），已确认时立即调用
+func (m *Message) whenSettled(fn func(acked bool)) {
+	m.settleMu.Lock()
+	if m.settled {
+		acked := m.acked
+		m.settleMu.Unlock()
+		fn(acked)
+		return
 	}
-	return nil
+	m.onSettle = append(m.onSettle, fn)
+	m.settleMu.Unlock()
 }
 
 // IsSettled reports whether Ack or Nack has already been called.
@@ -135,6 +171,24 @@ func (m *Message) IsSettled() bool {
 	return m.settled
 }
 
+// Defer 声明由处理器稍后自行 Ack/Nack（如 BatchConsumer 缓冲的消息），
+// 订阅者在处理器返回后不再自动确认或拒绝该消息
+func (m *Message) Defer() {
+	m.settleMu.Lock()
+	defer m.settleMu.Unlock()
+	m.deferred = true
+}
+
+// IsDeferred 消息是否已由处理器接管确认
+func (m *Message) IsDeferred() bool {
+	if m == nil {
+		return false
+	}
+	m.settleMu.Lock()
+	defer m.settleMu.Unlock()
+	return m.deferred
+}
+
 // SetAckFunc 设置确认函数（由 Adapter 调用）
 func (m *Message) SetAckFunc(ack func() error) {
 	m.ack = ack
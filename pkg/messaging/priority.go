@@ -0,0 +1,306 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// ========== 优先级 ==========
+
+// MetadataPriority 消息优先级（0 ~ MaxPriority）
+const MetadataPriority = "priority"
+
+// ErrPriorityConsumerClosed 优先级消费者已关闭，消息未被接收（已 Nack）
+var ErrPriorityConsumerClosed = errors.New("messaging: priority consumer is closed")
+
+// Priority 消息优先级，数值越大越优先；未设置优先级的消息视为 PriorityLow
+type Priority uint8
+
+// 常用优先级，分别落在三个优先级档位
+const (
+	PriorityLow    Priority = 0
+	PriorityNormal Priority = 5
+	PriorityHigh   Priority = 9
+
+	// MaxPriority 最大优先级（RabbitMQ 队列的 x-max-priority）
+	MaxPriority Priority = 9
+)
+
+// PriorityBands 不支持原生优先级的提供者使用的档位数（每档一个子 topic）
+const PriorityBands = 3
+
+// PriorityPublisher 支持原生优先级的发布者（rabbitmq：消息属性 priority）
+type PriorityPublisher interface {
+	PublishWithPriority(ctx context.Context, topic string, msg *Message, priority Priority) error
+}
+
+// PrioritySubscriber 支持原生优先级的订阅者（rabbitmq：x-max-priority 队列）
+type PrioritySubscriber interface {
+	SubscribeWithPriority(topic, channel string, handler Handler, middlewares ...Middleware) error
+}
+
+// PriorityBand 优先级所在档位：0-3 → 0，4-6 → 1，7-9 → 2
+func PriorityBand(priority Priority) int {
+	if priority > MaxPriority {
+		priority = MaxPriority
+	}
+	return int(priority) * PriorityBands / int(MaxPriority+1)
+}
+
+// PriorityTopic 不支持原生优先级时，优先级对应的子 topic
+// 最低档位使用原 topic，因此未设置优先级的发布者无需修改；其余档位为 "<topic>.priority<档位>"
+func PriorityTopic(topic string, priority Priority) string {
+	return bandTopic(topic, PriorityBand(priority))
+}
+
+func bandTopic(topic string, band int) string {
+	if band == 0 {
+		return topic
+	}
+	return fmt.Sprintf("%s.priority%d", topic, band)
+}
+
+// ParsePriority 读取消息的优先级，未设置或非法时返回 PriorityLow
+func ParsePriority(msg *Message) Priority {
+	p, err := strconv.Atoi(msg.Metadata[MetadataPriority])
+	if err != nil || p < 0 {
+		return PriorityLow
+	}
+	if p > int(MaxPriority) {
+		return MaxPriority
+	}
+	return Priority(p)
+}
+
+// PublishWithPriority 按优先级发布消息
+// 发布者支持原生优先级时直接使用；否则发布到 PriorityTopic 对应的子 topic，
+// 由 PriorityConsumer 按权重消费。不修改传入的消息。
+//
+// 使用示例：
+//
+//	err := messaging.PublishWithPriority(ctx, publisher, "notification.send", msg, messaging.PriorityHigh)
+func PublishWithPriority(ctx context.Context, publisher Publisher, topic string, msg *Message, priority Priority) error {
+	if priority > MaxPriority {
+		priority = MaxPriority
+	}
+	if pp, ok := publisher.(PriorityPublisher); ok {
+		return pp.PublishWithPriority(ctx, topic, msg, priority)
+	}
+	return publisher.PublishMessage(ctx, PriorityTopic(topic, priority), WithPriority(msg, priority))
+}
+
+// WithPriority 返回写入优先级 Metadata 的消息副本（共享 Payload）
+func WithPriority(msg *Message, priority Priority) *Message {
+	md := make(map[string]string, len(msg.Metadata)+1)
+	for k, v := range msg.Metadata {
+		md[k] = v
+	}
+	md[MetadataPriority] = strconv.Itoa(int(priority))
+	return &Message{
+		UUID:      msg.UUID,
+		Metadata:  md,
+		Payload:   msg.Payload,
+		Attempts:  msg.Attempts,
+		Timestamp: msg.Timestamp,
+		Topic:     msg.Topic,
+		Channel:   msg.Channel,
+	}
+}
+
+// PriorityConfig 优先级消费配置
+type PriorityConfig struct {
+	// Weights 各档位（由低到高）的轮询权重，默认 {1, 3, 9}
+	// 高档位持续有消息时，低档位仍按权重获得处理机会，不会饿死
+	Weights [PriorityBands]int
+
+	// Workers 并发处理数（默认 1）
+	Workers int
+
+	// QueueSize 每个档位的缓冲长度（默认 64），满时阻塞投递形成背压
+	QueueSize int
+}
+
+// PriorityConsumer 按优先级消费
+//
+// 订阅者支持原生优先级（PrioritySubscriber）时直接使用；否则订阅每个档位的子 topic，
+// 消息进入对应档位的缓冲后由处理器接管确认（Message.Defer），
+// worker 按权重轮询各档位：轮到的档位为空时取当前最高的非空档位。
+type PriorityConsumer struct {
+	cfg     PriorityConfig
+	handler Handler
+
+	schedule []int // 加权轮询序列
+	cursor   atomic.Uint64
+	queues   [PriorityBands]chan priorityJob
+
+	startOnce sync.Once
+	wg        sync.WaitGroup
+	mu        sync.RWMutex
+	closed    bool
+	done      chan struct{}
+}
+
+type priorityJob struct {
+	ctx context.Context
+	msg *Message
+}
+
+// NewPriorityConsumer 创建优先级消费者
+//
+// 使用示例：
+//
+//	consumer := messaging.NewPriorityConsumer(messaging.PriorityConfig{Workers: 8}, sender.Handle)
+//	defer consumer.Close()
+//	err := consumer.Subscribe(subscriber, "notification.send", "sender")
+func NewPriorityConsumer(cfg PriorityConfig, handler Handler, middlewares ...Middleware) *PriorityConsumer {
+	if cfg.Weights == ([PriorityBands]int{}) {
+		cfg.Weights = [PriorityBands]int{1, 3, 9}
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 64
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	c := &PriorityConsumer{
+		cfg:     cfg,
+		handler: handler,
+		done:    make(chan struct{}),
+	}
+	for band := PriorityBands - 1; band >= 0; band-- {
+		for i := 0; i < cfg.Weights[band]; i++ {
+			c.schedule = append(c.schedule, band)
+		}
+		c.queues[band] = make(chan priorityJob, cfg.QueueSize)
+	}
+	if len(c.schedule) == 0 {
+		c.schedule = []int{PriorityBands - 1}
+	}
+	return c
+}
+
+// Subscribe 订阅 topic 的所有优先级
+func (c *PriorityConsumer) Subscribe(subscriber Subscriber, topic, channel string) error {
+	if ps, ok := subscriber.(PrioritySubscriber); ok {
+		return ps.SubscribeWithPriority(topic, channel, c.handler)
+	}
+
+	c.startOnce.Do(func() {
+		for i := 0; i < c.cfg.Workers; i++ {
+			c.wg.Add(1)
+			go c.work()
+		}
+	})
+	for band := 0; band < PriorityBands; band++ {
+		if err := subscriber.Subscribe(bandTopic(topic, band), channel, c.enqueue(band)); err != nil {
+			return fmt.Errorf("subscribe priority band %d of %s: %w", band, topic, err)
+		}
+	}
+	return nil
+}
+
+// Close 停止处理，缓冲中尚未处理的消息被 Nack（由 broker 重新投递）
+// 应在订阅者停止之后调用
+func (c *PriorityConsumer) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	close(c.done)
+	c.mu.Unlock()
+
+	c.wg.Wait()
+	// closed 之后不会再有消息进入缓冲
+	for _, queue := range c.queues {
+		for len(queue) > 0 {
+			job := <-queue
+			_ = job.msg.Nack()
+		}
+	}
+	return nil
+}
+
+// enqueue 档位的投递处理器
+func (c *PriorityConsumer) enqueue(band int) Handler {
+	return func(ctx context.Context, msg *Message) error {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		if c.closed {
+			return ErrPriorityConsumerClosed
+		}
+
+		msg.Defer()
+		select {
+		case c.queues[band] <- priorityJob{ctx: context.WithoutCancel(ctx), msg: msg}:
+			return nil
+		case <-ctx.Done():
+			_ = msg.Nack()
+			return ctx.Err()
+		}
+	}
+}
+
+func (c *PriorityConsumer) work() {
+	defer c.wg.Done()
+
+	for {
+		job, ok := c.next()
+		if !ok {
+			return
+		}
+		err := c.handler(job.ctx, job.msg)
+		if job.msg.IsSettled() {
+			continue
+		}
+		if err != nil {
+			_ = job.msg.Nack()
+		} else {
+			_ = job.msg.Ack()
+		}
+	}
+}
+
+// next 按加权轮询取下一条消息；所有档位为空时阻塞
+func (c *PriorityConsumer) next() (priorityJob, bool) {
+	select {
+	case <-c.done:
+		return priorityJob{}, false
+	default:
+	}
+
+	band := c.schedule[(c.cursor.Add(1)-1)%uint64(len(c.schedule))]
+	select {
+	case job := <-c.queues[band]:
+		return job, true
+	default:
+	}
+
+	for b := PriorityBands - 1; b >= 0; b-- {
+		select {
+		case job := <-c.queues[b]:
+			return job, true
+		default:
+		}
+	}
+
+	select {
+	case job := <-c.queues[2]:
+		return job, true
+	case job := <-c.queues[1]:
+		return job, true
+	case job := <-c.queues[0]:
+		return job, true
+	case <-c.done:
+		return priorityJob{}, false
+	}
+}
@@ -0,0 +1,203 @@
+package messaging
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakePriorityPublisher struct {
+	fakePublisher
+	priorities []Priority
+}
+
+func (p *fakePriorityPublisher) PublishWithPriority(ctx context.Context, topic string, msg *Message, priority Priority) error {
+	p.priorities = append(p.priorities, priority)
+	return p.PublishMessage(ctx, topic, msg)
+}
+
+type fakePrioritySubscriber struct {
+	fakeSubscriber
+	native []string
+}
+
+func (s *fakePrioritySubscriber) SubscribeWithPriority(topic, channel string, handler Handler, middlewares ...Middleware) error {
+	s.native = append(s.native, topic+":"+channel)
+	return s.Subscribe(topic, channel, handler)
+}
+
+func TestPriorityTopic(t *testing.T) {
+	tests := map[Priority]string{
+		PriorityLow:    "jobs",
+		3:              "jobs",
+		4:              "jobs.priority1",
+		PriorityNormal: "jobs.priority1",
+		7:              "jobs.priority2",
+		PriorityHigh:   "jobs.priority2",
+		200:            "jobs.priority2",
+	}
+	for priority, want := range tests {
+		if got := PriorityTopic("jobs", priority); got != want {
+			t.Fatalf("PriorityTopic(%d) = %q, want %q", priority, got, want)
+		}
+	}
+}
+
+func TestPublishWithPriority(t *testing.T) {
+	pub := &fakePublisher{}
+	msg := NewMessage("m1", []byte("x"))
+	if err := PublishWithPriority(context.Background(), pub, "jobs", msg, PriorityHigh); err != nil {
+		t.Fatalf("PublishWithPriority: %v", err)
+	}
+	published := pub.messages()
+	if len(published) != 1 || published[0].topic != "jobs.priority2" {
+		t.Fatalf("published = %+v, want sub-topic jobs.priority2", published)
+	}
+	if ParsePriority(published[0].msg) != PriorityHigh {
+		t.Fatalf("priority metadata = %q", published[0].msg.Metadata[MetadataPriority])
+	}
+	if _, ok := msg.Metadata[MetadataPriority]; ok {
+		t.Fatalf("PublishWithPriority mutated the caller's message")
+	}
+
+	native := &fakePriorityPublisher{}
+	if err := PublishWithPriority(context.Background(), native, "jobs", msg, 42); err != nil {
+		t.Fatalf("PublishWithPriority native: %v", err)
+	}
+	if len(native.priorities) != 1 || native.priorities[0] != MaxPriority || native.messages()[0].topic != "jobs" {
+		t.Fatalf("native publish priorities=%v messages=%+v", native.priorities, native.messages())
+	}
+}
+
+// startPriorityConsumer 订阅并阻塞唯一的 worker，返回各档位的投递处理器、处理顺序与放行函数
+func startPriorityConsumer(t *testing.T, weights [PriorityBands]int) (*PriorityConsumer, [PriorityBands]Handler, func() []string, func()) {
+	t.Helper()
+	var mu sync.Mutex
+	var order []string
+	gate := make(chan struct{})
+	entered := make(chan struct{})
+
+	consumer := NewPriorityConsumer(PriorityConfig{Weights: weights}, func(ctx context.Context, msg *Message) error {
+		if msg.UUID == "gate" {
+			close(entered)
+			<-gate
+			return nil
+		}
+		mu.Lock()
+		order = append(order, msg.UUID)
+		mu.Unlock()
+		return nil
+	})
+
+	sub := &fakeSubscriber{}
+	if err := consumer.Subscribe(sub, "jobs", "workers"); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if len(sub.subscribed) != PriorityBands {
+		t.Fatalf("subscribed = %v, want one subscription per band", sub.subscribed)
+	}
+
+	var handlers [PriorityBands]Handler
+	for band := range handlers {
+		handlers[band] = sub.handler(bandTopic("jobs", band) + ":workers")
+	}
+	_ = handlers[0](context.Background(), NewMessage("gate", nil))
+	<-entered
+
+	processed := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), order...)
+	}
+	return consumer, handlers, processed, func() { close(gate) }
+}
+
+func waitProcessed(t *testing.T, processed func() []string, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if got := processed(); len(got) >= n {
+			return got
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("processed %v, want %d messages", processed(), n)
+	return nil
+}
+
+func TestPriorityConsumerPrefersHigherBands(t *testing.T) {
+	consumer, handlers, processed, release := startPriorityConsumer(t, [PriorityBands]int{})
+	defer consumer.Close()
+
+	for _, id := range []string{"low-1", "low-2"} {
+		_ = handlers[0](context.Background(), NewMessage(id, nil))
+	}
+	for _, id := range []string{"high-1", "high-2"} {
+		_ = handlers[2](context.Background(), NewMessage(id, nil))
+	}
+	release()
+
+	got := waitProcessed(t, processed, 4)
+	want := []string{"high-1", "high-2", "low-1", "low-2"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("order = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestPriorityConsumerDoesNotStarveLowerBands(t *testing.T) {
+	consumer, handlers, processed, release := startPriorityConsumer(t, [PriorityBands]int{1, 0, 1})
+	defer consumer.Close()
+
+	for _, id := range []string{"high-1", "high-2", "high-3"} {
+		_ = handlers[2](context.Background(), NewMessage(id, nil))
+	}
+	for _, id := range []string{"low-1", "low-2", "low-3"} {
+		_ = handlers[0](context.Background(), NewMessage(id, nil))
+	}
+	release()
+
+	got := waitProcessed(t, processed, 6)
+	if got[0] != "low-1" && got[1] != "low-1" {
+		t.Fatalf("low priority message was starved: %v", got)
+	}
+}
+
+func TestPriorityConsumerAcksAndClose(t *testing.T) {
+	rec := &settleRecorder{}
+	consumer, handlers, processed, release := startPriorityConsumer(t, [PriorityBands]int{})
+
+	_ = handlers[1](context.Background(), rec.message("normal-1"))
+	release()
+	waitProcessed(t, processed, 1)
+	deadline := time.Now().Add(time.Second)
+	for acked, _ := rec.counts(); acked != 1; acked, _ = rec.counts() {
+		if time.Now().After(deadline) {
+			t.Fatalf("processed message was not acked")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := consumer.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	late := rec.message("late")
+	if err := handlers[0](context.Background(), late); err != ErrPriorityConsumerClosed || late.IsDeferred() {
+		t.Fatalf("enqueue after Close = %v, deferred=%v", err, late.IsDeferred())
+	}
+}
+
+func TestPriorityConsumerUsesNativeSubscriber(t *testing.T) {
+	consumer := NewPriorityConsumer(PriorityConfig{}, func(ctx context.Context, msg *Message) error { return nil })
+	defer consumer.Close()
+
+	sub := &fakePrioritySubscriber{}
+	if err := consumer.Subscribe(sub, "jobs", "workers"); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if len(sub.native) != 1 || len(sub.subscribed) != 1 {
+		t.Fatalf("native=%v subscribed=%v, want a single native subscription", sub.native, sub.subscribed)
+	}
+}
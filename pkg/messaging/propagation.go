@@ -0,0 +1,162 @@
+package messaging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+
+	"github.com/FangcunMount/component-base/pkg/log"
+)
+
+// ========== 追踪上下文传播 ==========
+
+// 追踪上下文使用的 Metadata 键
+const (
+	// MetadataTraceID 链路 ID
+	MetadataTraceID = "trace_id"
+
+	// MetadataSpanID 发布方的 span ID（消费方会生成新的 span ID）
+	MetadataSpanID = "span_id"
+
+	// MetadataRequestID 请求 ID
+	MetadataRequestID = "request_id"
+)
+
+// Propagator 在 context 与消息 Metadata 之间传播追踪上下文
+//
+// 发布者在发送前调用 Inject，订阅者在调用 handler 前调用 Extract。
+// 默认实现为 LogPropagator（与 pkg/log 的 trace_id/span_id/request_id 对接），
+// 需要 W3C traceparent 等格式时实现该接口，并通过 SetPropagator 或 CompositePropagator 组合使用。
+type Propagator interface {
+	// Inject 将 ctx 中的追踪信息写入 md（不覆盖 md 中已有的值）
+	Inject(ctx context.Context, md map[string]string)
+
+	// Extract 从 md 中恢复追踪信息，返回新的 ctx
+	Extract(ctx context.Context, md map[string]string) context.Context
+}
+
+// LogPropagator 基于 pkg/log 追踪字段的传播器
+// 消费时沿用 trace_id 与 request_id，并为本次消费生成新的 span ID
+type LogPropagator struct{}
+
+// Inject 实现 Propagator 接口
+func (LogPropagator) Inject(ctx context.Context, md map[string]string) {
+	setIfAbsent(md, MetadataTraceID, log.ExtractTraceID(ctx))
+	setIfAbsent(md, MetadataSpanID, log.ExtractSpanID(ctx))
+	setIfAbsent(md, MetadataRequestID, log.ExtractRequestID(ctx))
+}
+
+// Extract 实现 Propagator 接口
+// 消息不携带 trace_id 时原样返回 ctx
+func (LogPropagator) Extract(ctx context.Context, md map[string]string) context.Context {
+	traceID := md[MetadataTraceID]
+	if traceID == "" {
+		return ctx
+	}
+	return log.WithTraceContext(ctx, traceID, newSpanID(), md[MetadataRequestID])
+}
+
+func setIfAbsent(md map[string]string, key, value string) {
+	if value == "" {
+		return
+	}
+	if _, ok := md[key]; !ok {
+		md[key] = value
+	}
+}
+
+// newSpanID 生成 8 字节（16 位十六进制）的 span ID
+func newSpanID() string {
+	return randomHex(8)
+}
+
+// randomHex 生成 n 字节随机数的十六进制表示
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
+
+// compositePropagator 依次执行多个传播器
+type compositePropagator []Propagator
+
+// CompositePropagator 组合多个传播器
+// Inject 依次写入；Extract 依次恢复，后面的传播器可以覆盖前面的结果
+func CompositePropagator(propagators ...Propagator) Propagator {
+	return compositePropagator(propagators)
+}
+
+func (c compositePropagator) Inject(ctx context.Context, md map[string]string) {
+	for _, p := range c {
+		p.Inject(ctx, md)
+	}
+}
+
+func (c compositePropagator) Extract(ctx context.Context, md map[string]string) context.Context {
+	for _, p := range c {
+		ctx = p.Extract(ctx, md)
+	}
+	return ctx
+}
+
+var (
+	propagator   Propagator = LogPropagator{}
+	propagatorMu sync.RWMutex
+)
+
+// SetPropagator 设置全局传播器（为 nil 时关闭传播）
+// 应在创建发布者/订阅者之前调用
+func SetPropagator(p Propagator) {
+	propagatorMu.Lock()
+	defer propagatorMu.Unlock()
+	propagator = p
+}
+
+// GetPropagator 返回全局传播器
+func GetPropagator() Propagator {
+	propagatorMu.RLock()
+	defer propagatorMu.RUnlock()
+	return propagator
+}
+
+// InjectTraceContext 将 ctx 中的追踪信息写入消息 Metadata
+// 不修改传入的消息：需要写入时返回带新 Metadata 的副本（共享 Payload）
+// 由各提供者的发布者在发送前调用
+func InjectTraceContext(ctx context.Context, msg *Message) *Message {
+	p := GetPropagator()
+	if p == nil || msg == nil {
+		return msg
+	}
+
+	md := make(map[string]string, len(msg.Metadata)+3)
+	for k, v := range msg.Metadata {
+		md[k] = v
+	}
+	p.Inject(ctx, md)
+	if len(md) == len(msg.Metadata) {
+		return msg
+	}
+
+	return &Message{
+		UUID:      msg.UUID,
+		Metadata:  md,
+		Payload:   msg.Payload,
+		Attempts:  msg.Attempts,
+		Timestamp: msg.Timestamp,
+		Topic:     msg.Topic,
+		Channel:   msg.Channel,
+	}
+}
+
+// ExtractTraceContext 从消息 Metadata 恢复追踪上下文
+// 由各提供者的订阅者在调用 handler 前调用
+func ExtractTraceContext(ctx context.Context, msg *Message) context.Context {
+	p := GetPropagator()
+	if p == nil || msg == nil {
+		return ctx
+	}
+	return p.Extract(ctx, msg.Metadata)
+}
@@ -0,0 +1,61 @@
+package messaging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/FangcunMount/component-base/pkg/log"
+)
+
+func TestInjectTraceContextCopiesMessage(t *testing.T) {
+	ctx := log.WithTraceContext(context.Background(), "trace-1", "span-1", "req-1")
+	msg := NewMessage("msg-1", []byte("payload"))
+	msg.Metadata["tenant"] = "t1"
+
+	injected := InjectTraceContext(ctx, msg)
+	if injected == msg {
+		t.Fatalf("inject should not modify the caller's message")
+	}
+	if _, ok := msg.Metadata[MetadataTraceID]; ok {
+		t.Fatalf("original metadata modified: %v", msg.Metadata)
+	}
+	md := injected.Metadata
+	if md[MetadataTraceID] != "trace-1" || md[MetadataSpanID] != "span-1" || md[MetadataRequestID] != "req-1" || md["tenant"] != "t1" {
+		t.Fatalf("injected metadata = %v", md)
+	}
+
+	// 已有的追踪信息不被覆盖，无需写入时返回原消息
+	if again := InjectTraceContext(context.Background(), injected); again != injected {
+		t.Fatalf("message without new trace fields should be returned as is")
+	}
+}
+
+func TestExtractTraceContextStartsNewSpan(t *testing.T) {
+	msg := NewMessage("msg-1", nil)
+	msg.Metadata[MetadataTraceID] = "trace-1"
+	msg.Metadata[MetadataSpanID] = "producer-span"
+	msg.Metadata[MetadataRequestID] = "req-1"
+
+	ctx := ExtractTraceContext(context.Background(), msg)
+	if log.ExtractTraceID(ctx) != "trace-1" || log.ExtractRequestID(ctx) != "req-1" {
+		t.Fatalf("trace/request id not restored")
+	}
+	if span := log.ExtractSpanID(ctx); span == "" || span == "producer-span" {
+		t.Fatalf("span id = %q, want a new span", span)
+	}
+
+	if ctx := ExtractTraceContext(context.Background(), NewMessage("msg-2", nil)); log.ExtractTraceID(ctx) != "" {
+		t.Fatalf("message without trace id should not add trace context")
+	}
+}
+
+func TestSetPropagatorDisablesPropagation(t *testing.T) {
+	SetPropagator(nil)
+	defer SetPropagator(LogPropagator{})
+
+	ctx := log.WithTraceID(context.Background(), "trace-1")
+	msg := NewMessage("msg-1", nil)
+	if InjectTraceContext(ctx, msg) != msg || len(msg.Metadata) != 0 {
+		t.Fatalf("nil propagator should disable injection")
+	}
+}
@@ -0,0 +1,308 @@
+// Package provision 根据事件目录预先创建消息中间件的拓扑
+//
+// 从 eventcatalog.Catalog 的 topic 与订阅推导出计划：每个 topic、ChannelPolicy 返回的 channel，
+// 以及可选的死信 topic（<topic>.dlq）与其 channel。计划可以只输出（dry-run），
+// 也可以应用到任意 Target：
+//   - NSQ：nsq.TopicCreator（创建 topic 与 channel，忽略绑定与优先级）
+//   - RabbitMQ：rabbitmq.Provisioner（声明 exchange、队列与绑定，实现 QueueTarget）
+//
+// 所有操作都是幂等的，可以在每次启动时执行。
+//
+// 使用示例：
+//
+//	plan, err := provision.NewPlan(catalog, provision.Options{
+//	    Channels:   provision.StaticChannels("order-service"),
+//	    DeadLetter: true,
+//	    Broker:     messaging.ProviderRabbitMQ,
+//	})
+//	target, err := rabbitmq.NewProvisioner(url)
+//	defer target.Close()
+//
+//	runner := processruntime.Runner[State, Prepared]{
+//	    Stages: []processruntime.Stage[State]{
+//	        provision.NewStage[State](target, plan, provision.StageConfig{DryRun: *dryRun}),
+//	        // ...
+//	    },
+//	}
+package provision
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/FangcunMount/component-base/pkg/eventcatalog"
+	"github.com/FangcunMount/component-base/pkg/messaging"
+	"github.com/FangcunMount/component-base/pkg/processruntime"
+)
+
+// DefaultDeadLetterChannel 死信 topic 默认的 channel（与 NewRedriveHandler 的示例一致）
+// RabbitMQ 的队列名全局唯一，Broker 为 RabbitMQ 时默认使用死信 topic 名称，见 Options.Broker
+const DefaultDeadLetterChannel = "redrive"
+
+// DefaultStageTimeout 启动阶段执行计划的默认超时
+const DefaultStageTimeout = 30 * time.Second
+
+// Target 拓扑创建目标
+// nsq.TopicCreator 与 rabbitmq.Provisioner 均已实现
+type Target interface {
+	// EnsureTopic 确保 topic（RabbitMQ 中为 exchange）存在
+	EnsureTopic(ctx context.Context, topic string) error
+
+	// EnsureChannel 确保 channel（RabbitMQ 中为使用默认绑定的队列）存在
+	EnsureChannel(ctx context.Context, topic, channel string) error
+}
+
+// QueueTarget channel 为队列的目标（可选能力），Apply 通过它应用 Channel 的绑定与优先级
+// rabbitmq.Provisioner 已实现；未实现的目标（如 NSQ）使用 EnsureChannel
+type QueueTarget interface {
+	Target
+
+	// EnsureQueue 声明 channel 对应的队列；binding 为 nil 时不创建绑定，priority 时声明为优先级队列
+	EnsureQueue(ctx context.Context, topic, channel string, binding *messaging.Binding, priority bool) error
+}
+
+// Channel 需要创建的 channel
+type Channel struct {
+	// Name channel 名称
+	Name string
+
+	// Binding RabbitMQ 队列的绑定规则，应与订阅者一致：
+	//   - &messaging.Binding{}：默认绑定（与 Subscribe 一致，topic exchange 上为 #）
+	//   - 指定 RoutingKeys/Headers：与 SubscribeWithBinding 一致
+	//   - nil：只声明队列，绑定交给订阅者（订阅时才确定绑定的队列）
+	// 多余的绑定不会随订阅者的绑定变化而删除，队列会收到所有匹配任一绑定的消息
+	Binding *messaging.Binding
+
+	// Priority 声明为优先级队列（x-max-priority），与 SubscribeWithPriority 一致
+	Priority bool
+}
+
+// ChannelPolicy 为目录中的 topic 返回需要创建的 channel
+// 返回空切片时只创建 topic
+type ChannelPolicy func(sub eventcatalog.TopicSubscription) []Channel
+
+// StaticChannels 每个 topic 都创建相同的 channel（通常是服务名），使用默认绑定
+// 注意 RabbitMQ 的队列名全局唯一：同名 channel 对应同一个队列，绑定到所有 topic 的 exchange；
+// 使用 SubscribeWithBinding 订阅的队列应使用 UnboundChannels 或自定义 ChannelPolicy
+func StaticChannels(names ...string) ChannelPolicy {
+	return staticChannels(names, func() *messaging.Binding { return &messaging.Binding{} })
+}
+
+// UnboundChannels 每个 topic 都创建相同的 channel，但不创建绑定，绑定由订阅者负责
+// 订阅者上线之前队列收不到消息，只用于预先声明队列参数
+func UnboundChannels(names ...string) ChannelPolicy {
+	return staticChannels(names, func() *messaging.Binding { return nil })
+}
+
+func staticChannels(names []string, binding func() *messaging.Binding) ChannelPolicy {
+	return func(eventcatalog.TopicSubscription) []Channel {
+		channels := make([]Channel, 0, len(names))
+		for _, name := range names {
+			channels = append(channels, Channel{Name: name, Binding: binding()})
+		}
+		return channels
+	}
+}
+
+// Options 计划选项
+type Options struct {
+	// Channels channel 策略（可选），未配置时只创建 topic
+	Channels ChannelPolicy
+
+	// DeadLetter 是否同时创建死信 topic 及其 channel
+	// RabbitMQ 中没有绑定队列的 exchange 会丢弃消息，使用死信策略时应开启
+	DeadLetter bool
+
+	// DeadLetterTopicFunc 死信主题命名函数（默认 messaging.DeadLetterTopic）
+	DeadLetterTopicFunc func(topic string) string
+
+	// DeadLetterChannel 死信 topic 的 channel
+	// 默认为 redrive；Broker 为 RabbitMQ 时默认为死信 topic 名称（每个死信 topic 一个队列）
+	DeadLetterChannel string
+
+	// Broker 目标的 Provider（可选），用于选择与 broker 相关的默认值
+	Broker messaging.Provider
+}
+
+// Step 计划中的一个步骤；Channel 为空时表示创建 topic
+type Step struct {
+	Topic    string
+	Channel  string
+	Binding  *messaging.Binding
+	Priority bool
+}
+
+func (s Step) String() string {
+	if s.Channel == "" {
+		return "topic   " + s.Topic
+	}
+	line := "channel " + s.Topic + "/" + s.Channel
+	switch {
+	case s.Binding == nil:
+		line += " [unbound]"
+	case len(s.Binding.RoutingKeys) > 0:
+		line += " [keys=" + strings.Join(s.Binding.RoutingKeys, ",") + "]"
+	case len(s.Binding.Headers) > 0:
+		pairs := make([]string, 0, len(s.Binding.Headers))
+		for k, v := range s.Binding.Headers {
+			pairs = append(pairs, k+"="+v)
+		}
+		sort.Strings(pairs)
+		match := "any"
+		if s.Binding.MatchAll {
+			match = "all"
+		}
+		line += " [headers(" + match + ")=" + strings.Join(pairs, ",") + "]"
+	}
+	if s.Priority {
+		line += " [priority]"
+	}
+	return line
+}
+
+// Plan 拓扑创建计划（按 topic 名称有序）
+type Plan struct {
+	Steps []Step
+}
+
+// String 返回可读的计划（每行一个步骤），用于 dry-run
+func (p Plan) String() string {
+	var b strings.Builder
+	for _, step := range p.Steps {
+		b.WriteString(step.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// NewPlan 根据事件目录生成计划
+// 同一 topic 下重复的 channel 只保留第一个
+func NewPlan(catalog *eventcatalog.Catalog, opts Options) (Plan, error) {
+	if catalog == nil {
+		return Plan{}, fmt.Errorf("catalog cannot be nil")
+	}
+	if opts.DeadLetterTopicFunc == nil {
+		opts.DeadLetterTopicFunc = messaging.DeadLetterTopic
+	}
+	deadLetterChannel := func(dlq string) string {
+		switch {
+		case opts.DeadLetterChannel != "":
+			return opts.DeadLetterChannel
+		case opts.Broker == messaging.ProviderRabbitMQ:
+			return dlq
+		default:
+			return DefaultDeadLetterChannel
+		}
+	}
+
+	subs := catalog.TopicSubscriptions()
+	sort.Slice(subs, func(i, j int) bool { return subs[i].TopicName < subs[j].TopicName })
+
+	var plan Plan
+	seen := make(map[[2]string]bool)
+	add := func(step Step) {
+		key := [2]string{step.Topic, step.Channel}
+		if !seen[key] {
+			seen[key] = true
+			plan.Steps = append(plan.Steps, step)
+		}
+	}
+	for _, sub := range subs {
+		if sub.TopicName == "" {
+			return Plan{}, fmt.Errorf("topic %s has no name", sub.TopicKey)
+		}
+		add(Step{Topic: sub.TopicName})
+		if opts.Channels != nil {
+			channels := append([]Channel(nil), opts.Channels(sub)...)
+			sort.SliceStable(channels, func(i, j int) bool { return channels[i].Name < channels[j].Name })
+			for _, channel := range channels {
+				if channel.Name == "" {
+					return Plan{}, fmt.Errorf("channel policy returned an empty channel for topic %s", sub.TopicName)
+				}
+				add(Step{Topic: sub.TopicName, Channel: channel.Name, Binding: channel.Binding, Priority: channel.Priority})
+			}
+		}
+		if opts.DeadLetter {
+			dlq := opts.DeadLetterTopicFunc(sub.TopicName)
+			add(Step{Topic: dlq})
+			add(Step{Topic: dlq, Channel: deadLetterChannel(dlq), Binding: &messaging.Binding{}})
+		}
+	}
+	return plan, nil
+}
+
+// Apply 依次执行计划，单个步骤失败不影响其余步骤，返回聚合错误
+// 目标实现 QueueTarget 时按步骤的绑定与优先级声明队列
+func Apply(ctx context.Context, target Target, plan Plan) error {
+	queues, _ := target.(QueueTarget)
+
+	var errs []error
+	for _, step := range plan.Steps {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		var err error
+		switch {
+		case step.Channel == "":
+			err = target.EnsureTopic(ctx, step.Topic)
+		case queues != nil:
+			err = queues.EnsureQueue(ctx, step.Topic, step.Channel, step.Binding, step.Priority)
+		default:
+			err = target.EnsureChannel(ctx, step.Topic, step.Channel)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", step, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// StageConfig 启动阶段配置
+type StageConfig struct {
+	// DryRun 只输出计划，不执行
+	DryRun bool
+
+	// Output dry-run 的输出（默认 os.Stdout）
+	Output io.Writer
+
+	// Timeout 执行计划的超时（默认 30s）
+	Timeout time.Duration
+}
+
+// NewStage 创建执行计划的 processruntime 启动阶段
+func NewStage[S any](target Target, plan Plan, cfg StageConfig) processruntime.Stage[S] {
+	if cfg.Output == nil {
+		cfg.Output = os.Stdout
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultStageTimeout
+	}
+	return stage[S]{target: target, plan: plan, cfg: cfg}
+}
+
+type stage[S any] struct {
+	target Target
+	plan   Plan
+	cfg    StageConfig
+}
+
+func (s stage[S]) Name() string {
+	return "messaging-provision"
+}
+
+func (s stage[S]) Run(*S) error {
+	if s.cfg.DryRun {
+		_, err := io.WriteString(s.cfg.Output, s.plan.String())
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	return Apply(ctx, s.target, s.plan)
+}
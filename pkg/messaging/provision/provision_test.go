@@ -0,0 +1,216 @@
+package provision
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/FangcunMount/component-base/pkg/eventcatalog"
+	"github.com/FangcunMount/component-base/pkg/messaging"
+	"github.com/FangcunMount/component-base/pkg/messaging/nsq"
+	"github.com/FangcunMount/component-base/pkg/processruntime"
+)
+
+const catalogYAML = `
+version: "1"
+topics:
+  order:
+    name: order.events
+  payment:
+    name: payment.events
+  unused:
+    name: unused.events
+events:
+  order.created:
+    topic: order
+    delivery: best_effort
+    handler: order_handler
+  payment.paid:
+    topic: payment
+    delivery: durable_outbox
+    handler: payment_handler
+`
+
+func testCatalog(t *testing.T) *eventcatalog.Catalog {
+	t.Helper()
+	cfg, err := eventcatalog.ParseWithOptions([]byte(catalogYAML), eventcatalog.ValidateOptions{})
+	if err != nil {
+		t.Fatalf("Parse catalog: %v", err)
+	}
+	return eventcatalog.NewCatalog(cfg)
+}
+
+type fakeTarget struct {
+	mu    sync.Mutex
+	steps []string
+	fail  string
+}
+
+func (f *fakeTarget) EnsureTopic(ctx context.Context, topic string) error {
+	return f.record(Step{Topic: topic})
+}
+
+func (f *fakeTarget) EnsureChannel(ctx context.Context, topic, channel string) error {
+	return f.record(Step{Topic: topic, Channel: channel, Binding: &messaging.Binding{}})
+}
+
+type fakeQueueTarget struct {
+	fakeTarget
+}
+
+func (f *fakeQueueTarget) EnsureQueue(ctx context.Context, topic, channel string, binding *messaging.Binding, priority bool) error {
+	return f.record(Step{Topic: topic, Channel: channel, Binding: binding, Priority: priority})
+}
+
+func (f *fakeTarget) record(step Step) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if step.Topic == f.fail {
+		return errors.New("broker rejected")
+	}
+	f.steps = append(f.steps, step.String())
+	return nil
+}
+
+func TestNewPlanFromCatalog(t *testing.T) {
+	plan, err := NewPlan(testCatalog(t), Options{
+		Channels: func(sub eventcatalog.TopicSubscription) []Channel {
+			return []Channel{
+				{Name: "order-service", Binding: &messaging.Binding{}},
+				{Name: sub.TopicKey + "-audit", Binding: &messaging.Binding{RoutingKeys: []string{"*.created"}}, Priority: true},
+			}
+		},
+		DeadLetter: true,
+	})
+	if err != nil {
+		t.Fatalf("NewPlan: %v", err)
+	}
+
+	want := `topic   order.events
+channel order.events/order-audit [keys=*.created] [priority]
+channel order.events/order-service
+topic   order.events.dlq
+channel order.events.dlq/redrive
+topic   payment.events
+channel payment.events/order-service
+channel payment.events/payment-audit [keys=*.created] [priority]
+topic   payment.events.dlq
+channel payment.events.dlq/redrive
+`
+	if got := plan.String(); got != want {
+		t.Fatalf("plan =\n%s\nwant\n%s", got, want)
+	}
+
+	topicsOnly, err := NewPlan(testCatalog(t), Options{})
+	if err != nil || len(topicsOnly.Steps) != 2 {
+		t.Fatalf("topics-only plan = %v, %v", topicsOnly.Steps, err)
+	}
+	if _, err := NewPlan(testCatalog(t), Options{Channels: StaticChannels("")}); err == nil {
+		t.Fatalf("empty channel names should be rejected")
+	}
+}
+
+func TestApplyDeclaresQueuesWithBindings(t *testing.T) {
+	plan, err := NewPlan(testCatalog(t), Options{
+		Channels:   UnboundChannels("svc"),
+		DeadLetter: true,
+		Broker:     messaging.ProviderRabbitMQ,
+	})
+	if err != nil {
+		t.Fatalf("NewPlan: %v", err)
+	}
+
+	target := &fakeQueueTarget{}
+	if err := Apply(context.Background(), target, plan); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	want := []string{
+		"topic   order.events",
+		"channel order.events/svc [unbound]",
+		"topic   order.events.dlq",
+		"channel order.events.dlq/order.events.dlq",
+		"topic   payment.events",
+		"channel payment.events/svc [unbound]",
+		"topic   payment.events.dlq",
+		"channel payment.events.dlq/payment.events.dlq",
+	}
+	if strings.Join(target.steps, ";") != strings.Join(want, ";") {
+		t.Fatalf("applied steps = %v", target.steps)
+	}
+}
+
+func TestApplyContinuesAfterFailures(t *testing.T) {
+	plan, _ := NewPlan(testCatalog(t), Options{Channels: StaticChannels("svc")})
+	target := &fakeTarget{fail: "order.events"}
+
+	err := Apply(context.Background(), target, plan)
+	if err == nil || !strings.Contains(err.Error(), "channel order.events/svc: broker rejected") {
+		t.Fatalf("Apply = %v", err)
+	}
+	if strings.Join(target.steps, ";") != "topic   payment.events;channel payment.events/svc" {
+		t.Fatalf("applied steps = %v", target.steps)
+	}
+}
+
+func TestStageRunsInRunner(t *testing.T) {
+	plan, _ := NewPlan(testCatalog(t), Options{Channels: StaticChannels("svc"), DeadLetter: true})
+
+	var out bytes.Buffer
+	target := &fakeTarget{}
+	_, failed, err := processruntime.Runner[struct{}, struct{}]{
+		Stages: []processruntime.Stage[struct{}]{NewStage[struct{}](target, plan, StageConfig{DryRun: true, Output: &out})},
+	}.Run()
+	if err != nil || failed != "" {
+		t.Fatalf("dry run: failed=%s err=%v", failed, err)
+	}
+	if out.String() != plan.String() || len(target.steps) != 0 {
+		t.Fatalf("dry run should only print the plan: out=%q steps=%v", out.String(), target.steps)
+	}
+
+	target.fail = "payment.events.dlq"
+	_, failed, err = processruntime.Runner[struct{}, struct{}]{
+		Stages: []processruntime.Stage[struct{}]{NewStage[struct{}](target, plan, StageConfig{})},
+	}.Run()
+	if err == nil || failed != "messaging-provision" {
+		t.Fatalf("failed stage = %q, err = %v", failed, err)
+	}
+	if len(target.steps) != len(plan.Steps)-2 {
+		t.Fatalf("applied %d of %d steps", len(target.steps), len(plan.Steps))
+	}
+}
+
+func TestApplyCreatesNSQTopicsAndChannels(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	nsqd := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.URL.Path+"?"+r.URL.RawQuery)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer nsqd.Close()
+
+	plan, _ := NewPlan(testCatalog(t), Options{Channels: StaticChannels("svc")})
+	creator := nsq.NewTopicCreator(strings.TrimPrefix(nsqd.URL, "http://"), slog.Default())
+	for i := 0; i < 2; i++ { // 重复执行是幂等的
+		if err := Apply(context.Background(), creator, plan); err != nil {
+			t.Fatalf("Apply: %v", err)
+		}
+	}
+
+	want := []string{
+		"/topic/create?topic=order.events",
+		"/channel/create?topic=order.events&channel=svc",
+		"/topic/create?topic=payment.events",
+		"/channel/create?topic=payment.events&channel=svc",
+	}
+	if len(requests) != 2*len(want) || strings.Join(requests[:len(want)], ",") != strings.Join(want, ",") {
+		t.Fatalf("nsqd requests = %v", requests)
+	}
+}
@@ -0,0 +1,94 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+)
+
+// ========== 批量发布 ==========
+
+// BatchPublisher 支持批量发布的发布者
+//
+// 各提供者的实现：
+//   - nsq：MPUB（一次往返，整批成功或整批失败）
+//   - rabbitmq：同一 channel 连续发布，统一等待 publisher confirm
+//
+// 不支持的发布者由 PublishBatch 逐条发布
+type BatchPublisher interface {
+	// PublishBatch 批量发布消息
+	// 部分或全部失败时返回 *BatchPublishError，其中按下标记录每条消息的错误
+	PublishBatch(ctx context.Context, topic string, msgs []*Message) error
+}
+
+// BatchPublishError 批量发布的逐条错误
+// Errors 与发布的消息一一对应，成功的消息对应 nil
+type BatchPublishError struct {
+	Errors []error
+}
+
+// NewBatchPublishError 根据逐条错误创建批量错误，全部成功时返回 nil
+func NewBatchPublishError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return &BatchPublishError{Errors: errs}
+		}
+	}
+	return nil
+}
+
+// Error 实现 error 接口
+func (e *BatchPublishError) Error() string {
+	failed := e.Failed()
+	return fmt.Sprintf("messaging: %d of %d messages failed to publish, first error: %v",
+		len(failed), len(e.Errors), e.Errors[failed[0]])
+}
+
+// Unwrap 支持 errors.Is / errors.As 匹配任意一条消息的错误
+func (e *BatchPublishError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// Failed 返回失败消息的下标
+func (e *BatchPublishError) Failed() []int {
+	var failed []int
+	for i, err := range e.Errors {
+		if err != nil {
+			failed = append(failed, i)
+		}
+	}
+	return failed
+}
+
+// PublishBatch 批量发布消息
+// 发布者实现了 BatchPublisher 时使用其批量实现，否则逐条 PublishMessage；
+// 失败时返回 *BatchPublishError，可通过 errors.As 获取每条消息的结果
+//
+// 使用示例：
+//
+//	err := messaging.PublishBatch(ctx, publisher, "order.created", msgs)
+//	var batchErr *messaging.BatchPublishError
+//	if errors.As(err, &batchErr) {
+//	    for _, i := range batchErr.Failed() {
+//	        retry(msgs[i])
+//	    }
+//	}
+func PublishBatch(ctx context.Context, publisher Publisher, topic string, msgs []*Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	if bp, ok := publisher.(BatchPublisher); ok {
+		return bp.PublishBatch(ctx, topic, msgs)
+	}
+
+	errs := make([]error, len(msgs))
+	for i, msg := range msgs {
+		errs[i] = publisher.PublishMessage(ctx, topic, msg)
+	}
+	return NewBatchPublishError(errs)
+}
@@ -0,0 +1,55 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type failingPublisher struct {
+	fakePublisher
+	failUUID string
+	err      error
+}
+
+func (p *failingPublisher) PublishMessage(ctx context.Context, topic string, msg *Message) error {
+	if msg.UUID == p.failUUID {
+		return p.err
+	}
+	return p.fakePublisher.PublishMessage(ctx, topic, msg)
+}
+
+func TestPublishBatchFallbackReportsPerMessageErrors(t *testing.T) {
+	brokerErr := errors.New("broker down")
+	pub := &failingPublisher{failUUID: "msg-2", err: brokerErr}
+	msgs := []*Message{NewMessage("msg-1", nil), NewMessage("msg-2", nil), NewMessage("msg-3", nil)}
+
+	err := PublishBatch(context.Background(), pub, "jobs", msgs)
+	var batchErr *BatchPublishError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("error = %v, want *BatchPublishError", err)
+	}
+	if failed := batchErr.Failed(); len(failed) != 1 || failed[0] != 1 {
+		t.Fatalf("failed = %v, want [1]", failed)
+	}
+	if !errors.Is(err, brokerErr) {
+		t.Fatalf("batch error should unwrap to the message error")
+	}
+	if len(pub.messages()) != 2 {
+		t.Fatalf("published %d messages, want 2", len(pub.messages()))
+	}
+}
+
+func TestPublishBatchSucceeds(t *testing.T) {
+	pub := &fakePublisher{}
+	msgs := []*Message{NewMessage("msg-1", nil), NewMessage("msg-2", nil)}
+	if err := PublishBatch(context.Background(), pub, "jobs", msgs); err != nil {
+		t.Fatalf("publish batch: %v", err)
+	}
+	if err := PublishBatch(context.Background(), pub, "jobs", nil); err != nil {
+		t.Fatalf("empty batch: %v", err)
+	}
+	if len(pub.messages()) != 2 {
+		t.Fatalf("published %d messages, want 2", len(pub.messages()))
+	}
+}
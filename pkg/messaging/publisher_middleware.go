@@ -0,0 +1,216 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ========== 发布侧中间件 ==========
+
+// 发布侧中间件写入的 Metadata 键
+const (
+	// MetadataSource 消息来源（发布服务名）
+	MetadataSource = "source"
+
+	// MetadataPublishedAt 发布时间（RFC3339Nano）
+	MetadataPublishedAt = "published_at"
+)
+
+// ErrPayloadTooLarge 消息体超过发布大小限制
+var ErrPayloadTooLarge = errors.New("messaging: payload too large")
+
+// PublishFunc 发布一条消息
+type PublishFunc func(ctx context.Context, topic string, msg *Message) error
+
+// PublisherMiddleware 发布侧中间件，用于在发布前后执行额外逻辑（元数据、指标、校验、加密等）
+// 中间件不应修改传入的消息，需要改写时将副本传给下一层
+type PublisherMiddleware func(PublishFunc) PublishFunc
+
+// WrapPublisher 为发布者添加发布侧中间件
+//
+// 中间件按传入顺序执行（第一个在最外层）。Publish 的原始字节包装为消息后经过中间件链，
+// 以 PublishMessage 发布（NSQ 使用 Metadata 信封，订阅者透明解码）。
+// 底层发布者的延迟发布（DelayedPublisher）与原生优先级（PriorityPublisher）能力得以保留，
+// 经这些能力发布的消息同样经过中间件链；PublishBatch 逐条经过中间件链发布。
+//
+// 使用示例：
+//
+//	publisher := messaging.WrapPublisher(bus.Publisher(),
+//	    messaging.PublishMetadataMiddleware("order-service"),
+//	    messaging.PublishMetricsMiddleware(collector),
+//	    messaging.PublishSizeLimitMiddleware(1<<20),
+//	)
+func WrapPublisher(publisher Publisher, middlewares ...PublisherMiddleware) Publisher {
+	w := &publisherWrapper{Publisher: publisher, middlewares: middlewares}
+	w.publish = w.chain(publisher.PublishMessage)
+
+	_, delayed := publisher.(DelayedPublisher)
+	_, priority := publisher.(PriorityPublisher)
+	switch {
+	case delayed && priority:
+		return &delayedPriorityPublisherWrapper{w}
+	case delayed:
+		return &delayedPublisherWrapper{w}
+	case priority:
+		return &priorityPublisherWrapper{w}
+	}
+	return w
+}
+
+// publisherWrapper 经过中间件链的发布者
+type publisherWrapper struct {
+	Publisher
+	middlewares []PublisherMiddleware
+	publish     PublishFunc
+}
+
+// Publish 实现 Publisher 接口
+func (w *publisherWrapper) Publish(ctx context.Context, topic string, body []byte) error {
+	return w.publish(ctx, topic, NewMessage("", body))
+}
+
+// PublishMessage 实现 Publisher 接口
+func (w *publisherWrapper) PublishMessage(ctx context.Context, topic string, msg *Message) error {
+	return w.publish(ctx, topic, msg)
+}
+
+// chain 以 final 为终点组装中间件链
+func (w *publisherWrapper) chain(final PublishFunc) PublishFunc {
+	for i := len(w.middlewares) - 1; i >= 0; i-- {
+		final = w.middlewares[i](final)
+	}
+	return final
+}
+
+func (w *publisherWrapper) publishDelayed(ctx context.Context, topic string, msg *Message, delay time.Duration) error {
+	dp := w.Publisher.(DelayedPublisher)
+	return w.chain(func(ctx context.Context, topic string, msg *Message) error {
+		return dp.PublishDelayed(ctx, topic, msg, delay)
+	})(ctx, topic, msg)
+}
+
+func (w *publisherWrapper) publishWithPriority(ctx context.Context, topic string, msg *Message, priority Priority) error {
+	pp := w.Publisher.(PriorityPublisher)
+	return w.chain(func(ctx context.Context, topic string, msg *Message) error {
+		return pp.PublishWithPriority(ctx, topic, msg, priority)
+	})(ctx, topic, msg)
+}
+
+// delayedPublisherWrapper 保留 DelayedPublisher 能力
+type delayedPublisherWrapper struct {
+	*publisherWrapper
+}
+
+// PublishDelayed 实现 DelayedPublisher 接口
+func (w *delayedPublisherWrapper) PublishDelayed(ctx context.Context, topic string, msg *Message, delay time.Duration) error {
+	return w.publishDelayed(ctx, topic, msg, delay)
+}
+
+// priorityPublisherWrapper 保留 PriorityPublisher 能力
+type priorityPublisherWrapper struct {
+	*publisherWrapper
+}
+
+// PublishWithPriority 实现 PriorityPublisher 接口
+func (w *priorityPublisherWrapper) PublishWithPriority(ctx context.Context, topic string, msg *Message, priority Priority) error {
+	return w.publishWithPriority(ctx, topic, msg, priority)
+}
+
+// delayedPriorityPublisherWrapper 同时保留 DelayedPublisher 与 PriorityPublisher 能力
+type delayedPriorityPublisherWrapper struct {
+	*publisherWrapper
+}
+
+// PublishDelayed 实现 DelayedPublisher 接口
+func (w *delayedPriorityPublisherWrapper) PublishDelayed(ctx context.Context, topic string, msg *Message, delay time.Duration) error {
+	return w.publishDelayed(ctx, topic, msg, delay)
+}
+
+// PublishWithPriority 实现 PriorityPublisher 接口
+func (w *delayedPriorityPublisherWrapper) PublishWithPriority(ctx context.Context, topic string, msg *Message, priority Priority) error {
+	return w.publishWithPriority(ctx, topic, msg, priority)
+}
+
+// ========== 内置发布侧中间件 ==========
+
+// PublishMetadataMiddleware 元数据补全中间件
+// 写入 source、published_at 以及 ctx 中的追踪信息（trace_id/span_id/request_id），
+// 不覆盖消息中已有的值；消息没有 UUID 时生成一个。不修改传入的消息
+func PublishMetadataMiddleware(source string) PublisherMiddleware {
+	return func(next PublishFunc) PublishFunc {
+		return func(ctx context.Context, topic string, msg *Message) error {
+			md := make(map[string]string, len(msg.Metadata)+5)
+			for k, v := range msg.Metadata {
+				md[k] = v
+			}
+			setIfAbsent(md, MetadataSource, source)
+			setIfAbsent(md, MetadataPublishedAt, time.Now().UTC().Format(time.RFC3339Nano))
+			if p := GetPropagator(); p != nil {
+				p.Inject(ctx, md)
+			}
+
+			id := msg.UUID
+			if id == "" {
+				id = uuid.NewString()
+			}
+			return next(ctx, topic, &Message{
+				UUID:      id,
+				Metadata:  md,
+				Payload:   msg.Payload,
+				Attempts:  msg.Attempts,
+				Timestamp: msg.Timestamp,
+				Topic:     msg.Topic,
+				Channel:   msg.Channel,
+			})
+		}
+	}
+}
+
+// PublishMetricsCollector 发布指标收集器
+type PublishMetricsCollector interface {
+	// IncrementPublished 增加发布成功计数
+	IncrementPublished(topic string)
+
+	// IncrementPublishFailed 增加发布失败计数
+	IncrementPublishFailed(topic string)
+
+	// RecordPublishDuration 记录发布耗时
+	RecordPublishDuration(topic string, duration time.Duration)
+}
+
+// PublishMetricsMiddleware 发布指标中间件
+func PublishMetricsMiddleware(collector PublishMetricsCollector) PublisherMiddleware {
+	return func(next PublishFunc) PublishFunc {
+		return func(ctx context.Context, topic string, msg *Message) error {
+			start := time.Now()
+
+			err := next(ctx, topic, msg)
+
+			collector.RecordPublishDuration(topic, time.Since(start))
+			if err != nil {
+				collector.IncrementPublishFailed(topic)
+			} else {
+				collector.IncrementPublished(topic)
+			}
+			return err
+		}
+	}
+}
+
+// PublishSizeLimitMiddleware 消息体大小限制中间件
+// 消息体超过 maxBytes 时不发布，返回 ErrPayloadTooLarge
+func PublishSizeLimitMiddleware(maxBytes int) PublisherMiddleware {
+	return func(next PublishFunc) PublishFunc {
+		return func(ctx context.Context, topic string, msg *Message) error {
+			if len(msg.Payload) > maxBytes {
+				return fmt.Errorf("%w: %d bytes exceeds limit of %d bytes on topic %s",
+					ErrPayloadTooLarge, len(msg.Payload), maxBytes, topic)
+			}
+			return next(ctx, topic, msg)
+		}
+	}
+}
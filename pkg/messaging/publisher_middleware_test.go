@@ -0,0 +1,164 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/FangcunMount/component-base/pkg/log"
+)
+
+type fakePublishMetrics struct {
+	mu        sync.Mutex
+	published map[string]int
+	failed    map[string]int
+	durations int
+}
+
+func (m *fakePublishMetrics) IncrementPublished(topic string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.published == nil {
+		m.published = make(map[string]int)
+	}
+	m.published[topic]++
+}
+
+func (m *fakePublishMetrics) IncrementPublishFailed(topic string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failed == nil {
+		m.failed = make(map[string]int)
+	}
+	m.failed[topic]++
+}
+
+func (m *fakePublishMetrics) RecordPublishDuration(topic string, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.durations++
+}
+
+func TestWrapPublisherRunsMiddlewaresInOrder(t *testing.T) {
+	var order []string
+	trace := func(name string) PublisherMiddleware {
+		return func(next PublishFunc) PublishFunc {
+			return func(ctx context.Context, topic string, msg *Message) error {
+				order = append(order, name)
+				return next(ctx, topic, msg)
+			}
+		}
+	}
+
+	pub := &fakePublisher{}
+	wrapped := WrapPublisher(pub, trace("outer"), trace("inner"))
+	if err := wrapped.Publish(context.Background(), "orders", []byte("raw")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if err := wrapped.PublishMessage(context.Background(), "orders", NewMessage("m1", []byte("x"))); err != nil {
+		t.Fatalf("PublishMessage: %v", err)
+	}
+
+	want := []string{"outer", "inner", "outer", "inner"}
+	if len(order) != len(want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+	published := pub.messages()
+	if len(published) != 2 || string(published[0].msg.Payload) != "raw" || published[1].msg.UUID != "m1" {
+		t.Fatalf("published = %+v", published)
+	}
+}
+
+func TestPublishMetadataMiddleware(t *testing.T) {
+	pub := &fakePublisher{}
+	wrapped := WrapPublisher(pub, PublishMetadataMiddleware("order-service"))
+
+	msg := NewMessage("", []byte("x"))
+	msg.Metadata[MetadataSource] = "legacy"
+	ctx := log.WithTraceContext(context.Background(), "trace-1", "span-1", "req-1")
+	if err := wrapped.PublishMessage(ctx, "orders", msg); err != nil {
+		t.Fatalf("PublishMessage: %v", err)
+	}
+
+	got := pub.messages()[0].msg
+	if got.UUID == "" {
+		t.Fatalf("missing generated UUID")
+	}
+	if got.Metadata[MetadataSource] != "legacy" {
+		t.Fatalf("source = %q, existing value should be kept", got.Metadata[MetadataSource])
+	}
+	if _, err := time.Parse(time.RFC3339Nano, got.Metadata[MetadataPublishedAt]); err != nil {
+		t.Fatalf("published_at = %q: %v", got.Metadata[MetadataPublishedAt], err)
+	}
+	if got.Metadata[MetadataTraceID] != "trace-1" || got.Metadata[MetadataRequestID] != "req-1" {
+		t.Fatalf("trace metadata = %v", got.Metadata)
+	}
+	if msg.UUID != "" || len(msg.Metadata) != 1 {
+		t.Fatalf("middleware mutated the caller's message: %+v", msg)
+	}
+}
+
+func TestPublishMetricsAndSizeLimitMiddlewares(t *testing.T) {
+	metrics := &fakePublishMetrics{}
+	pub := &fakePublisher{}
+	wrapped := WrapPublisher(pub, PublishMetricsMiddleware(metrics), PublishSizeLimitMiddleware(4))
+
+	if err := wrapped.Publish(context.Background(), "orders", []byte("1234")); err != nil {
+		t.Fatalf("Publish within limit: %v", err)
+	}
+	if err := wrapped.Publish(context.Background(), "orders", []byte("12345")); !errors.Is(err, ErrPayloadTooLarge) {
+		t.Fatalf("error = %v, want ErrPayloadTooLarge", err)
+	}
+	if len(pub.messages()) != 1 {
+		t.Fatalf("oversized message should not be published")
+	}
+	if metrics.published["orders"] != 1 || metrics.failed["orders"] != 1 || metrics.durations != 2 {
+		t.Fatalf("metrics published=%v failed=%v durations=%d", metrics.published, metrics.failed, metrics.durations)
+	}
+}
+
+func TestWrapPublisherPreservesCapabilities(t *testing.T) {
+	var calls int
+	count := func(next PublishFunc) PublishFunc {
+		return func(ctx context.Context, topic string, msg *Message) error {
+			calls++
+			return next(ctx, topic, msg)
+		}
+	}
+
+	plain := WrapPublisher(&fakePublisher{}, count)
+	if SupportsDelay(plain) {
+		t.Fatalf("wrapper should not claim delayed publish support")
+	}
+	if _, ok := plain.(PriorityPublisher); ok {
+		t.Fatalf("wrapper should not claim native priority support")
+	}
+
+	delayed := WrapPublisher(WithTimerDelay(&fakePublisher{}, nil), count)
+	if err := PublishDelayed(context.Background(), delayed, "jobs", NewMessage("d1", nil), time.Millisecond); err != nil {
+		t.Fatalf("PublishDelayed: %v", err)
+	}
+
+	native := &fakePriorityPublisher{}
+	priority := WrapPublisher(native, count)
+	if err := PublishWithPriority(context.Background(), priority, "jobs", NewMessage("p1", nil), PriorityHigh); err != nil {
+		t.Fatalf("PublishWithPriority: %v", err)
+	}
+	if len(native.priorities) != 1 || native.priorities[0] != PriorityHigh {
+		t.Fatalf("native priorities = %v", native.priorities)
+	}
+
+	if err := PublishBatch(context.Background(), plain, "jobs", []*Message{NewMessage("b1", nil), NewMessage("b2", nil)}); err != nil {
+		t.Fatalf("PublishBatch: %v", err)
+	}
+	if calls != 4 {
+		t.Fatalf("middleware calls = %d, want every publish path to run the chain", calls)
+	}
+}
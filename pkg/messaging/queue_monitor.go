@@ -0,0 +1,262 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// ========== 队列积压监控 ==========
+
+// QueueStats 一个 topic/channel 的积压统计
+//
+// 各 Provider 的映射：
+//   - NSQ：depth（内存 + 磁盘）、in_flight_count、requeue_count、timeout_count、deferred_count、客户端数
+//   - RabbitMQ：messages_ready、messages_unacknowledged、message_stats.redeliver、consumers（无超时与延迟计数）
+type QueueStats struct {
+	Topic   string `json:"topic"`
+	Channel string `json:"channel"`
+
+	// Depth 等待投递的消息数
+	Depth int64 `json:"depth"`
+
+	// InFlight 已投递、尚未确认的消息数
+	InFlight int64 `json:"in_flight"`
+
+	// Deferred 延迟投递中的消息数
+	Deferred int64 `json:"deferred"`
+
+	// Requeued 累计重投次数（broker 重启后清零）
+	Requeued int64 `json:"requeued"`
+
+	// TimedOut 累计处理超时次数（broker 重启后清零）
+	TimedOut int64 `json:"timed_out"`
+
+	// Consumers 消费者（连接）数
+	Consumers int `json:"consumers"`
+}
+
+// QueueSnapshot 一次采样的积压快照
+type QueueSnapshot struct {
+	Broker      string       `json:"broker"`
+	GeneratedAt time.Time    `json:"generated_at"`
+	Queues      []QueueStats `json:"queues"`
+}
+
+// QueueStatsReader 积压统计读取器
+// nsq.StatsReader（nsqd /stats）与 rabbitmq.StatsReader（管理 API）均已实现
+type QueueStatsReader interface {
+	QueueSnapshot(ctx context.Context, now time.Time) (QueueSnapshot, error)
+}
+
+// 告警指标
+const (
+	QueueMetricDepth     = "depth"
+	QueueMetricInFlight  = "in_flight"
+	QueueMetricConsumers = "consumers"
+	QueueMetricRequeued  = "requeued"
+	QueueMetricTimedOut  = "timed_out"
+)
+
+// QueueAlertRule 积压告警规则，零值的阈值不检查
+type QueueAlertRule struct {
+	// Name 规则名称
+	Name string
+
+	// Topic / Channel 匹配的 topic 与 channel，为空时匹配全部
+	Topic   string
+	Channel string
+
+	// MaxDepth 等待投递的消息数超过该值时告警
+	MaxDepth int64
+
+	// MaxInFlight 未确认的消息数超过该值时告警
+	MaxInFlight int64
+
+	// MinConsumers 消费者数低于该值时告警（用于发现消费者全部下线）
+	MinConsumers int
+
+	// MaxRequeued 两次采样之间新增的重投次数超过该值时告警
+	MaxRequeued int64
+
+	// MaxTimedOut 两次采样之间新增的超时次数超过该值时告警
+	MaxTimedOut int64
+}
+
+// QueueAlert 触发的告警
+type QueueAlert struct {
+	Rule      string `json:"rule"`
+	Topic     string `json:"topic"`
+	Channel   string `json:"channel"`
+	Metric    string `json:"metric"`
+	Value     int64  `json:"value"`
+	Threshold int64  `json:"threshold"`
+}
+
+func (a QueueAlert) String() string {
+	op := ">"
+	if a.Metric == QueueMetricConsumers {
+		op = "<"
+	}
+	return fmt.Sprintf("%s: %s/%s %s=%d %s %d", a.Rule, a.Topic, a.Channel, a.Metric, a.Value, op, a.Threshold)
+}
+
+func (r QueueAlertRule) matches(q QueueStats) bool {
+	return (r.Topic == "" || r.Topic == q.Topic) && (r.Channel == "" || r.Channel == q.Channel)
+}
+
+// EvaluateQueueAlerts 根据规则评估快照；重投与超时按与 previous 的差值计算（previous 中没有的队列不检查）
+func EvaluateQueueAlerts(rules []QueueAlertRule, previous, current QueueSnapshot) []QueueAlert {
+	prev := make(map[[2]string]QueueStats, len(previous.Queues))
+	for _, q := range previous.Queues {
+		prev[[2]string{q.Topic, q.Channel}] = q
+	}
+
+	var alerts []QueueAlert
+	for _, q := range current.Queues {
+		last, hasLast := prev[[2]string{q.Topic, q.Channel}]
+		for _, rule := range rules {
+			if !rule.matches(q) {
+				continue
+			}
+			alert := func(metric string, value, threshold int64) {
+				alerts = append(alerts, QueueAlert{
+					Rule:      rule.Name,
+					Topic:     q.Topic,
+					Channel:   q.Channel,
+					Metric:    metric,
+					Value:     value,
+					Threshold: threshold,
+				})
+			}
+			if rule.MaxDepth > 0 && q.Depth > rule.MaxDepth {
+				alert(QueueMetricDepth, q.Depth, rule.MaxDepth)
+			}
+			if rule.MaxInFlight > 0 && q.InFlight > rule.MaxInFlight {
+				alert(QueueMetricInFlight, q.InFlight, rule.MaxInFlight)
+			}
+			if rule.MinConsumers > 0 && q.Consumers < rule.MinConsumers {
+				alert(QueueMetricConsumers, int64(q.Consumers), int64(rule.MinConsumers))
+			}
+			// 计数变小说明 broker 重启过，本次不检查
+			if hasLast && rule.MaxRequeued > 0 && q.Requeued >= last.Requeued && q.Requeued-last.Requeued > rule.MaxRequeued {
+				alert(QueueMetricRequeued, q.Requeued-last.Requeued, rule.MaxRequeued)
+			}
+			if hasLast && rule.MaxTimedOut > 0 && q.TimedOut >= last.TimedOut && q.TimedOut-last.TimedOut > rule.MaxTimedOut {
+				alert(QueueMetricTimedOut, q.TimedOut-last.TimedOut, rule.MaxTimedOut)
+			}
+		}
+	}
+	return alerts
+}
+
+// QueueMonitorConfig 积压监控配置
+type QueueMonitorConfig struct {
+	// Interval 采样间隔（默认 30s）
+	Interval time.Duration
+
+	// Rules 告警规则
+	Rules []QueueAlertRule
+
+	// OnSnapshot 每次采样成功后回调（可选），用于导出指标
+	OnSnapshot func(QueueSnapshot)
+
+	// OnAlert 告警回调（可选，默认写日志）；条件持续满足时每次采样都会触发
+	OnAlert func(QueueAlert)
+
+	// OnError 采样失败回调（可选，默认写日志）
+	OnError func(error)
+}
+
+// QueueMonitor 定期采样队列积压并评估告警规则
+type QueueMonitor struct {
+	reader QueueStatsReader
+	cfg    QueueMonitorConfig
+
+	mu   sync.RWMutex
+	last QueueSnapshot
+}
+
+// NewQueueMonitor 创建积压监控
+//
+// 使用示例：
+//
+//	monitor := messaging.NewQueueMonitor(nsq.NewStatsReader("localhost:4151"), messaging.QueueMonitorConfig{
+//	    Rules: []messaging.QueueAlertRule{
+//	        {Name: "backlog", MaxDepth: 10000},
+//	        {Name: "no-consumer", Topic: "order.paid", MinConsumers: 1},
+//	    },
+//	    OnAlert: func(a messaging.QueueAlert) { alerter.Fire(a.String()) },
+//	})
+//	go monitor.Run(ctx)
+func NewQueueMonitor(reader QueueStatsReader, cfg QueueMonitorConfig) *QueueMonitor {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 30 * time.Second
+	}
+	if cfg.OnAlert == nil {
+		cfg.OnAlert = func(a QueueAlert) {
+			log.Printf("[messaging] queue alert %s", a)
+		}
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = func(err error) {
+			log.Printf("[messaging] read queue stats failed: %v", err)
+		}
+	}
+	return &QueueMonitor{reader: reader, cfg: cfg}
+}
+
+// Poll 采样一次并评估告警规则（不触发回调）
+func (m *QueueMonitor) Poll(ctx context.Context) (QueueSnapshot, []QueueAlert, error) {
+	snapshot, err := m.reader.QueueSnapshot(ctx, time.Now())
+	if err != nil {
+		return QueueSnapshot{}, nil, err
+	}
+
+	m.mu.Lock()
+	previous := m.last
+	m.last = snapshot
+	m.mu.Unlock()
+
+	return snapshot, EvaluateQueueAlerts(m.cfg.Rules, previous, snapshot), nil
+}
+
+// Last 返回最近一次成功采样的快照（尚未采样时为零值）
+func (m *QueueMonitor) Last() QueueSnapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.last
+}
+
+// Run 立即采样一次，之后按 Interval 定期采样，直到 ctx 结束
+func (m *QueueMonitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.tick(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *QueueMonitor) tick(ctx context.Context) {
+	snapshot, alerts, err := m.Poll(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			m.cfg.OnError(err)
+		}
+		return
+	}
+	if m.cfg.OnSnapshot != nil {
+		m.cfg.OnSnapshot(snapshot)
+	}
+	for _, alert := range alerts {
+		m.cfg.OnAlert(alert)
+	}
+}
@@ -0,0 +1,142 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeStatsReader struct {
+	mu        sync.Mutex
+	snapshots []QueueSnapshot
+	err       error
+}
+
+func (r *fakeStatsReader) QueueSnapshot(ctx context.Context, now time.Time) (QueueSnapshot, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return QueueSnapshot{}, r.err
+	}
+	snapshot := r.snapshots[0]
+	if len(r.snapshots) > 1 {
+		r.snapshots = r.snapshots[1:]
+	}
+	snapshot.GeneratedAt = now
+	return snapshot, nil
+}
+
+func TestEvaluateQueueAlerts(t *testing.T) {
+	rules := []QueueAlertRule{
+		{Name: "backlog", MaxDepth: 100, MaxInFlight: 50},
+		{Name: "no-consumer", Topic: "order.paid", MinConsumers: 1},
+		{Name: "retry-storm", Channel: "billing", MaxRequeued: 10, MaxTimedOut: 5},
+	}
+	previous := QueueSnapshot{Queues: []QueueStats{
+		{Topic: "order.paid", Channel: "billing", Requeued: 100, TimedOut: 20},
+	}}
+	current := QueueSnapshot{Queues: []QueueStats{
+		{Topic: "order.paid", Channel: "fulfillment", Depth: 500, InFlight: 10, Consumers: 0},
+		{Topic: "order.paid", Channel: "billing", Depth: 10, Consumers: 2, Requeued: 150, TimedOut: 22},
+		{Topic: "user.created", Channel: "billing", Requeued: 999, Consumers: 0},
+	}}
+
+	var got []string
+	for _, alert := range EvaluateQueueAlerts(rules, previous, current) {
+		got = append(got, alert.String())
+	}
+	want := []string{
+		"backlog: order.paid/fulfillment depth=500 > 100",
+		"no-consumer: order.paid/fulfillment consumers=0 < 1",
+		"retry-storm: order.paid/billing requeued=50 > 10",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("alerts =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// 计数回退（broker 重启）不触发增量告警
+	restarted := QueueSnapshot{Queues: []QueueStats{{Topic: "order.paid", Channel: "billing", Requeued: 3, Consumers: 1}}}
+	if alerts := EvaluateQueueAlerts(rules, current, restarted); len(alerts) != 0 {
+		t.Fatalf("alerts after restart = %v", alerts)
+	}
+}
+
+func TestQueueMonitorRun(t *testing.T) {
+	reader := &fakeStatsReader{snapshots: []QueueSnapshot{
+		{Broker: "nsq", Queues: []QueueStats{{Topic: "order.paid", Channel: "billing", Depth: 1}}},
+		{Broker: "nsq", Queues: []QueueStats{{Topic: "order.paid", Channel: "billing", Depth: 1000}}},
+	}}
+
+	var mu sync.Mutex
+	var snapshots int
+	var alerts []QueueAlert
+	monitor := NewQueueMonitor(reader, QueueMonitorConfig{
+		Interval: 5 * time.Millisecond,
+		Rules:    []QueueAlertRule{{Name: "backlog", MaxDepth: 100}},
+		OnSnapshot: func(QueueSnapshot) {
+			mu.Lock()
+			snapshots++
+			mu.Unlock()
+		},
+		OnAlert: func(a QueueAlert) {
+			mu.Lock()
+			alerts = append(alerts, a)
+			mu.Unlock()
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- monitor.Run(ctx) }()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		n := len(alerts)
+		mu.Unlock()
+		if n > 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(alerts) == 0 || alerts[0].Value != 1000 || snapshots < 2 {
+		t.Fatalf("alerts = %v, snapshots = %d", alerts, snapshots)
+	}
+	if last := monitor.Last(); last.Broker != "nsq" || last.Queues[0].Depth != 1000 {
+		t.Fatalf("Last = %+v", last)
+	}
+}
+
+func TestQueueMonitorReportsReadErrors(t *testing.T) {
+	reader := &fakeStatsReader{err: errors.New("connection refused")}
+	errs := make(chan error, 1)
+	monitor := NewQueueMonitor(reader, QueueMonitorConfig{
+		Interval: time.Hour,
+		OnError:  func(err error) { errs <- err },
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go monitor.Run(ctx)
+	select {
+	case err := <-errs:
+		if !strings.Contains(err.Error(), "connection refused") {
+			t.Fatalf("error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("read error not reported")
+	}
+	if _, _, err := monitor.Poll(context.Background()); err == nil {
+		t.Fatalf("Poll should return the read error")
+	}
+}
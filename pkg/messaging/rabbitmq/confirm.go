@@ -0,0 +1,155 @@
+package rabbitmq
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+var (
+	// ErrPublishNacked broker 对发布的消息返回了 nack
+	ErrPublishNacked = errors.New("rabbitmq: publish nacked by broker")
+
+	// ErrMessageReturned mandatory 消息无法路由，被 broker 退回
+	ErrMessageReturned = errors.New("rabbitmq: message returned by broker")
+)
+
+// NackError broker nack 错误
+// 可通过 errors.Is(err, ErrPublishNacked) 或 errors.As 判断
+type NackError struct {
+	Exchange    string
+	MessageID   string
+	DeliveryTag uint64
+}
+
+// Error 实现 error 接口
+func (e *NackError) Error() string {
+	return fmt.Sprintf("rabbitmq: publish to exchange %s nacked by broker (message_id=%s, delivery_tag=%d)",
+		e.Exchange, e.MessageID, e.DeliveryTag)
+}
+
+// Is 支持 errors.Is(err, ErrPublishNacked)
+func (e *NackError) Is(target error) bool {
+	return target == ErrPublishNacked
+}
+
+// ReturnedMessage 被 broker 退回的消息
+type ReturnedMessage struct {
+	Exchange   string
+	RoutingKey string
+	MessageID  string
+	ReplyCode  uint16
+	ReplyText  string
+	Headers    map[string]interface{}
+	Body       []byte
+}
+
+// ReturnedError mandatory 消息被退回错误
+// 可通过 errors.Is(err, ErrMessageReturned) 或 errors.As 判断
+type ReturnedError struct {
+	Returned ReturnedMessage
+}
+
+// Error 实现 error 接口
+func (e *ReturnedError) Error() string {
+	return fmt.Sprintf("rabbitmq: message %s returned by broker from exchange %s: %d %s",
+		e.Returned.MessageID, e.Returned.Exchange, e.Returned.ReplyCode, e.Returned.ReplyText)
+}
+
+// Is 支持 errors.Is(err, ErrMessageReturned)
+func (e *ReturnedError) Is(target error) bool {
+	return target == ErrMessageReturned
+}
+
+// returnWatcher 监听 channel 上的 basic.return
+//
+// amqp091 在连接读取协程中按协议顺序先投递 basic.return 再处理 basic.ack，
+// 因此退回消息的记录与查询都在同一个协程中串行进行：
+// 发布方收到 ack 后再查询，一定能看到先于 ack 到达的退回记录。
+type returnWatcher struct {
+	track    bool
+	onReturn func(ReturnedMessage)
+	lookups  chan returnLookup
+	done     chan struct{}
+}
+
+type returnLookup struct {
+	messageID string
+	reply     chan *ReturnedMessage
+}
+
+// newReturnWatcher 在 channel 上注册 NotifyReturn 并启动监听协程
+// track: 是否记录退回消息供 take 查询（仅在启用 confirm 时需要，否则会无限增长）
+func newReturnWatcher(ch *amqp.Channel, track bool, onReturn func(ReturnedMessage)) *returnWatcher {
+	w := &returnWatcher{
+		track:    track,
+		onReturn: onReturn,
+		lookups:  make(chan returnLookup),
+		done:     make(chan struct{}),
+	}
+	go w.run(ch.NotifyReturn(make(chan amqp.Return)))
+	return w
+}
+
+func (w *returnWatcher) run(returns <-chan amqp.Return) {
+	defer close(w.done)
+
+	returned := make(map[string]ReturnedMessage)
+	for {
+		select {
+		case r, ok := <-returns:
+			if !ok {
+				return
+			}
+			msg := ReturnedMessage{
+				Exchange:   r.Exchange,
+				RoutingKey: r.RoutingKey,
+				MessageID:  r.MessageId,
+				ReplyCode:  r.ReplyCode,
+				ReplyText:  r.ReplyText,
+				Headers:    r.Headers,
+				Body:       r.Body,
+			}
+			if w.track && msg.MessageID != "" {
+				returned[msg.MessageID] = msg
+			}
+			if w.onReturn != nil {
+				go w.onReturn(msg)
+			}
+		case lookup := <-w.lookups:
+			if msg, ok := returned[lookup.messageID]; ok {
+				delete(returned, lookup.messageID)
+				lookup.reply <- &msg
+			} else {
+				lookup.reply <- nil
+			}
+		}
+	}
+}
+
+// take 查询并移除指定消息的退回记录
+func (w *returnWatcher) take(messageID string) (*ReturnedMessage, bool) {
+	if w == nil || messageID == "" {
+		return nil, false
+	}
+	reply := make(chan *ReturnedMessage, 1)
+	select {
+	case w.lookups <- returnLookup{messageID: messageID, reply: reply}:
+	case <-w.done:
+		return nil, false
+	}
+	msg := <-reply
+	return msg, msg != nil
+}
+
+// newMessageID 为 mandatory 消息生成 MessageId，用于关联退回记录
+func newMessageID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
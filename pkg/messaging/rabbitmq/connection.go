@@ -0,0 +1,298 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+var (
+	// ErrNotConnected 连接已断开且正在重连（或未开启自动重连）
+	ErrNotConnected = errors.New("rabbitmq: connection is not available")
+
+	// ErrReconnectFailed 超过 MaxReconnectAttempts 仍未重连成功
+	ErrReconnectFailed = errors.New("rabbitmq: reconnect attempts exhausted")
+)
+
+// ConnectionState 连接状态
+type ConnectionState string
+
+const (
+	// StateConnected 已连接
+	StateConnected ConnectionState = "connected"
+
+	// StateReconnecting 连接断开，正在重连
+	StateReconnecting ConnectionState = "reconnecting"
+
+	// StateFailed 连接断开且不再重连（未开启自动重连或重连次数耗尽）
+	StateFailed ConnectionState = "failed"
+
+	// StateClosed 已主动关闭
+	StateClosed ConnectionState = "closed"
+)
+
+// maxReconnectBackoff 重连退避的上限
+const maxReconnectBackoff = time.Minute
+
+// connection 带监督的 AMQP 连接
+//
+// 通过 NotifyClose 监听连接断开，按 ReconnectDelay 指数退避重连；
+// 重连成功后依次调用注册的 setup 回调，由发布者/订阅者重建 channel、
+// 重新声明 exchange/queue 并恢复消费。
+type connection struct {
+	url     string
+	options options
+
+	mu       sync.RWMutex
+	conn     *amqp.Connection
+	state    ConnectionState
+	lastErr  error
+	attempts int
+	ready    chan struct{} // 连接可用时关闭；断开时替换为新的 channel
+	setups   []func(*amqp.Connection) error
+
+	closeCh   chan struct{}
+	closeOnce sync.Once
+}
+
+// dialConnection 建立连接并启动监督协程
+// 未开启自动重连时，连接断开后状态变为 StateFailed
+func dialConnection(url string, o options) (*connection, error) {
+	c := &connection{
+		url:     url,
+		options: o,
+		ready:   make(chan struct{}),
+		closeCh: make(chan struct{}),
+	}
+
+	conn, err := c.dial()
+	if err != nil {
+		return nil, fmt.Errorf("连接 RabbitMQ 失败: %w", err)
+	}
+	c.conn = conn
+	c.state = StateConnected
+	close(c.ready)
+
+	go c.supervise(conn)
+
+	return c, nil
+}
+
+func (c *connection) dial() (*amqp.Connection, error) {
+	cfg := amqp.Config{
+		Heartbeat: c.options.heartbeat,
+		Locale:    "en_US",
+	}
+	if c.options.connectionTimeout > 0 {
+		cfg.Dial = amqp.DefaultDial(c.options.connectionTimeout)
+	}
+	return amqp.DialConfig(c.url, cfg)
+}
+
+// onReconnect 注册重连成功后的回调
+func (c *connection) onReconnect(setup func(*amqp.Connection) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setups = append(c.setups, setup)
+}
+
+// current 返回当前可用的底层连接
+func (c *connection) current() (*amqp.Connection, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.state != StateConnected || c.conn == nil || c.conn.IsClosed() {
+		return nil, c.unavailableLocked()
+	}
+	return c.conn, nil
+}
+
+// waitReady 等待连接恢复可用
+// 连接已关闭或不再重连时立即返回错误
+func (c *connection) waitReady(ctx context.Context) error {
+	for {
+		c.mu.RLock()
+		state, ready := c.state, c.ready
+		err := c.unavailableLocked()
+		c.mu.RUnlock()
+
+		switch state {
+		case StateConnected:
+			return nil
+		case StateFailed, StateClosed:
+			return err
+		}
+
+		select {
+		case <-ready:
+		case <-c.closeCh:
+			return ErrNotConnected
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %v", ErrNotConnected, ctx.Err())
+		}
+	}
+}
+
+// health 返回连接健康状态
+func (c *connection) health() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.state == StateConnected && c.conn != nil && !c.conn.IsClosed() {
+		return nil
+	}
+	return c.unavailableLocked()
+}
+
+// currentState 返回当前连接状态
+func (c *connection) currentState() ConnectionState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.state
+}
+
+func (c *connection) unavailableLocked() error {
+	switch c.state {
+	case StateReconnecting:
+		return fmt.Errorf("%w: reconnecting (attempt %d): %v", ErrNotConnected, c.attempts, c.lastErr)
+	case StateFailed:
+		if c.options.autoReconnect {
+			return fmt.Errorf("%w: %v", ErrReconnectFailed, c.lastErr)
+		}
+		return fmt.Errorf("%w: %v", ErrNotConnected, c.lastErr)
+	case StateClosed:
+		return fmt.Errorf("%w: closed", ErrNotConnected)
+	}
+	return ErrNotConnected
+}
+
+// supervise 监听连接断开并重连
+func (c *connection) supervise(conn *amqp.Connection) {
+	for {
+		closeErr, ok := <-conn.NotifyClose(make(chan *amqp.Error, 1))
+
+		// 主动调用 close 时先关闭 closeCh，不触发重连
+		select {
+		case <-c.closeCh:
+			return
+		default:
+		}
+
+		// 注册时连接已断开，通道会被直接关闭
+		var lost error = amqp.ErrClosed
+		if ok && closeErr != nil {
+			lost = closeErr
+		}
+
+		log.Printf("[rabbitmq] connection lost: %v", lost)
+		c.mu.Lock()
+		c.lastErr = lost
+		c.ready = make(chan struct{})
+		if !c.options.autoReconnect {
+			c.state = StateFailed
+			c.mu.Unlock()
+			return
+		}
+		c.state = StateReconnecting
+		c.mu.Unlock()
+
+		next, err := c.reconnect()
+		if err != nil {
+			return
+		}
+		conn = next
+	}
+}
+
+// reconnect 按指数退避重连，直到成功、次数耗尽或连接被关闭
+func (c *connection) reconnect() (*amqp.Connection, error) {
+	delay := c.options.reconnectDelay
+	for attempt := 1; ; attempt++ {
+		c.mu.Lock()
+		c.attempts = attempt
+		c.mu.Unlock()
+
+		select {
+		case <-c.closeCh:
+			return nil, ErrNotConnected
+		case <-time.After(delay):
+		}
+
+		conn, err := c.dial()
+		if err == nil {
+			if err = c.restore(conn); err == nil {
+				log.Printf("[rabbitmq] reconnected after %d attempt(s)", attempt)
+				return conn, nil
+			}
+			conn.Close()
+		}
+
+		log.Printf("[rabbitmq] reconnect attempt %d failed: %v", attempt, err)
+		c.mu.Lock()
+		c.lastErr = err
+		if c.options.maxReconnectAttempts > 0 && attempt >= c.options.maxReconnectAttempts {
+			c.state = StateFailed
+			c.mu.Unlock()
+			return nil, ErrReconnectFailed
+		}
+		c.mu.Unlock()
+
+		delay *= 2
+		if limit := maxDuration(c.options.reconnectDelay, maxReconnectBackoff); delay > limit {
+			delay = limit
+		}
+	}
+}
+
+// restore 在新连接上执行所有 setup 回调，并切换为已连接状态
+func (c *connection) restore(conn *amqp.Connection) error {
+	c.mu.RLock()
+	setups := append([]func(*amqp.Connection) error(nil), c.setups...)
+	c.mu.RUnlock()
+
+	for _, setup := range setups {
+		if err := setup(conn); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.closeCh:
+		return ErrNotConnected
+	default:
+	}
+	c.conn = conn
+	c.state = StateConnected
+	c.lastErr = nil
+	c.attempts = 0
+	close(c.ready)
+	return nil
+}
+
+// close 主动关闭连接，停止重连
+func (c *connection) close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.closeCh)
+		c.mu.Lock()
+		conn := c.conn
+		c.state = StateClosed
+		c.mu.Unlock()
+		if conn != nil && !conn.IsClosed() {
+			err = conn.Close()
+		}
+	})
+	return err
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
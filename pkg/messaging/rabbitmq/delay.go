@@ -0,0 +1,136 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/FangcunMount/component-base/pkg/messaging"
+)
+
+// DelayMode 延迟发布的实现方式
+type DelayMode string
+
+const (
+	// DelayTTL 按延迟时长声明 TTL 队列（<topic>.delay.<秒数>s）及同名的 fanout exchange，
+	// 消息到期后经死信按原路由键转发回原 exchange，不依赖任何插件。
+	// 每个 topic 的每个延迟时长对应一个队列（与路由键无关），延迟会向上取整到秒，建议使用有限的几档延迟。
+	DelayTTL DelayMode = "ttl"
+
+	// DelayPlugin 使用 rabbitmq_delayed_message_exchange 插件：
+	// 消息发往 <topic>.delayed（x-delayed-message 类型），到期后转发到原 exchange。
+	// 需要在 broker 上启用插件，否则声明 exchange 时会失败。
+	DelayPlugin DelayMode = "plugin"
+)
+
+// delayedExchangeSuffix 插件模式下延迟 exchange 的后缀
+const delayedExchangeSuffix = ".delayed"
+
+// WithDelayMode 设置延迟发布的实现方式（默认 DelayTTL）
+func WithDelayMode(mode DelayMode) Option {
+	return func(o *options) {
+		if mode != "" {
+			o.delayMode = mode
+		}
+	}
+}
+
+// PublishDelayed 实现 messaging.DelayedPublisher 接口
+// delay <= 0 时立即发布；路由键与 PublishMessage 相同，到期后按原路由键投递
+func (p *publisher) PublishDelayed(ctx context.Context, topic string, msg *messaging.Message, delay time.Duration) error {
+	if delay <= 0 {
+		return p.PublishMessage(ctx, topic, msg)
+	}
+
+	msg = messaging.InjectTraceContext(ctx, msg)
+	routingKey := p.options.routingKey(topic, msg)
+	publishing := newPublishing(msg)
+
+	if p.options.delayMode == DelayPlugin {
+		publishing.Headers["x-delay"] = delay.Milliseconds()
+		return p.publish(ctx, topic+delayedExchangeSuffix, routingKey, publishing,
+			p.exchangeDeclaration(topic), p.delayedExchangeDeclaration(topic))
+	}
+
+	ttl := delay.Truncate(time.Second)
+	if ttl < delay {
+		ttl += time.Second
+	}
+	// 经 fanout exchange 投递到 TTL 队列，消息保留原路由键，到期后死信时按原路由键路由
+	name := delayQueueName(topic, ttl)
+	return p.publish(ctx, name, routingKey, publishing,
+		p.exchangeDeclaration(topic), p.delayQueueDeclaration(name, topic, ttl))
+}
+
+// delayQueueName TTL 队列及其 fanout exchange 的名称
+func delayQueueName(topic string, ttl time.Duration) string {
+	return fmt.Sprintf("%s.delay.%ds", topic, int64(ttl/time.Second))
+}
+
+// delayQueueArgs TTL 队列参数
+// 不设置 x-dead-letter-routing-key：死信时沿用消息发布时的路由键，同一队列可承载所有路由键。
+// 不设置 x-expires：发布不算队列的“使用”，队列会在声明后固定时间被删除（连同未到期的消息），
+// 而发布者缓存了声明结果，之后的延迟消息会因无法路由被静默丢弃
+func delayQueueArgs(topic string, ttl time.Duration) amqp.Table {
+	return amqp.Table{
+		amqp.QueueMessageTTLArg:  ttl.Milliseconds(),
+		"x-dead-letter-exchange": topic,
+	}
+}
+
+// delayQueueDeclaration TTL 队列及同名的 fanout exchange：无消费者，消息到期后死信到原 exchange
+// 通过 fanout exchange 而不是默认 exchange 投递，路由键不受队列名约束
+func (p *publisher) delayQueueDeclaration(name, topic string, ttl time.Duration) declaration {
+	return declaration{
+		key: "delay:" + name,
+		declare: func(ch *amqp.Channel) error {
+			if err := declareExchange(ch, name, amqp.ExchangeFanout); err != nil {
+				return err
+			}
+			_, err := ch.QueueDeclare(
+				name,  // name
+				true,  // durable
+				false, // delete when unused
+				false, // exclusive
+				false, // no-wait
+				delayQueueArgs(topic, ttl),
+			)
+			if err != nil {
+				return fmt.Errorf("声明延迟队列 %s 失败: %w", name, err)
+			}
+			if err := ch.QueueBind(name, "", name, false, nil); err != nil {
+				return fmt.Errorf("绑定延迟队列 %s 失败: %w", name, err)
+			}
+			return nil
+		},
+	}
+}
+
+// delayedExchangeDeclaration 插件模式的延迟 exchange，并绑定到原 exchange
+// 延迟 exchange 使用 fanout 转发，消息保留原路由键，由原 exchange 完成路由
+func (p *publisher) delayedExchangeDeclaration(topic string) declaration {
+	name := topic + delayedExchangeSuffix
+	return declaration{
+		key: "exchange:" + name,
+		declare: func(ch *amqp.Channel) error {
+			err := ch.ExchangeDeclare(
+				name,                // name
+				"x-delayed-message", // type
+				true,                // durable
+				false,               // auto-deleted
+				false,               // internal
+				false,               // no-wait
+				amqp.Table{"x-delayed-type": amqp.ExchangeFanout},
+			)
+			if err != nil {
+				return fmt.Errorf("声明延迟 exchange %s 失败（需启用 rabbitmq_delayed_message_exchange 插件）: %w", name, err)
+			}
+			if err := ch.ExchangeBind(topic, "", name, false, nil); err != nil {
+				return fmt.Errorf("绑定延迟 exchange %s 到 %s 失败: %w", name, topic, err)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,55 @@
+package rabbitmq
+
+import (
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/FangcunMount/component-base/pkg/messaging"
+)
+
+func TestDelayQueueArgsDeadLetterToTopicWithoutExpiry(t *testing.T) {
+	args := delayQueueArgs("order.created", 30*time.Second)
+
+	if got := args[amqp.QueueMessageTTLArg]; got != int64(30000) {
+		t.Fatalf("%s = %v, want 30000", amqp.QueueMessageTTLArg, got)
+	}
+	if got := args["x-dead-letter-exchange"]; got != "order.created" {
+		t.Fatalf("x-dead-letter-exchange = %v, want order.created", got)
+	}
+	// 死信沿用消息自身的路由键，队列与路由键无关
+	if _, ok := args["x-dead-letter-routing-key"]; ok {
+		t.Fatal("delay queue must not override the routing key")
+	}
+	// 发布者缓存声明结果，队列不能自动过期
+	if _, ok := args[amqp.QueueTTLArg]; ok {
+		t.Fatalf("delay queue must not set %s", amqp.QueueTTLArg)
+	}
+	if err := args.Validate(); err != nil {
+		t.Fatalf("args.Validate() error = %v", err)
+	}
+}
+
+func TestDelayQueueName(t *testing.T) {
+	if got := delayQueueName("order.created", 5*time.Second); got != "order.created.delay.5s" {
+		t.Fatalf("delayQueueName() = %q", got)
+	}
+	if got := delayQueueName("order.created", time.Minute); got != "order.created.delay.60s" {
+		t.Fatalf("delayQueueName() = %q", got)
+	}
+}
+
+func TestOptionsFromConfigAppliesDelayMode(t *testing.T) {
+	cfg := messaging.DefaultConfig().RabbitMQ
+	cfg.DelayMode = string(DelayPlugin)
+
+	o := newOptions(optionsFromConfig(cfg)...)
+	if o.delayMode != DelayPlugin {
+		t.Fatalf("delayMode = %q, want %q", o.delayMode, DelayPlugin)
+	}
+
+	if o := newOptions(optionsFromConfig(messaging.RabbitMQConfig{})...); o.delayMode != DelayTTL {
+		t.Fatalf("delayMode = %q, want default %q", o.delayMode, DelayTTL)
+	}
+}
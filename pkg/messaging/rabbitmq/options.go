@@ -0,0 +1,253 @@
+package rabbitmq
+
+import (
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/FangcunMount/component-base/pkg/messaging"
+)
+
+// ConfirmMode publisher confirm 模式
+type ConfirmMode string
+
+const (
+	// ConfirmNone 不启用 publisher confirm（发出即成功）
+	ConfirmNone ConfirmMode = "none"
+
+	// ConfirmSync 每次发布都同步等待 broker 的 ack/nack
+	ConfirmSync ConfirmMode = "sync"
+
+	// ConfirmBatch 发布后立即返回，累计到批次大小或调用 Flush 时统一等待确认
+	ConfirmBatch ConfirmMode = "batch"
+)
+
+// OutagePolicy 连接中断期间的发布策略
+type OutagePolicy string
+
+const (
+	// OutageFail 连接中断时发布立即失败（返回 ErrNotConnected）
+	OutageFail OutagePolicy = "fail"
+
+	// OutageBuffer 连接中断时发布挂起等待重连，重连后再真正发布并返回结果；
+	// 同时挂起的发布数量受 OutageBufferSize 限制，超出时立即失败，等待时间受调用方 ctx 控制
+	OutageBuffer OutagePolicy = "buffer"
+)
+
+const (
+	// defaultConfirmBatchSize 批量确认模式下的默认批次大小
+	defaultConfirmBatchSize = 100
+
+	// defaultOutageBufferSize 连接中断期间默认最多挂起的发布数量
+	defaultOutageBufferSize = 1000
+
+	// defaultPrefetchCount 默认预取数量
+	defaultPrefetchCount = 200
+)
+
+// Option RabbitMQ 发布者/订阅者选项
+type Option func(*options)
+
+type options struct {
+	confirmMode      ConfirmMode
+	confirmTimeout   time.Duration
+	confirmBatchSize int
+	mandatory        bool
+	onReturn         func(ReturnedMessage)
+
+	autoReconnect        bool
+	reconnectDelay       time.Duration
+	maxReconnectAttempts int
+	heartbeat            time.Duration
+	connectionTimeout    time.Duration
+	outagePolicy         OutagePolicy
+	outageBufferSize     int
+
+	prefetchCount int
+	prefetchSize  int
+
+	exchangeType   string
+	routingKeyFunc func(topic string, msg *messaging.Message) string
+	delayMode      DelayMode
+}
+
+func newOptions(opts ...Option) options {
+	o := options{
+		confirmMode:      ConfirmNone,
+		confirmBatchSize: defaultConfirmBatchSize,
+		autoReconnect:    true,
+		reconnectDelay:   5 * time.Second,
+		outagePolicy:     OutageBuffer,
+		outageBufferSize: defaultOutageBufferSize,
+		prefetchCount:    defaultPrefetchCount,
+		exchangeType:     amqp.ExchangeFanout,
+		delayMode:        DelayTTL,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	return o
+}
+
+// WithConfirmMode 设置 publisher confirm 模式
+func WithConfirmMode(mode ConfirmMode) Option {
+	return func(o *options) {
+		if mode != "" {
+			o.confirmMode = mode
+		}
+	}
+}
+
+// WithConfirmTimeout 设置等待 confirm 的超时时间（0 表示只受调用方 ctx 控制）
+func WithConfirmTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.confirmTimeout = timeout
+	}
+}
+
+// WithConfirmBatchSize 设置批量确认模式下的批次大小
+func WithConfirmBatchSize(size int) Option {
+	return func(o *options) {
+		if size > 0 {
+			o.confirmBatchSize = size
+		}
+	}
+}
+
+// WithMandatory 以 mandatory 方式发布，无法路由到任何队列的消息会被 broker 退回
+// onReturn: 退回消息回调（可为 nil），在独立 goroutine 中执行
+// 启用 confirm 时，被退回的消息还会以 *ReturnedError 的形式返回给发布方
+func WithMandatory(onReturn func(ReturnedMessage)) Option {
+	return func(o *options) {
+		o.mandatory = true
+		o.onReturn = onReturn
+	}
+}
+
+// WithAutoReconnect 配置自动重连
+// delay: 首次重连延迟（之后指数退避，最长 1 分钟）
+// maxAttempts: 最大重连次数（0 表示无限重试）
+func WithAutoReconnect(enabled bool, delay time.Duration, maxAttempts int) Option {
+	return func(o *options) {
+		o.autoReconnect = enabled
+		if delay > 0 {
+			o.reconnectDelay = delay
+		}
+		o.maxReconnectAttempts = maxAttempts
+	}
+}
+
+// WithHeartbeat 设置 AMQP 心跳间隔（0 表示使用默认值）
+func WithHeartbeat(interval time.Duration) Option {
+	return func(o *options) {
+		o.heartbeat = interval
+	}
+}
+
+// WithConnectionTimeout 设置建立 TCP 连接的超时时间
+func WithConnectionTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.connectionTimeout = timeout
+	}
+}
+
+// WithOutagePolicy 设置连接中断期间的发布策略
+// bufferSize: OutageBuffer 策略下最多同时挂起的发布数量
+func WithOutagePolicy(policy OutagePolicy, bufferSize int) Option {
+	return func(o *options) {
+		if policy != "" {
+			o.outagePolicy = policy
+		}
+		if bufferSize > 0 {
+			o.outageBufferSize = bufferSize
+		}
+	}
+}
+
+// WithQoS 设置消费者预取数量与大小
+func WithQoS(prefetchCount, prefetchSize int) Option {
+	return func(o *options) {
+		if prefetchCount > 0 {
+			o.prefetchCount = prefetchCount
+		}
+		o.prefetchSize = prefetchSize
+	}
+}
+
+// WithExchangeType 设置 exchange 类型：fanout（默认）、direct、topic、headers
+// 发布者与订阅者需使用相同的类型，否则声明 exchange 时 broker 会拒绝
+func WithExchangeType(kind string) Option {
+	return func(o *options) {
+		if kind != "" {
+			o.exchangeType = kind
+		}
+	}
+}
+
+// WithRoutingKeyFunc 设置发布时的路由键计算函数
+// 消息 Metadata 中的 messaging.MetadataRoutingKey 优先；未设置时调用 fn，
+// 仍为空则使用空路由键
+func WithRoutingKeyFunc(fn func(topic string, msg *messaging.Message) string) Option {
+	return func(o *options) {
+		o.routingKeyFunc = fn
+	}
+}
+
+// validate 校验选项
+func (o options) validate() error {
+	switch o.exchangeType {
+	case amqp.ExchangeFanout, amqp.ExchangeDirect, amqp.ExchangeTopic, amqp.ExchangeHeaders:
+	default:
+		return fmt.Errorf("rabbitmq: unsupported exchange type %q", o.exchangeType)
+	}
+	switch o.delayMode {
+	case DelayTTL, DelayPlugin:
+	default:
+		return fmt.Errorf("rabbitmq: unsupported delay mode %q", o.delayMode)
+	}
+	switch o.confirmMode {
+	case ConfirmNone, ConfirmSync, ConfirmBatch:
+	default:
+		return fmt.Errorf("rabbitmq: unsupported confirm mode %q", o.confirmMode)
+	}
+	switch o.outagePolicy {
+	case OutageFail, OutageBuffer:
+	default:
+		return fmt.Errorf("rabbitmq: unsupported outage policy %q", o.outagePolicy)
+	}
+	return nil
+}
+
+// routingKey 计算消息的路由键
+func (o options) routingKey(topic string, msg *messaging.Message) string {
+	if key := msg.Metadata[messaging.MetadataRoutingKey]; key != "" {
+		return key
+	}
+	if o.routingKeyFunc != nil {
+		return o.routingKeyFunc(topic, msg)
+	}
+	return ""
+}
+
+// optionsFromConfig 将统一配置转换为选项
+func optionsFromConfig(cfg messaging.RabbitMQConfig) []Option {
+	opts := []Option{
+		WithConfirmMode(ConfirmMode(cfg.ConfirmMode)),
+		WithConfirmTimeout(cfg.ConfirmTimeout),
+		WithConfirmBatchSize(cfg.ConfirmBatchSize),
+		WithAutoReconnect(cfg.AutoReconnect, cfg.ReconnectDelay, cfg.MaxReconnectAttempts),
+		WithHeartbeat(cfg.HeartbeatInterval),
+		WithConnectionTimeout(cfg.ConnectionTimeout),
+		WithOutagePolicy(OutagePolicy(cfg.OutagePolicy), cfg.OutageBufferSize),
+		WithQoS(cfg.PrefetchCount, cfg.PrefetchSize),
+		WithExchangeType(cfg.ExchangeType),
+		WithDelayMode(DelayMode(cfg.DelayMode)),
+	}
+	if cfg.Mandatory {
+		opts = append(opts, WithMandatory(nil))
+	}
+	return opts
+}
@@ -0,0 +1,79 @@
+package rabbitmq
+
+import (
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/FangcunMount/component-base/pkg/messaging"
+)
+
+func TestOptionsFromConfigAppliesExchangeType(t *testing.T) {
+	cfg := messaging.DefaultConfig().RabbitMQ
+	cfg.ExchangeType = amqp.ExchangeTopic
+
+	o := newOptions(optionsFromConfig(cfg)...)
+	if o.exchangeType != amqp.ExchangeTopic {
+		t.Fatalf("exchangeType = %q, want %q", o.exchangeType, amqp.ExchangeTopic)
+	}
+	if err := o.validate(); err != nil {
+		t.Fatalf("validate() error = %v", err)
+	}
+}
+
+func TestOptionsFromConfigKeepsDefaultExchangeTypeWhenUnset(t *testing.T) {
+	o := newOptions(optionsFromConfig(messaging.RabbitMQConfig{})...)
+	if o.exchangeType != amqp.ExchangeFanout {
+		t.Fatalf("exchangeType = %q, want %q", o.exchangeType, amqp.ExchangeFanout)
+	}
+}
+
+func TestOptionsFromConfigRejectsUnknownExchangeType(t *testing.T) {
+	cfg := messaging.DefaultConfig().RabbitMQ
+	cfg.ExchangeType = "broadcast"
+
+	if err := newOptions(optionsFromConfig(cfg)...).validate(); err == nil {
+		t.Fatal("validate() should reject an unsupported exchange type")
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr bool
+	}{
+		{name: "defaults"},
+		{name: "confirm none", opts: []Option{WithConfirmMode(ConfirmNone)}},
+		{name: "confirm sync", opts: []Option{WithConfirmMode(ConfirmSync)}},
+		{name: "confirm batch", opts: []Option{WithConfirmMode(ConfirmBatch)}},
+		{name: "unknown confirm mode", opts: []Option{WithConfirmMode("synch")}, wantErr: true},
+		{name: "outage fail", opts: []Option{WithOutagePolicy(OutageFail, 0)}},
+		{name: "outage buffer", opts: []Option{WithOutagePolicy(OutageBuffer, 10)}},
+		{name: "unknown outage policy", opts: []Option{WithOutagePolicy("drop", 0)}, wantErr: true},
+		{name: "unknown exchange type", opts: []Option{WithExchangeType("broadcast")}, wantErr: true},
+		{name: "unknown delay mode", opts: []Option{WithDelayMode("cron")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newOptions(tt.opts...).validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOptionsFromConfigRejectsUnknownConfirmModeAndOutagePolicy(t *testing.T) {
+	cfg := messaging.DefaultConfig().RabbitMQ
+	cfg.ConfirmMode = "Sync"
+	if err := newOptions(optionsFromConfig(cfg)...).validate(); err == nil {
+		t.Fatal("validate() should reject an unsupported confirm mode")
+	}
+
+	cfg = messaging.DefaultConfig().RabbitMQ
+	cfg.OutagePolicy = "queue"
+	if err := newOptions(optionsFromConfig(cfg)...).validate(); err == nil {
+		t.Fatal("validate() should reject an unsupported outage policy")
+	}
+}
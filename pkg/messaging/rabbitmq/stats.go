@@ -0,0 +1,155 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/FangcunMount/component-base/pkg/messaging"
+)
+
+// StatsConfig 管理 API 统计读取配置
+type StatsConfig struct {
+	// URL 管理 API 地址，例如 http://localhost:15672
+	URL string
+
+	// Username / Password 管理 API 的 Basic Auth 凭据（需要 monitoring 标签）
+	Username string
+	Password string
+
+	// VHost 虚拟主机（默认 /）
+	VHost string
+
+	// HTTPClient HTTP 客户端（nil 时使用 5s 超时的默认客户端），测试时可注入 httptest 的客户端
+	HTTPClient *http.Client
+
+	// Topics 只统计绑定到这些 exchange 的队列（为空时统计全部）
+	Topics []string
+}
+
+// StatsReader 读取 RabbitMQ 管理 API 的队列统计，实现 messaging.QueueStatsReader
+//
+// topic 对应 exchange、channel 对应队列：通过绑定关系确定队列所属的 exchange，
+// 绑定到多个 exchange 的队列在每个 exchange 下各输出一条（计数相同）；
+// 没有绑定到任何 exchange 的队列（如延迟队列）不输出。
+type StatsReader struct {
+	cfg    StatsConfig
+	topics map[string]bool
+}
+
+// NewStatsReader 创建管理 API 统计读取器
+func NewStatsReader(cfg StatsConfig) *StatsReader {
+	cfg.URL = strings.TrimRight(cfg.URL, "/")
+	if cfg.VHost == "" {
+		cfg.VHost = "/"
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 5 * time.Second}
+	}
+	r := &StatsReader{cfg: cfg}
+	if len(cfg.Topics) > 0 {
+		r.topics = make(map[string]bool, len(cfg.Topics))
+		for _, topic := range cfg.Topics {
+			r.topics[topic] = true
+		}
+	}
+	return r
+}
+
+type queueInfo struct {
+	Name                   string `json:"name"`
+	MessagesReady          int64  `json:"messages_ready"`
+	MessagesUnacknowledged int64  `json:"messages_unacknowledged"`
+	Consumers              int    `json:"consumers"`
+	MessageStats           struct {
+		Redeliver int64 `json:"redeliver"`
+	} `json:"message_stats"`
+}
+
+type bindingInfo struct {
+	Source          string `json:"source"`
+	Destination     string `json:"destination"`
+	DestinationType string `json:"destination_type"`
+}
+
+// QueueSnapshot 实现 messaging.QueueStatsReader 接口
+func (r *StatsReader) QueueSnapshot(ctx context.Context, now time.Time) (messaging.QueueSnapshot, error) {
+	vhost := url.PathEscape(r.cfg.VHost)
+
+	var queues []queueInfo
+	if err := r.get(ctx, "/api/queues/"+vhost+
+		"?columns=name,messages_ready,messages_unacknowledged,consumers,message_stats.redeliver", &queues); err != nil {
+		return messaging.QueueSnapshot{}, err
+	}
+	var bindings []bindingInfo
+	if err := r.get(ctx, "/api/bindings/"+vhost, &bindings); err != nil {
+		return messaging.QueueSnapshot{}, err
+	}
+
+	// 队列 → 绑定的 exchange（忽略默认 exchange）
+	exchanges := make(map[string][]string)
+	seen := make(map[[2]string]bool)
+	for _, b := range bindings {
+		key := [2]string{b.Destination, b.Source}
+		if b.DestinationType != "queue" || b.Source == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		exchanges[b.Destination] = append(exchanges[b.Destination], b.Source)
+	}
+
+	snapshot := messaging.QueueSnapshot{Broker: "rabbitmq", GeneratedAt: now}
+	for _, q := range queues {
+		sources := exchanges[q.Name]
+		sort.Strings(sources)
+		for _, topic := range sources {
+			if r.topics != nil && !r.topics[topic] {
+				continue
+			}
+			snapshot.Queues = append(snapshot.Queues, messaging.QueueStats{
+				Topic:     topic,
+				Channel:   q.Name,
+				Depth:     q.MessagesReady,
+				InFlight:  q.MessagesUnacknowledged,
+				Requeued:  q.MessageStats.Redeliver,
+				Consumers: q.Consumers,
+			})
+		}
+	}
+	sort.Slice(snapshot.Queues, func(i, j int) bool {
+		a, b := snapshot.Queues[i], snapshot.Queues[j]
+		if a.Topic != b.Topic {
+			return a.Topic < b.Topic
+		}
+		return a.Channel < b.Channel
+	})
+	return snapshot, nil
+}
+
+func (r *StatsReader) get(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.cfg.URL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(r.cfg.Username, r.cfg.Password)
+	resp, err := r.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求管理 API %s 失败: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("请求管理 API %s 失败: status=%d, body=%s", path, resp.StatusCode, string(body))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("解析管理 API %s 响应失败: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package rabbitmq
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/FangcunMount/component-base/pkg/messaging"
+)
+
+func TestStatsReaderQueueSnapshot(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "monitor" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.EscapedPath() {
+		case "/api/queues/%2F":
+			w.Write([]byte(`[
+			  {"name": "billing", "messages_ready": 120, "messages_unacknowledged": 8, "consumers": 2,
+			   "message_stats": {"redeliver": 15}},
+			  {"name": "audit", "messages_ready": 1, "messages_unacknowledged": 0, "consumers": 0},
+			  {"name": "order.paid.delay.5000", "messages_ready": 9, "consumers": 0}
+			]`))
+		case "/api/bindings/%2F":
+			w.Write([]byte(`[
+			  {"source": "", "destination": "billing", "destination_type": "queue"},
+			  {"source": "order.paid", "destination": "billing", "destination_type": "queue", "routing_key": ""},
+			  {"source": "order.paid", "destination": "audit", "destination_type": "queue"},
+			  {"source": "user.created", "destination": "audit", "destination_type": "queue"},
+			  {"source": "order.paid.delayed", "destination": "order.paid", "destination_type": "exchange"}
+			]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	reader := NewStatsReader(StatsConfig{
+		URL:        server.URL + "/",
+		Username:   "monitor",
+		Password:   "secret",
+		HTTPClient: server.Client(),
+	})
+	snapshot, err := reader.QueueSnapshot(context.Background(), time.Now())
+	if err != nil {
+		t.Fatalf("QueueSnapshot: %v", err)
+	}
+
+	want := []messaging.QueueStats{
+		{Topic: "order.paid", Channel: "audit", Depth: 1},
+		{Topic: "order.paid", Channel: "billing", Depth: 120, InFlight: 8, Requeued: 15, Consumers: 2},
+		{Topic: "user.created", Channel: "audit", Depth: 1},
+	}
+	if snapshot.Broker != "rabbitmq" || len(snapshot.Queues) != len(want) {
+		t.Fatalf("snapshot = %+v", snapshot)
+	}
+	for i := range want {
+		if snapshot.Queues[i] != want[i] {
+			t.Fatalf("queue %d = %+v, want %+v", i, snapshot.Queues[i], want[i])
+		}
+	}
+
+	filtered := NewStatsReader(StatsConfig{URL: server.URL, Username: "monitor", Password: "secret",
+		HTTPClient: server.Client(), Topics: []string{"user.created"}})
+	if snapshot, err := filtered.QueueSnapshot(context.Background(), time.Now()); err != nil || len(snapshot.Queues) != 1 {
+		t.Fatalf("filtered snapshot = %+v, %v", snapshot, err)
+	}
+
+	unauthorized := NewStatsReader(StatsConfig{URL: server.URL, HTTPClient: server.Client()})
+	if _, err := unauthorized.QueueSnapshot(context.Background(), time.Now()); err == nil {
+		t.Fatalf("unauthorized request should fail")
+	}
+}
@@ -0,0 +1,67 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/FangcunMount/component-base/pkg/messaging"
+	"github.com/FangcunMount/component-base/pkg/messaging/messagingtest"
+)
+
+func TestDeadLetterAfterRedeliveriesOfMessageWithoutID(t *testing.T) {
+	publishing := newPublishing(messaging.NewMessage("", []byte("poison")))
+	if publishing.MessageId == "" {
+		t.Fatalf("publishing without uuid should get a MessageId")
+	}
+
+	dlq := messagingtest.NewPublisher()
+	handler := messaging.DeadLetterMiddleware(messaging.DeadLetterPolicy{MaxAttempts: 4, Publisher: dlq})(
+		func(ctx context.Context, msg *messaging.Message) error { return errors.New("boom") },
+	)
+
+	// 经典队列：每次重投的 DeliveryTag 都不同，Attempts 最多为 2
+	for tag := uint64(1); tag <= 4; tag++ {
+		d := amqp.Delivery{
+			MessageId:   publishing.MessageId,
+			DeliveryTag: tag,
+			Redelivered: tag > 1,
+			Body:        publishing.Body,
+		}
+		err := handler(context.Background(), newDomainMessage("orders", "billing", d))
+		if tag < 4 && err == nil {
+			t.Fatalf("delivery %d should be requeued", tag)
+		}
+		if tag == 4 && err != nil {
+			t.Fatalf("delivery %d error = %v, want dead lettered", tag, err)
+		}
+	}
+
+	dead := dlq.AssertPublished(t, "orders.dlq")
+	messagingtest.AssertMetadata(t, dead, messaging.MetadataDLQAttempts, "4")
+	if dead.UUID != publishing.MessageId {
+		t.Fatalf("dead letter uuid = %q, want %q", dead.UUID, publishing.MessageId)
+	}
+}
+
+func TestRetryConsumerSkipsRemovedAndPausedConsumers(t *testing.T) {
+	// conn 为 nil：重试若未提前放弃会在访问连接时 panic
+	s := &subscriber{consumers: make(map[string]*consumer), stopCh: make(chan struct{})}
+
+	removed := &consumer{topic: "orders", channel: "billing", failed: true}
+	s.retryConsumer(removed)
+
+	paused := &consumer{topic: "orders", channel: "audit", failed: true, paused: true}
+	s.consumers["orders:audit"] = paused
+	s.retryConsumer(paused)
+
+	// 共享 channel 不可用时交给重连后的 setup 恢复
+	pending := &consumer{topic: "orders", channel: "search", failed: true}
+	s.consumers["orders:search"] = pending
+	s.retryConsumer(pending)
+	if !pending.failed {
+		t.Fatalf("consumer should stay failed until the channel is available")
+	}
+}
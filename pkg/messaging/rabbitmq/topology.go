@@ -0,0 +1,174 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/FangcunMount/component-base/pkg/messaging"
+)
+
+// declareExchange 声明 topic 对应的持久化 exchange
+func declareExchange(ch *amqp.Channel, topic, exchangeType string) error {
+	err := ch.ExchangeDeclare(
+		topic,        // name
+		exchangeType, // type
+		true,         // durable
+		false,        // auto-deleted
+		false,        // internal
+		false,        // no-wait
+		nil,          // arguments
+	)
+	if err != nil {
+		return fmt.Errorf("声明 exchange %s 失败: %w", topic, err)
+	}
+	return nil
+}
+
+// declareQueue 声明 channel 对应的持久化队列，并按绑定规则绑定到 topic 的 exchange
+func declareQueue(ch *amqp.Channel, topic, channel, exchangeType string, binding messaging.Binding, priority bool) (amqp.Queue, error) {
+	q, err := declareUnboundQueue(ch, channel, priority)
+	if err != nil {
+		return q, err
+	}
+
+	// 每个路由键一条绑定
+	keys, args := bindingArgs(exchangeType, binding)
+	for _, routingKey := range keys {
+		err = ch.QueueBind(
+			q.Name,     // queue name
+			routingKey, // routing key (fanout/headers 类型忽略)
+			topic,      // exchange
+			false,      // no-wait
+			args,       // arguments (headers 类型的匹配条件)
+		)
+		if err != nil {
+			return q, fmt.Errorf("绑定 queue %s 到 exchange %s（routing key %q）失败: %w", channel, topic, routingKey, err)
+		}
+	}
+	return q, nil
+}
+
+// declareUnboundQueue 只声明 channel 对应的持久化队列，不创建绑定
+func declareUnboundQueue(ch *amqp.Channel, channel string, priority bool) (amqp.Queue, error) {
+	q, err := ch.QueueDeclare(
+		channel,             // name
+		true,                // durable
+		false,               // delete when unused
+		false,               // exclusive
+		false,               // no-wait
+		queueArgs(priority), // arguments
+	)
+	if err != nil {
+		return q, fmt.Errorf("声明 queue %s 失败: %w", channel, err)
+	}
+	return q, nil
+}
+
+// queueArgs 队列参数，优先级队列带 x-max-priority
+func queueArgs(priority bool) amqp.Table {
+	if !priority {
+		return nil
+	}
+	return amqp.Table{"x-max-priority": int32(messaging.MaxPriority)}
+}
+
+// bindingArgs 将绑定规则转换为 QueueBind 的路由键与参数
+func bindingArgs(exchangeType string, binding messaging.Binding) ([]string, amqp.Table) {
+	switch exchangeType {
+	case amqp.ExchangeHeaders:
+		// 没有匹配条件时使用 all，等价于接收全部消息
+		args := amqp.Table{"x-match": "any"}
+		if binding.MatchAll || len(binding.Headers) == 0 {
+			args["x-match"] = "all"
+		}
+		for k, v := range binding.Headers {
+			args[k] = v
+		}
+		return []string{""}, args
+	case amqp.ExchangeTopic:
+		if len(binding.RoutingKeys) == 0 {
+			return []string{"#"}, nil
+		}
+	case amqp.ExchangeDirect:
+		if len(binding.RoutingKeys) == 0 {
+			return []string{""}, nil
+		}
+	default:
+		return []string{""}, nil
+	}
+	return binding.RoutingKeys, nil
+}
+
+// Provisioner 预先声明 exchange、队列与绑定，实现 provision.QueueTarget
+//
+// 声明参数与订阅者一致（同样的 exchange 类型、绑定与优先级参数），重复声明是幂等的。
+// 队列参数（如 x-max-priority）与已存在的队列不一致时 broker 会拒绝声明。
+type Provisioner struct {
+	conn    *amqp.Connection
+	options options
+}
+
+// NewProvisioner 创建 RabbitMQ 拓扑声明器
+// opts 中只有 WithExchangeType、WithHeartbeat、WithConnectionTimeout 生效，应与订阅者的配置一致
+func NewProvisioner(url string, opts ...Option) (*Provisioner, error) {
+	o := newOptions(opts...)
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+
+	c := &connection{url: url, options: o}
+	conn, err := c.dial()
+	if err != nil {
+		return nil, fmt.Errorf("连接 RabbitMQ 失败: %w", err)
+	}
+	return &Provisioner{conn: conn, options: o}, nil
+}
+
+// EnsureTopic 声明 topic 对应的 exchange
+func (p *Provisioner) EnsureTopic(ctx context.Context, topic string) error {
+	return p.withChannel(ctx, func(ch *amqp.Channel) error {
+		return declareExchange(ch, topic, p.options.exchangeType)
+	})
+}
+
+// EnsureChannel 声明 exchange 与 channel 对应的队列，并使用默认绑定
+func (p *Provisioner) EnsureChannel(ctx context.Context, topic, channel string) error {
+	return p.EnsureQueue(ctx, topic, channel, &messaging.Binding{}, false)
+}
+
+// EnsureQueue 声明 exchange 与 channel 对应的队列
+// binding 为 nil 时不创建绑定（由订阅者绑定）；priority 时与 SubscribeWithPriority 一样声明为优先级队列
+func (p *Provisioner) EnsureQueue(ctx context.Context, topic, channel string, binding *messaging.Binding, priority bool) error {
+	return p.withChannel(ctx, func(ch *amqp.Channel) error {
+		if err := declareExchange(ch, topic, p.options.exchangeType); err != nil {
+			return err
+		}
+		if binding == nil {
+			_, err := declareUnboundQueue(ch, channel, priority)
+			return err
+		}
+		_, err := declareQueue(ch, topic, channel, p.options.exchangeType, *binding, priority)
+		return err
+	})
+}
+
+// Close 关闭连接
+func (p *Provisioner) Close() error {
+	return p.conn.Close()
+}
+
+// withChannel 在独立的 channel 上执行声明
+// 声明冲突时 broker 会关闭 channel，每次声明使用新的 channel 互不影响
+func (p *Provisioner) withChannel(ctx context.Context, declare func(*amqp.Channel) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	ch, err := p.conn.Channel()
+	if err != nil {
+		return fmt.Errorf("创建 channel 失败: %w", err)
+	}
+	defer ch.Close()
+	return declare(ch)
+}
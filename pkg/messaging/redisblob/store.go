@@ -0,0 +1,95 @@
+// Package redisblob 提供基于 Redis 的 messaging.BlobStore 实现（Claim Check 外置消息体存储）
+//
+// 每个引用对应一个键，过期清理交给 Redis 的 TTL。
+//
+// 使用示例：
+//
+//	values := store.NewValueStore[[]byte](client, store.BytesCodec{})
+//	blobs := redisblob.NewStore(values)
+//	cfg := messaging.ClaimCheckConfig{Store: blobs, Threshold: 512 << 10, TTL: 72 * time.Hour}
+//	publisher := messaging.WrapPublisher(bus.Publisher(), messaging.ClaimCheckPublisherMiddleware(cfg))
+//	router.AddHandlerWithMiddleware("document.uploaded", "indexer", handler, messaging.ClaimCheckMiddleware(cfg))
+package redisblob
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/FangcunMount/component-base/pkg/messaging"
+	"github.com/FangcunMount/component-base/pkg/redis/keyspace"
+	"github.com/FangcunMount/component-base/pkg/redis/store"
+)
+
+// DefaultNamespace 默认键命名空间
+const DefaultNamespace = "messaging:blob"
+
+// Store 基于 redis/store.ValueStore 的外置消息体存储
+type Store struct {
+	values   *store.ValueStore[[]byte]
+	keyspace keyspace.Keyspace
+}
+
+// Option Store 配置项
+type Option func(*Store)
+
+// WithKeyspace 设置键命名空间（默认 messaging:blob）
+func WithKeyspace(ks keyspace.Keyspace) Option {
+	return func(s *Store) {
+		s.keyspace = ks
+	}
+}
+
+// NewStore 创建外置消息体存储
+// values: 使用 store.BytesCodec 的 ValueStore
+func NewStore(values *store.ValueStore[[]byte], opts ...Option) *Store {
+	s := &Store{
+		values:   values,
+		keyspace: keyspace.New(DefaultNamespace),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Put 实现 messaging.BlobStore 接口
+func (s *Store) Put(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	storeKey, err := s.key(key)
+	if err != nil {
+		return err
+	}
+	return s.values.Set(ctx, storeKey, data, ttl)
+}
+
+// Get 实现 messaging.BlobStore 接口
+func (s *Store) Get(ctx context.Context, key string) ([]byte, error) {
+	storeKey, err := s.key(key)
+	if err != nil {
+		return nil, err
+	}
+	data, ok, err := s.values.Get(ctx, storeKey)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, messaging.ErrBlobNotFound
+	}
+	return data, nil
+}
+
+// Delete 实现 messaging.BlobStore 接口
+func (s *Store) Delete(ctx context.Context, key string) error {
+	storeKey, err := s.key(key)
+	if err != nil {
+		return err
+	}
+	return s.values.Delete(ctx, storeKey)
+}
+
+func (s *Store) key(key string) (store.StoreKey, error) {
+	if key == "" {
+		return "", fmt.Errorf("blob key is empty")
+	}
+	return store.NewStoreKey(s.keyspace.Prefix(key))
+}
@@ -0,0 +1,93 @@
+package redisblob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/FangcunMount/component-base/pkg/messaging"
+	"github.com/FangcunMount/component-base/pkg/redis/store"
+)
+
+func newTestStore(t *testing.T, opts ...Option) (*miniredis.Miniredis, *Store) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return mr, NewStore(store.NewValueStore[[]byte](client, store.BytesCodec{}), opts...)
+}
+
+func TestStorePutGetDelete(t *testing.T) {
+	mr, blobs := newTestStore(t)
+	ctx := context.Background()
+	data := bytes.Repeat([]byte("x"), 4096)
+
+	if err := blobs.Put(ctx, "ref-1", data, time.Hour); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if ttl := mr.TTL("messaging:blob:ref-1"); ttl != time.Hour {
+		t.Fatalf("TTL = %s, want 1h", ttl)
+	}
+	got, err := blobs.Get(ctx, "ref-1")
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("Get = %d bytes, %v", len(got), err)
+	}
+
+	if err := blobs.Delete(ctx, "ref-1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := blobs.Get(ctx, "ref-1"); !errors.Is(err, messaging.ErrBlobNotFound) {
+		t.Fatalf("Get after Delete = %v, want ErrBlobNotFound", err)
+	}
+}
+
+func TestStoreExpiresBlobs(t *testing.T) {
+	mr, blobs := newTestStore(t)
+	ctx := context.Background()
+
+	if err := blobs.Put(ctx, "ref-1", []byte("doc"), time.Minute); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	mr.FastForward(2 * time.Minute)
+	if _, err := blobs.Get(ctx, "ref-1"); !errors.Is(err, messaging.ErrBlobNotFound) {
+		t.Fatalf("Get after TTL = %v, want ErrBlobNotFound", err)
+	}
+}
+
+func TestClaimCheckRoundTripThroughRedis(t *testing.T) {
+	mr, blobs := newTestStore(t)
+	cfg := messaging.ClaimCheckConfig{Store: blobs, Threshold: 16, TTL: time.Hour, DeleteAfterProcessing: true}
+
+	var sent *messaging.Message
+	publish := messaging.ClaimCheckPublisherMiddleware(cfg)(func(ctx context.Context, topic string, msg *messaging.Message) error {
+		sent = msg
+		return nil
+	})
+	payload := bytes.Repeat([]byte("large-document "), 10)
+	if err := publish(context.Background(), "document.uploaded", messaging.NewMessage("doc-1", payload)); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if len(sent.Payload) != 0 || sent.Metadata[messaging.MetadataClaimCheck] == "" {
+		t.Fatalf("published message should carry only a reference: %+v", sent)
+	}
+
+	var received []byte
+	handler := messaging.ClaimCheckMiddleware(cfg)(func(ctx context.Context, msg *messaging.Message) error {
+		received = msg.Payload
+		return nil
+	})
+	if err := handler(context.Background(), sent); err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	if !bytes.Equal(received, payload) {
+		t.Fatalf("rehydrated payload = %q", received)
+	}
+	if keys := mr.Keys(); len(keys) != 0 {
+		t.Fatalf("blob should be deleted after processing, keys = %v", keys)
+	}
+}
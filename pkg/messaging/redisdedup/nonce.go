@@ -0,0 +1,100 @@
+package redisdedup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/FangcunMount/component-base/pkg/messaging"
+	"github.com/FangcunMount/component-base/pkg/redis/keyspace"
+	"github.com/FangcunMount/component-base/pkg/redis/store"
+)
+
+// DefaultNonceNamespace 签名随机数的默认键命名空间
+const DefaultNonceNamespace = "messaging:nonce"
+
+// NonceStore 基于 redis/store.ValueStore 的签名随机数存储
+// 同时实现 messaging.NonceStore 与 messaging.NonceClaimer：ClaimNonce 使用 SETNX 原子占用
+//
+// 使用示例：
+//
+//	values := store.NewValueStore[string](client, store.StringCodec{})
+//	verifier := messaging.VerifySignatureMiddleware(messaging.VerifierConfig{
+//	    GetKey:     keys.Lookup,
+//	    NonceStore: redisdedup.NewNonceStore(values),
+//	})
+type NonceStore struct {
+	values   *store.ValueStore[string]
+	keyspace keyspace.Keyspace
+}
+
+var (
+	_ messaging.NonceStore   = (*NonceStore)(nil)
+	_ messaging.NonceClaimer = (*NonceStore)(nil)
+)
+
+// NonceOption NonceStore 配置项
+type NonceOption func(*NonceStore)
+
+// WithNonceKeyspace 设置键命名空间（默认 messaging:nonce）
+func WithNonceKeyspace(ks keyspace.Keyspace) NonceOption {
+	return func(s *NonceStore) {
+		s.keyspace = ks
+	}
+}
+
+// NewNonceStore 创建签名随机数存储
+// values: 使用 store.StringCodec 的 ValueStore
+func NewNonceStore(values *store.ValueStore[string], opts ...NonceOption) *NonceStore {
+	s := &NonceStore{
+		values:   values,
+		keyspace: keyspace.New(DefaultNonceNamespace),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Exists 实现 messaging.NonceStore 接口
+func (s *NonceStore) Exists(ctx context.Context, nonce string) (bool, error) {
+	key, err := s.key(nonce)
+	if err != nil {
+		return false, err
+	}
+	return s.values.Exists(ctx, key)
+}
+
+// Store 实现 messaging.NonceStore 接口
+func (s *NonceStore) Store(ctx context.Context, nonce string, ttl time.Duration) error {
+	key, err := s.key(nonce)
+	if err != nil {
+		return err
+	}
+	return s.values.Set(ctx, key, "1", ttl)
+}
+
+// ClaimNonce 实现 messaging.NonceClaimer 接口
+func (s *NonceStore) ClaimNonce(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	key, err := s.key(nonce)
+	if err != nil {
+		return false, err
+	}
+	return s.values.SetIfAbsent(ctx, key, "1", ttl)
+}
+
+// ReleaseNonce 实现 messaging.NonceClaimer 接口
+func (s *NonceStore) ReleaseNonce(ctx context.Context, nonce string) error {
+	key, err := s.key(nonce)
+	if err != nil {
+		return err
+	}
+	return s.values.Delete(ctx, key)
+}
+
+func (s *NonceStore) key(nonce string) (store.StoreKey, error) {
+	if nonce == "" {
+		return "", fmt.Errorf("signature nonce is empty")
+	}
+	return store.NewStoreKey(s.keyspace.Prefix(nonce))
+}
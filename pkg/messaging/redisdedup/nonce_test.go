@@ -0,0 +1,53 @@
+package redisdedup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/FangcunMount/component-base/pkg/redis/store"
+)
+
+func newTestNonceStore(t *testing.T) (*miniredis.Miniredis, *NonceStore) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return mr, NewNonceStore(store.NewValueStore[string](client, store.StringCodec{}))
+}
+
+func TestNonceStoreClaimIsExclusiveUntilReleased(t *testing.T) {
+	mr, nonces := newTestNonceStore(t)
+	ctx := context.Background()
+
+	claimed, err := nonces.ClaimNonce(ctx, "n-1", time.Hour)
+	if err != nil || !claimed {
+		t.Fatalf("first ClaimNonce = %v, %v", claimed, err)
+	}
+	if ttl := mr.TTL("messaging:nonce:n-1"); ttl != time.Hour {
+		t.Fatalf("nonce TTL = %s, want 1h", ttl)
+	}
+	if claimed, _ := nonces.ClaimNonce(ctx, "n-1", time.Hour); claimed {
+		t.Fatal("second ClaimNonce should fail while the nonce is held")
+	}
+	if exists, _ := nonces.Exists(ctx, "n-1"); !exists {
+		t.Fatal("claimed nonce should exist")
+	}
+
+	if err := nonces.ReleaseNonce(ctx, "n-1"); err != nil {
+		t.Fatalf("ReleaseNonce: %v", err)
+	}
+	if claimed, _ := nonces.ClaimNonce(ctx, "n-1", time.Hour); !claimed {
+		t.Fatal("ClaimNonce after release should succeed")
+	}
+}
+
+func TestNonceStoreRejectsEmptyNonce(t *testing.T) {
+	_, nonces := newTestNonceStore(t)
+	if _, err := nonces.ClaimNonce(context.Background(), "", time.Hour); err == nil {
+		t.Fatal("empty nonce should be rejected")
+	}
+}
@@ -0,0 +1,160 @@
+// Package redisdedup 提供基于 Redis 的 messaging.DeduplicationStore 实现，
+// 以及用于签名验证防重放的 NonceStore（见 nonce.go）
+//
+// 每条消息对应一个键：
+//   - Claim：SETNX "processing:<令牌>"，带处理超时（消费者崩溃后认领自动失效）
+//   - Complete：值仍为本次认领时覆盖为 "done"，带已处理标记的保留时间
+//   - Release：值仍为本次认领时删除键，允许重投的消息再次处理
+//
+// Complete/Release 通过 Lua 脚本比较令牌后原子地写入/删除：认领过期并被其他消费者
+// 重新认领后，迟到的消费者不会覆盖或删除对方的认领（返回 messaging.ErrDeduplicationClaimLost）
+//
+// 使用示例：
+//
+//	values := store.NewValueStore[string](client, store.StringCodec{})
+//	dedup := redisdedup.NewStore(values, redisdedup.WithProcessingTTL(2*time.Minute))
+//	router.AddHandlerWithMiddleware("order.paid", "fulfillment", handler,
+//	    messaging.DeduplicationMiddleware(dedup, 24*time.Hour))
+package redisdedup
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/FangcunMount/component-base/pkg/messaging"
+	"github.com/FangcunMount/component-base/pkg/redis/keyspace"
+	"github.com/FangcunMount/component-base/pkg/redis/store"
+)
+
+// DefaultProcessingTTL 认领的默认处理超时
+const DefaultProcessingTTL = 5 * time.Minute
+
+// DefaultNamespace 默认键命名空间
+const DefaultNamespace = "messaging:dedup"
+
+const (
+	stateProcessingPrefix = "processing:"
+	stateDone             = "done"
+)
+
+// Store 基于 redis/store.ValueStore 的去重存储
+type Store struct {
+	values        *store.ValueStore[string]
+	keyspace      keyspace.Keyspace
+	processingTTL time.Duration
+}
+
+// Option Store 配置项
+type Option func(*Store)
+
+// WithProcessingTTL 设置认领的处理超时
+// 应大于处理器的最长执行时间，否则处理中的消息可能被其他消费者再次认领
+func WithProcessingTTL(ttl time.Duration) Option {
+	return func(s *Store) {
+		if ttl > 0 {
+			s.processingTTL = ttl
+		}
+	}
+}
+
+// WithKeyspace 设置键命名空间（默认 messaging:dedup）
+func WithKeyspace(ks keyspace.Keyspace) Option {
+	return func(s *Store) {
+		s.keyspace = ks
+	}
+}
+
+// NewStore 创建去重存储
+// values: 使用 store.StringCodec 的 ValueStore
+func NewStore(values *store.ValueStore[string], opts ...Option) *Store {
+	s := &Store{
+		values:        values,
+		keyspace:      keyspace.New(DefaultNamespace),
+		processingTTL: DefaultProcessingTTL,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Claim 实现 messaging.DeduplicationStore 接口
+func (s *Store) Claim(ctx context.Context, uuid string) (messaging.DeduplicationState, string, error) {
+	key, err := s.key(uuid)
+	if err != nil {
+		return messaging.DeduplicationInProgress, "", err
+	}
+	token, err := newClaimToken()
+	if err != nil {
+		return messaging.DeduplicationInProgress, "", err
+	}
+
+	claimed, err := s.values.SetIfAbsent(ctx, key, stateProcessingPrefix+token, s.processingTTL)
+	if err != nil {
+		return messaging.DeduplicationInProgress, "", err
+	}
+	if claimed {
+		return messaging.DeduplicationClaimed, token, nil
+	}
+
+	state, ok, err := s.values.Get(ctx, key)
+	if err != nil {
+		return messaging.DeduplicationInProgress, "", err
+	}
+	if ok && state == stateDone {
+		return messaging.DeduplicationDone, "", nil
+	}
+	// 认领刚好过期（!ok）时也按处理中返回，由重投再次认领
+	return messaging.DeduplicationInProgress, "", nil
+}
+
+// Complete 实现 messaging.DeduplicationStore 接口
+func (s *Store) Complete(ctx context.Context, uuid, token string, ttl time.Duration) error {
+	key, err := s.key(uuid)
+	if err != nil {
+		return err
+	}
+	ok, err := s.values.CompareAndSet(ctx, key, stateProcessingPrefix+token, stateDone, ttl)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s", messaging.ErrDeduplicationClaimLost, uuid)
+	}
+	return nil
+}
+
+// Release 实现 messaging.DeduplicationStore 接口
+func (s *Store) Release(ctx context.Context, uuid, token string) error {
+	key, err := s.key(uuid)
+	if err != nil {
+		return err
+	}
+	ok, err := s.values.CompareAndDelete(ctx, key, stateProcessingPrefix+token)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s", messaging.ErrDeduplicationClaimLost, uuid)
+	}
+	return nil
+}
+
+// newClaimToken 生成认领令牌
+func newClaimToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate claim token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func (s *Store) key(uuid string) (store.StoreKey, error) {
+	if uuid == "" {
+		return "", fmt.Errorf("message uuid is empty")
+	}
+	return store.NewStoreKey(s.keyspace.Prefix(uuid))
+}
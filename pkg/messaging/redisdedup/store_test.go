@@ -0,0 +1,171 @@
+package redisdedup
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/FangcunMount/component-base/pkg/messaging"
+	"github.com/FangcunMount/component-base/pkg/redis/store"
+)
+
+func newTestStore(t *testing.T, opts ...Option) (*miniredis.Miniredis, *Store) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return mr, NewStore(store.NewValueStore[string](client, store.StringCodec{}), opts...)
+}
+
+func TestStoreClaimCompleteRelease(t *testing.T) {
+	mr, dedup := newTestStore(t, WithProcessingTTL(time.Minute))
+	ctx := context.Background()
+
+	state, token, err := dedup.Claim(ctx, "msg-1")
+	if err != nil || state != messaging.DeduplicationClaimed || token == "" {
+		t.Fatalf("first Claim = %v, %q, %v", state, token, err)
+	}
+	if ttl := mr.TTL("messaging:dedup:msg-1"); ttl != time.Minute {
+		t.Fatalf("processing TTL = %s, want 1m", ttl)
+	}
+	if state, _, _ := dedup.Claim(ctx, "msg-1"); state != messaging.DeduplicationInProgress {
+		t.Fatalf("second Claim = %v, want in progress", state)
+	}
+
+	if err := dedup.Release(ctx, "msg-1", token); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	state, token, _ = dedup.Claim(ctx, "msg-1")
+	if state != messaging.DeduplicationClaimed {
+		t.Fatalf("Claim after Release = %v, want claimed", state)
+	}
+
+	if err := dedup.Complete(ctx, "msg-1", token, 24*time.Hour); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if ttl := mr.TTL("messaging:dedup:msg-1"); ttl != 24*time.Hour {
+		t.Fatalf("done TTL = %s, want 24h", ttl)
+	}
+	if state, _, _ := dedup.Claim(ctx, "msg-1"); state != messaging.DeduplicationDone {
+		t.Fatalf("Claim after Complete = %v, want done", state)
+	}
+}
+
+func TestStoreClaimExpiresAfterProcessingTTL(t *testing.T) {
+	mr, dedup := newTestStore(t, WithProcessingTTL(time.Minute))
+	ctx := context.Background()
+
+	if state, _, _ := dedup.Claim(ctx, "msg-1"); state != messaging.DeduplicationClaimed {
+		t.Fatalf("first Claim = %v", state)
+	}
+	mr.FastForward(2 * time.Minute)
+	if state, _, _ := dedup.Claim(ctx, "msg-1"); state != messaging.DeduplicationClaimed {
+		t.Fatalf("Claim after processing TTL = %v, want claimed", state)
+	}
+}
+
+func TestStoreStaleClaimCannotTouchReclaimedMessage(t *testing.T) {
+	mr, dedup := newTestStore(t, WithProcessingTTL(time.Minute))
+	ctx := context.Background()
+
+	_, slow, _ := dedup.Claim(ctx, "msg-1")
+	mr.FastForward(2 * time.Minute)
+	state, current, _ := dedup.Claim(ctx, "msg-1")
+	if state != messaging.DeduplicationClaimed {
+		t.Fatalf("reclaim after processing TTL = %v, want claimed", state)
+	}
+
+	// 过期认领的持有者迟到完成/失败，不能影响新的认领
+	if err := dedup.Release(ctx, "msg-1", slow); !errors.Is(err, messaging.ErrDeduplicationClaimLost) {
+		t.Fatalf("stale Release = %v, want ErrDeduplicationClaimLost", err)
+	}
+	if err := dedup.Complete(ctx, "msg-1", slow, time.Hour); !errors.Is(err, messaging.ErrDeduplicationClaimLost) {
+		t.Fatalf("stale Complete = %v, want ErrDeduplicationClaimLost", err)
+	}
+	if state, _, _ := dedup.Claim(ctx, "msg-1"); state != messaging.DeduplicationInProgress {
+		t.Fatalf("Claim while reclaimed = %v, want in progress", state)
+	}
+
+	if err := dedup.Complete(ctx, "msg-1", current, time.Hour); err != nil {
+		t.Fatalf("Complete by current holder: %v", err)
+	}
+	if err := dedup.Release(ctx, "msg-1", slow); !errors.Is(err, messaging.ErrDeduplicationClaimLost) {
+		t.Fatalf("stale Release after completion = %v, want ErrDeduplicationClaimLost", err)
+	}
+	if state, _, _ := dedup.Claim(ctx, "msg-1"); state != messaging.DeduplicationDone {
+		t.Fatalf("Claim after Complete = %v, want done", state)
+	}
+}
+
+func TestDeduplicationMiddlewareProcessesConcurrentDuplicatesOnce(t *testing.T) {
+	_, dedup := newTestStore(t)
+
+	var processed atomic.Int32
+	release := make(chan struct{})
+	handler := messaging.DeduplicationMiddleware(dedup, time.Hour)(func(ctx context.Context, msg *messaging.Message) error {
+		processed.Add(1)
+		<-release
+		return nil
+	})
+
+	const duplicates = 5
+	var wg sync.WaitGroup
+	errs := make(chan error, duplicates)
+	for i := 0; i < duplicates; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- handler(context.Background(), messaging.NewMessage("order-1", nil))
+		}()
+	}
+
+	// 持有 claim 的处理器阻塞在 release 上，其余副本全部返回后再放行
+	inProgress := 0
+	timeout := time.After(5 * time.Second)
+	for i := 0; i < duplicates-1; i++ {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, messaging.ErrMessageInProgress) {
+				t.Fatalf("duplicate returned %v, want ErrMessageInProgress", err)
+			}
+			inProgress++
+		case <-timeout:
+			t.Fatalf("only %d of %d duplicates were rejected", inProgress, duplicates-1)
+		}
+	}
+	close(release)
+	wg.Wait()
+	if err := <-errs; err != nil {
+		t.Fatalf("claim holder returned %v", err)
+	}
+
+	if processed.Load() != 1 {
+		t.Fatalf("processed %d times, want 1", processed.Load())
+	}
+	if err := handler(context.Background(), messaging.NewMessage("order-1", nil)); err != nil || processed.Load() != 1 {
+		t.Fatalf("redelivery after completion: err=%v processed=%d", err, processed.Load())
+	}
+}
+
+func TestDeduplicationMiddlewareSurfacesStoreOutage(t *testing.T) {
+	mr, dedup := newTestStore(t)
+	mr.Close()
+
+	called := false
+	handler := messaging.DeduplicationMiddleware(dedup, time.Hour)(func(ctx context.Context, msg *messaging.Message) error {
+		called = true
+		return nil
+	})
+	if err := handler(context.Background(), messaging.NewMessage("order-1", nil)); err == nil {
+		t.Fatalf("expected error when redis is unavailable")
+	}
+	if called {
+		t.Fatalf("handler should not run without a deduplication claim")
+	}
+}
@@ -0,0 +1,156 @@
+package redisstream
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/FangcunMount/component-base/pkg/messaging"
+	redisruntime "github.com/FangcunMount/component-base/pkg/redis/runtime"
+)
+
+// Register 将 Redis Streams 提供者注册到 messaging 工厂
+//
+// 与 nsq、rabbitmq 不同，Redis 连接由 redis/runtime 统一管理，
+// 因此不在 init 中自动注册，而是由应用在 Runtime 建立连接后显式调用：
+//
+//	rt := redisruntime.New(defaultCfg, profiles)
+//	_ = rt.Connect()
+//	redisstream.Register(rt)
+//	bus, err := messaging.NewEventBus(&messaging.Config{Provider: messaging.ProviderRedisStream, ...})
+func Register(rt redisruntime.Runtime) {
+	messaging.RegisterProvider(messaging.ProviderRedisStream, func(config *messaging.Config) (messaging.EventBus, error) {
+		if config == nil {
+			config = messaging.DefaultConfig()
+		}
+		return NewEventBusFromRuntime(rt, config.RedisStream)
+	})
+}
+
+// eventBus Redis Streams 事件总线实现
+type eventBus struct {
+	client     goredis.UniversalClient
+	cfg        messaging.RedisStreamConfig
+	publisher  messaging.Publisher
+	subscriber messaging.Subscriber
+	router     *messaging.Router
+}
+
+// NewEventBusFromRuntime 使用 redis/runtime 中的 profile 创建事件总线
+// cfg.Profile 按 Runtime.Bind 的策略解析（缺失时可回退到默认 profile）
+func NewEventBusFromRuntime(rt redisruntime.Runtime, cfg messaging.RedisStreamConfig) (messaging.EventBus, error) {
+	if rt == nil {
+		return nil, fmt.Errorf("redis runtime is nil")
+	}
+	client, err := rt.Client(cfg.Profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve redis profile %q: %w", cfg.Profile, err)
+	}
+	return NewEventBus(client, cfg)
+}
+
+// NewEventBus 使用已有的 Redis 客户端创建事件总线
+// 客户端的生命周期由调用方（通常是 redis/runtime）管理，Close 不会关闭它
+func NewEventBus(client goredis.UniversalClient, cfg messaging.RedisStreamConfig) (messaging.EventBus, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	cfg = normalizeConfig(cfg)
+
+	pub := NewPublisher(client, cfg)
+	sub := NewSubscriber(client, cfg)
+
+	bus := &eventBus{
+		client:     client,
+		cfg:        cfg,
+		publisher:  pub,
+		subscriber: sub,
+	}
+	bus.router = messaging.NewRouter(sub)
+
+	return bus, nil
+}
+
+// Publisher 返回发布者
+func (b *eventBus) Publisher() messaging.Publisher {
+	return b.publisher
+}
+
+// Subscriber 返回订阅者
+func (b *eventBus) Subscriber() messaging.Subscriber {
+	return b.subscriber
+}
+
+// Router 返回路由器
+func (b *eventBus) Router() *messaging.Router {
+	return b.router
+}
+
+// DeleteTopic 实现 messaging.TopicDeleter 接口
+// 删除 topic 对应的 Stream，消费者组随 Stream 一并删除
+func (b *eventBus) DeleteTopic(ctx context.Context, topic, channel string) error {
+	if err := b.client.Del(ctx, streamKey(b.cfg, topic)).Err(); err != nil {
+		return fmt.Errorf("failed to delete stream %s: %w", streamKey(b.cfg, topic), err)
+	}
+	return nil
+}
+
+// Health 健康检查
+func (b *eventBus) Health() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := b.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("redis stream health check failed: %w", err)
+	}
+	return nil
+}
+
+// Close 关闭事件总线（不关闭底层 Redis 客户端）
+func (b *eventBus) Close() error {
+	var errs []error
+
+	if err := b.subscriber.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to close subscriber: %w", err))
+	}
+
+	if err := b.publisher.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to close publisher: %w", err))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("errors closing eventbus: %v", errs)
+	}
+
+	return nil
+}
+
+// normalizeConfig 为未设置的字段填充默认值
+func normalizeConfig(cfg messaging.RedisStreamConfig) messaging.RedisStreamConfig {
+	defaults := messaging.DefaultRedisStreamConfig()
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaults.BatchSize
+	}
+	if cfg.BlockTimeout <= 0 {
+		cfg.BlockTimeout = defaults.BlockTimeout
+	}
+	if cfg.ClaimMinIdle <= 0 {
+		cfg.ClaimMinIdle = defaults.ClaimMinIdle
+	}
+	if cfg.ClaimInterval <= 0 {
+		cfg.ClaimInterval = defaults.ClaimInterval
+	}
+	if cfg.StartID == "" {
+		cfg.StartID = defaults.StartID
+	}
+	// 零值使用默认值，避免 stream 无限增长、失败消息被无限重新认领；负数表示不限制
+	if cfg.MaxLen == 0 {
+		cfg.MaxLen = defaults.MaxLen
+		cfg.ApproxMaxLen = defaults.ApproxMaxLen
+	}
+	if cfg.MaxAttempts == 0 {
+		cfg.MaxAttempts = defaults.MaxAttempts
+	}
+	return cfg
+}
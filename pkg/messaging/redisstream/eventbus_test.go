@@ -0,0 +1,419 @@
+package redisstream
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/FangcunMount/component-base/pkg/log"
+	"github.com/FangcunMount/component-base/pkg/messaging"
+	redisruntime "github.com/FangcunMount/component-base/pkg/redis/runtime"
+)
+
+func newTestClient(t *testing.T) (*miniredis.Miniredis, goredis.UniversalClient) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return mr, client
+}
+
+func testConfig() messaging.RedisStreamConfig {
+	cfg := messaging.DefaultRedisStreamConfig()
+	cfg.ConsumerName = "test-consumer"
+	cfg.BlockTimeout = 20 * time.Millisecond
+	cfg.ClaimMinIdle = 30 * time.Millisecond
+	cfg.ClaimInterval = 20 * time.Millisecond
+	return cfg
+}
+
+func TestEventBusDeliversMessageWithMetadataAndAcks(t *testing.T) {
+	_, client := newTestClient(t)
+	bus, err := NewEventBus(client, testConfig())
+	if err != nil {
+		t.Fatalf("NewEventBus: %v", err)
+	}
+	t.Cleanup(func() { _ = bus.Close() })
+
+	received := make(chan *messaging.Message, 1)
+	if err := bus.Subscriber().Subscribe("orders", "billing", func(ctx context.Context, msg *messaging.Message) error {
+		received <- msg
+		return nil
+	}); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	msg := messaging.NewMessage("msg-1", []byte(`{"id":1}`))
+	msg.Metadata["trace_id"] = "trace-1"
+	if err := bus.Publisher().PublishMessage(context.Background(), "orders", msg); err != nil {
+		t.Fatalf("PublishMessage: %v", err)
+	}
+
+	var got *messaging.Message
+	select {
+	case got = <-received:
+	case <-time.After(2 * time.Second):
+		t.Fatal("message was not delivered")
+	}
+	if got.UUID != "msg-1" || string(got.Payload) != `{"id":1}` {
+		t.Fatalf("message = %+v", got)
+	}
+	if got.Metadata["trace_id"] != "trace-1" {
+		t.Fatalf("trace_id metadata = %q", got.Metadata["trace_id"])
+	}
+	if got.Topic != "orders" || got.Channel != "billing" || got.Attempts != 1 {
+		t.Fatalf("topic/channel/attempts = %s/%s/%d", got.Topic, got.Channel, got.Attempts)
+	}
+
+	waitFor(t, func() bool {
+		pending, err := client.XPending(context.Background(), "orders", "billing").Result()
+		return err == nil && pending.Count == 0
+	})
+}
+
+func TestEventBusPropagatesTraceContext(t *testing.T) {
+	_, client := newTestClient(t)
+	bus, err := NewEventBus(client, testConfig())
+	if err != nil {
+		t.Fatalf("NewEventBus: %v", err)
+	}
+	t.Cleanup(func() { _ = bus.Close() })
+
+	type traceInfo struct{ traceID, spanID, requestID string }
+	received := make(chan traceInfo, 1)
+	if err := bus.Subscriber().Subscribe("orders", "audit", func(ctx context.Context, msg *messaging.Message) error {
+		received <- traceInfo{log.ExtractTraceID(ctx), log.ExtractSpanID(ctx), log.ExtractRequestID(ctx)}
+		return nil
+	}); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	ctx := log.WithTraceContext(context.Background(), "trace-1", "span-1", "req-1")
+	if err := bus.Publisher().Publish(ctx, "orders", []byte("payload")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	select {
+	case got := <-received:
+		if got.traceID != "trace-1" || got.requestID != "req-1" {
+			t.Fatalf("handler ctx trace/request = %q/%q", got.traceID, got.requestID)
+		}
+		if got.spanID == "" || got.spanID == "span-1" {
+			t.Fatalf("handler ctx span = %q, want a new span", got.spanID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("message was not delivered")
+	}
+}
+
+func TestSubscriberReclaimsNackedEntries(t *testing.T) {
+	_, client := newTestClient(t)
+	sub := NewSubscriber(client, testConfig())
+	t.Cleanup(func() { _ = sub.Close() })
+
+	var calls atomic.Int32
+	done := make(chan uint16, 1)
+	if err := sub.Subscribe("jobs", "workers", func(ctx context.Context, msg *messaging.Message) error {
+		if calls.Add(1) == 1 {
+			return errors.New("transient failure")
+		}
+		done <- msg.Attempts
+		return nil
+	}); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	if err := NewPublisher(client, testConfig()).Publish(context.Background(), "jobs", []byte("work")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	select {
+	case attempts := <-done:
+		if attempts != 2 {
+			t.Fatalf("Attempts = %d, want 2", attempts)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("nacked message was not reclaimed")
+	}
+}
+
+func TestSubscriberDropsEntriesAfterMaxAttempts(t *testing.T) {
+	_, client := newTestClient(t)
+	cfg := testConfig()
+	cfg.MaxAttempts = 2
+	sub := NewSubscriber(client, cfg)
+	t.Cleanup(func() { _ = sub.Close() })
+
+	var calls atomic.Int32
+	if err := sub.Subscribe("jobs", "workers", func(ctx context.Context, msg *messaging.Message) error {
+		calls.Add(1)
+		return errors.New("permanent failure")
+	}); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if err := NewPublisher(client, cfg).Publish(context.Background(), "jobs", []byte("work")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	waitFor(t, func() bool {
+		pending, err := client.XPending(context.Background(), "jobs", "workers").Result()
+		return calls.Load() >= 2 && err == nil && pending.Count == 0
+	})
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("handler calls = %d, want 2", got)
+	}
+}
+
+func TestSubscriberLeavesDeferredMessagesPendingUntilBatchFlush(t *testing.T) {
+	_, client := newTestClient(t)
+	cfg := testConfig()
+	cfg.ClaimMinIdle = time.Minute
+	sub := NewSubscriber(client, cfg)
+	t.Cleanup(func() { _ = sub.Close() })
+
+	flushed := make(chan int, 1)
+	batch := messaging.NewBatchConsumer(messaging.BatchConfig{Size: 3, FlushInterval: time.Hour},
+		func(ctx context.Context, msgs []*messaging.Message) error {
+			flushed <- len(msgs)
+			return nil
+		})
+	t.Cleanup(func() { _ = batch.Close() })
+	if err := sub.Subscribe("metrics", "ingest", batch.Handler()); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	pub := NewPublisher(client, cfg)
+	for i := 0; i < 2; i++ {
+		if err := pub.Publish(context.Background(), "metrics", []byte(strconv.Itoa(i))); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+	waitFor(t, func() bool {
+		pending, err := client.XPending(context.Background(), "metrics", "ingest").Result()
+		return err == nil && pending.Count == 2
+	})
+	select {
+	case <-flushed:
+		t.Fatal("batch flushed before it was full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := pub.Publish(context.Background(), "metrics", []byte("2")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	select {
+	case n := <-flushed:
+		if n != 3 {
+			t.Fatalf("batch size = %d, want 3", n)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("batch was not flushed")
+	}
+	waitFor(t, func() bool {
+		pending, err := client.XPending(context.Background(), "metrics", "ingest").Result()
+		return err == nil && pending.Count == 0
+	})
+}
+
+func TestSubscriberPauseStopsReadingUntilResume(t *testing.T) {
+	_, client := newTestClient(t)
+	bus, err := NewEventBus(client, testConfig())
+	if err != nil {
+		t.Fatalf("NewEventBus: %v", err)
+	}
+	t.Cleanup(func() { _ = bus.Close() })
+
+	var received atomic.Int32
+	sub := bus.Subscriber()
+	if err := sub.Subscribe("orders", "billing", func(ctx context.Context, msg *messaging.Message) error {
+		received.Add(1)
+		return nil
+	}); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	pausable, ok := sub.(messaging.PausableSubscriber)
+	if !ok {
+		t.Fatalf("redisstream subscriber should implement PausableSubscriber")
+	}
+	if err := pausable.Pause("orders", "billing"); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond) // 等待消费循环退出
+
+	if err := bus.Publisher().Publish(context.Background(), "orders", []byte("x")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	time.Sleep(80 * time.Millisecond)
+	if n := received.Load(); n != 0 {
+		t.Fatalf("paused subscription received %d messages", n)
+	}
+
+	if err := pausable.Resume("orders", "billing"); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	waitFor(t, func() bool { return received.Load() == 1 })
+
+	if err := pausable.Pause("orders", "unknown"); err == nil {
+		t.Fatalf("Pause on unknown subscription should fail")
+	}
+}
+
+func TestPublisherTrimsStreamWithMaxLen(t *testing.T) {
+	_, client := newTestClient(t)
+	cfg := testConfig()
+	cfg.StreamPrefix = "stream:"
+	cfg.MaxLen = 5
+	cfg.ApproxMaxLen = false
+	pub := NewPublisher(client, cfg)
+
+	for i := 0; i < 20; i++ {
+		if err := pub.Publish(context.Background(), "events", []byte(strconv.Itoa(i))); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+
+	length, err := client.XLen(context.Background(), "stream:events").Result()
+	if err != nil {
+		t.Fatalf("XLen: %v", err)
+	}
+	if length != 5 {
+		t.Fatalf("XLen = %d, want 5", length)
+	}
+}
+
+func TestNormalizeConfigDefaultsRetentionAndAttempts(t *testing.T) {
+	defaults := messaging.DefaultRedisStreamConfig()
+
+	cfg := normalizeConfig(messaging.RedisStreamConfig{})
+	if cfg.MaxLen != defaults.MaxLen || cfg.ApproxMaxLen != defaults.ApproxMaxLen {
+		t.Fatalf("MaxLen = %d (approx %v), want %d (approx %v)", cfg.MaxLen, cfg.ApproxMaxLen, defaults.MaxLen, defaults.ApproxMaxLen)
+	}
+	if cfg.MaxAttempts != defaults.MaxAttempts {
+		t.Fatalf("MaxAttempts = %d, want %d", cfg.MaxAttempts, defaults.MaxAttempts)
+	}
+
+	unlimited := normalizeConfig(messaging.RedisStreamConfig{MaxLen: -1, MaxAttempts: -1})
+	if unlimited.MaxLen != -1 || unlimited.MaxAttempts != -1 {
+		t.Fatalf("negative limits should be kept, got MaxLen=%d MaxAttempts=%d", unlimited.MaxLen, unlimited.MaxAttempts)
+	}
+}
+
+func TestSubscriberZeroConfigStopsRedeliveringFailingEntries(t *testing.T) {
+	_, client := newTestClient(t)
+	cfg := messaging.RedisStreamConfig{
+		ConsumerName:  "test-consumer",
+		BlockTimeout:  20 * time.Millisecond,
+		ClaimMinIdle:  10 * time.Millisecond,
+		ClaimInterval: 10 * time.Millisecond,
+	}
+	bus, err := NewEventBus(client, cfg)
+	if err != nil {
+		t.Fatalf("NewEventBus: %v", err)
+	}
+	t.Cleanup(func() { _ = bus.Close() })
+
+	var calls atomic.Int32
+	if err := bus.Subscriber().Subscribe("events", "workers", func(ctx context.Context, msg *messaging.Message) error {
+		calls.Add(1)
+		return errors.New("always fails")
+	}); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if err := bus.Publisher().Publish(context.Background(), "events", []byte("poison")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	want := int32(messaging.DefaultRedisStreamConfig().MaxAttempts)
+	deadline := time.Now().Add(3 * time.Second)
+	for calls.Load() < want && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if got := calls.Load(); got != want {
+		t.Fatalf("handler calls = %d, want %d", got, want)
+	}
+}
+
+func TestNewEventBusFromRuntimeResolvesProfile(t *testing.T) {
+	mr := miniredis.RunT(t)
+	host, portText, err := net.SplitHostPort(mr.Addr())
+	if err != nil {
+		t.Fatalf("SplitHostPort: %v", err)
+	}
+	port, err := strconv.Atoi(portText)
+	if err != nil {
+		t.Fatalf("Atoi: %v", err)
+	}
+
+	rt := redisruntime.New(&redisruntime.Config{Host: host, Port: port}, map[string]*redisruntime.Config{
+		"messaging": {Database: 1},
+	})
+	if err := rt.Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	cfg := testConfig()
+	cfg.Profile = "messaging"
+	bus, err := NewEventBusFromRuntime(rt, cfg)
+	if err != nil {
+		t.Fatalf("NewEventBusFromRuntime: %v", err)
+	}
+	t.Cleanup(func() { _ = bus.Close() })
+
+	if err := bus.Health(); err != nil {
+		t.Fatalf("Health: %v", err)
+	}
+	if err := bus.Publisher().Publish(context.Background(), "events", []byte("x")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	mr.Select(1)
+	if !mr.Exists("events") {
+		t.Fatalf("stream should be written to the messaging profile database")
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("condition not met before deadline")
+}
+
+func TestRequesterCloseDeletesReplyStream(t *testing.T) {
+	mr, client := newTestClient(t)
+	bus, err := NewEventBus(client, testConfig())
+	if err != nil {
+		t.Fatalf("NewEventBus: %v", err)
+	}
+	t.Cleanup(func() { _ = bus.Close() })
+
+	requester, err := messaging.NewRequester(bus)
+	if err != nil {
+		t.Fatalf("NewRequester: %v", err)
+	}
+	key := testConfig().StreamPrefix + requester.ReplyTopic()
+	if !mr.Exists(key) {
+		t.Fatalf("reply stream %s should exist after warm-up", key)
+	}
+
+	if err := requester.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if mr.Exists(key) {
+		t.Fatalf("reply stream %s still exists after Close", key)
+	}
+}
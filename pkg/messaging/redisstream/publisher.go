@@ -0,0 +1,87 @@
+package redisstream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/FangcunMount/component-base/pkg/messaging"
+)
+
+// stream 条目字段名
+const (
+	fieldUUID     = "uuid"
+	fieldPayload  = "payload"
+	fieldMetadata = "metadata"
+)
+
+// publisher Redis Streams 发布者实现
+type publisher struct {
+	client goredis.UniversalClient
+	config messaging.RedisStreamConfig
+}
+
+// NewPublisher 创建 Redis Streams 发布者
+// topic 映射为 stream key（StreamPrefix + topic），消息通过 XADD 写入
+func NewPublisher(client goredis.UniversalClient, cfg messaging.RedisStreamConfig) messaging.Publisher {
+	return &publisher{
+		client: client,
+		config: normalizeConfig(cfg),
+	}
+}
+
+// Publish 发布消息（ctx 中的追踪信息写入 Metadata）
+func (p *publisher) Publish(ctx context.Context, topic string, body []byte) error {
+	return p.PublishMessage(ctx, topic, messaging.NewMessage("", body))
+}
+
+// PublishMessage 发布消息对象（支持 Metadata）
+// Metadata 以 JSON 形式保存在独立字段中，无需额外的信封编码
+func (p *publisher) PublishMessage(ctx context.Context, topic string, msg *messaging.Message) error {
+	if msg == nil {
+		return fmt.Errorf("message is nil")
+	}
+	msg = messaging.InjectTraceContext(ctx, msg)
+	values := map[string]interface{}{
+		fieldPayload: msg.Payload,
+	}
+	if msg.UUID != "" {
+		values[fieldUUID] = msg.UUID
+	}
+	if len(msg.Metadata) > 0 {
+		metadata, err := json.Marshal(msg.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to encode message metadata: %w", err)
+		}
+		values[fieldMetadata] = metadata
+	}
+	return p.add(ctx, topic, values)
+}
+
+// add 执行 XADD，并按配置使用 MAXLEN 裁剪 stream
+func (p *publisher) add(ctx context.Context, topic string, values map[string]interface{}) error {
+	args := &goredis.XAddArgs{
+		Stream: streamKey(p.config, topic),
+		Values: values,
+	}
+	if p.config.MaxLen > 0 {
+		args.MaxLen = p.config.MaxLen
+		args.Approx = p.config.ApproxMaxLen
+	}
+	if err := p.client.XAdd(ctx, args).Err(); err != nil {
+		return fmt.Errorf("failed to publish message to topic %s: %w", topic, err)
+	}
+	return nil
+}
+
+// Close 关闭发布者（客户端由 redis/runtime 管理，这里无需释放）
+func (p *publisher) Close() error {
+	return nil
+}
+
+// streamKey 将 topic 映射为 stream key
+func streamKey(cfg messaging.RedisStreamConfig, topic string) string {
+	return cfg.StreamPrefix + topic
+}
@@ -0,0 +1,402 @@
+package redisstream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/FangcunMount/component-base/pkg/messaging"
+)
+
+// subscriber Redis Streams 订阅者实现
+//
+// 映射关系：
+//   - topic → stream（StreamPrefix + topic）
+//   - channel → consumer group
+//
+// Ack 使用 XACK；Nack 不做任何操作，条目保留在 PEL（pending entries list）中，
+// 空闲超过 ClaimMinIdle 后由 XAUTOCLAIM 重新认领并投递。
+type subscriber struct {
+	client    goredis.UniversalClient
+	config    messaging.RedisStreamConfig
+	name      string
+	consumers map[string]*consumer
+	mu        sync.Mutex
+	stopped   bool
+}
+
+type consumer struct {
+	topic   string
+	channel string
+	stream  string
+	handler messaging.Handler
+	cancel  context.CancelFunc
+	done    chan struct{}
+	paused  bool
+}
+
+// NewSubscriber 创建 Redis Streams 订阅者
+func NewSubscriber(client goredis.UniversalClient, cfg messaging.RedisStreamConfig) messaging.Subscriber {
+	cfg = normalizeConfig(cfg)
+	name := cfg.ConsumerName
+	if name == "" {
+		name = defaultConsumerName()
+	}
+	return &subscriber{
+		client:    client,
+		config:    cfg,
+		name:      name,
+		consumers: make(map[string]*consumer),
+	}
+}
+
+// Subscribe 订阅主题
+// 消费组不存在时以 StartID 为起点自动创建（MKSTREAM）
+func (s *subscriber) Subscribe(topic, channel string, handler messaging.Handler) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return fmt.Errorf("subscriber is stopped")
+	}
+
+	key := topic + ":" + channel
+	if _, exists := s.consumers[key]; exists {
+		return fmt.Errorf("already subscribed to %s", key)
+	}
+
+	c := &consumer{
+		topic:   topic,
+		channel: channel,
+		stream:  streamKey(s.config, topic),
+		handler: handler,
+		done:    make(chan struct{}),
+	}
+	if err := s.ensureGroup(context.Background(), c); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	s.consumers[key] = c
+
+	go s.run(ctx, c, c.done)
+
+	return nil
+}
+
+// SubscribeWithMiddleware 订阅消息（支持中间件）
+func (s *subscriber) SubscribeWithMiddleware(topic, channel string, handler messaging.Handler, middlewares ...messaging.Middleware) error {
+	// 应用中间件
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	// 调用标准 Subscribe
+	return s.Subscribe(topic, channel, handler)
+}
+
+// Unsubscribe 实现 messaging.Unsubscriber 接口
+// 停止该 topic/channel 的消费循环；消费组与未确认的条目保留在 Redis 中
+func (s *subscriber) Unsubscribe(topic, channel string) error {
+	key := topic + ":" + channel
+
+	s.mu.Lock()
+	c, ok := s.consumers[key]
+	delete(s.consumers, key)
+	s.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("not subscribed to %s", key)
+	}
+	c.cancel()
+	<-c.done
+	return nil
+}
+
+// Pause 实现 messaging.PausableSubscriber 接口
+// 停止消费循环但不等待其退出（当前批次的条目仍会投递）；消费组与 pending 条目保留
+func (s *subscriber) Pause(topic, channel string) error {
+	key := topic + ":" + channel
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.consumers[key]
+	if !ok {
+		return fmt.Errorf("not subscribed to %s", key)
+	}
+	if !c.paused {
+		c.paused = true
+		c.cancel()
+	}
+	return nil
+}
+
+// Resume 实现 messaging.PausableSubscriber 接口
+// 上一个消费循环退出后启动新的消费循环
+func (s *subscriber) Resume(topic, channel string) error {
+	key := topic + ":" + channel
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.consumers[key]
+	if !ok {
+		return fmt.Errorf("not subscribed to %s", key)
+	}
+	if !c.paused || s.stopped {
+		return nil
+	}
+	c.paused = false
+
+	prev := c.done
+	done := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel, c.done = cancel, done
+	go func() {
+		<-prev
+		s.run(ctx, c, done)
+	}()
+	return nil
+}
+
+// Stop 停止所有订阅，并等待消费循环退出
+func (s *subscriber) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return
+	}
+	s.stopped = true
+
+	for _, c := range s.consumers {
+		c.cancel()
+	}
+	for _, c := range s.consumers {
+		<-c.done
+	}
+}
+
+// Close 关闭订阅者（客户端由 redis/runtime 管理，这里只停止消费）
+func (s *subscriber) Close() error {
+	s.Stop()
+	return nil
+}
+
+// ensureGroup 创建消费组，已存在时忽略 BUSYGROUP 错误
+func (s *subscriber) ensureGroup(ctx context.Context, c *consumer) error {
+	err := s.client.XGroupCreateMkStream(ctx, c.stream, c.channel, s.config.StartID).Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group %s on stream %s: %w", c.channel, c.stream, err)
+	}
+	return nil
+}
+
+// run 消费循环：周期性认领 stale pending 条目，其余时间阻塞读取新条目
+func (s *subscriber) run(ctx context.Context, c *consumer, done chan struct{}) {
+	defer close(done)
+
+	// 启动时立即检查一次，接管崩溃实例遗留的 pending 条目
+	var lastClaim time.Time
+	for ctx.Err() == nil {
+		if time.Since(lastClaim) >= s.config.ClaimInterval {
+			s.reclaim(ctx, c)
+			lastClaim = time.Now()
+		}
+
+		streams, err := s.client.XReadGroup(ctx, &goredis.XReadGroupArgs{
+			Group:    c.channel,
+			Consumer: s.name,
+			Streams:  []string{c.stream, ">"},
+			Count:    s.config.BatchSize,
+			Block:    s.readBlock(),
+		}).Result()
+		if err != nil {
+			if err == goredis.Nil || ctx.Err() != nil {
+				continue
+			}
+			if strings.HasPrefix(err.Error(), "NOGROUP") {
+				// stream 被删除后消费组随之消失，重新创建
+				if err := s.ensureGroup(ctx, c); err == nil {
+					continue
+				}
+			}
+			log.Printf("[redisstream] read stream %s group %s failed: %v", c.stream, c.channel, err)
+			sleepContext(ctx, time.Second)
+			continue
+		}
+
+		for _, stream := range streams {
+			for _, entry := range stream.Messages {
+				s.dispatch(c, entry, 1)
+			}
+		}
+	}
+}
+
+// readBlock 计算本次 XREADGROUP 的阻塞时间，保证不会错过下一次认领
+func (s *subscriber) readBlock() time.Duration {
+	if s.config.ClaimInterval < s.config.BlockTimeout {
+		return s.config.ClaimInterval
+	}
+	return s.config.BlockTimeout
+}
+
+// reclaim 使用 XAUTOCLAIM 认领空闲超过 ClaimMinIdle 的 pending 条目并重新投递
+func (s *subscriber) reclaim(ctx context.Context, c *consumer) {
+	start := "0-0"
+	for ctx.Err() == nil {
+		entries, next, err := s.client.XAutoClaim(ctx, &goredis.XAutoClaimArgs{
+			Stream:   c.stream,
+			Group:    c.channel,
+			Consumer: s.name,
+			MinIdle:  s.config.ClaimMinIdle,
+			Start:    start,
+			Count:    s.config.BatchSize,
+		}).Result()
+		if err != nil {
+			if err != goredis.Nil && ctx.Err() == nil {
+				log.Printf("[redisstream] autoclaim stream %s group %s failed: %v", c.stream, c.channel, err)
+			}
+			return
+		}
+
+		for _, entry := range entries {
+			s.dispatch(c, entry, s.deliveryCount(ctx, c, entry.ID))
+		}
+
+		if next == "" || next == "0-0" {
+			return
+		}
+		start = next
+	}
+}
+
+// deliveryCount 查询条目的投递次数（XAUTOCLAIM 认领时已递增）
+func (s *subscriber) deliveryCount(ctx context.Context, c *consumer, id string) int64 {
+	pending, err := s.client.XPendingExt(ctx, &goredis.XPendingExtArgs{
+		Stream: c.stream,
+		Group:  c.channel,
+		Start:  id,
+		End:    id,
+		Count:  1,
+	}).Result()
+	if err != nil || len(pending) == 0 {
+		return 1
+	}
+	return pending[0].RetryCount
+}
+
+// dispatch 将 stream 条目转换为领域消息并调用 handler
+func (s *subscriber) dispatch(c *consumer, entry goredis.XMessage, attempts int64) {
+	ack := func() error {
+		return s.client.XAck(context.Background(), c.stream, c.channel, entry.ID).Err()
+	}
+
+	// 条目已被 MAXLEN 裁剪，只剩 PEL 记录，直接确认
+	if entry.Values == nil {
+		_ = ack()
+		return
+	}
+
+	// 超过最大投递次数，与 NSQ 的 MaxAttempts 行为一致：确认并丢弃
+	if s.config.MaxAttempts > 0 && attempts > int64(s.config.MaxAttempts) {
+		log.Printf("[redisstream] drop message %s on %s/%s after %d attempts", entry.ID, c.stream, c.channel, attempts)
+		_ = ack()
+		return
+	}
+
+	domainMsg, err := decodeEntry(entry)
+	if err != nil {
+		// 保留在 PEL 中，达到 MaxAttempts 后丢弃
+		log.Printf("[redisstream] decode message %s on %s failed: %v", entry.ID, c.stream, err)
+		return
+	}
+	domainMsg.Attempts = uint16(attempts)
+	domainMsg.Timestamp = entryTimestamp(entry.ID)
+	domainMsg.Topic = c.topic
+	domainMsg.Channel = c.channel
+
+	// 注入 Ack/Nack 函数
+	domainMsg.SetAckFunc(ack)
+	domainMsg.SetNackFunc(func() error {
+		// 不 XACK，条目留在 PEL 中等待 XAUTOCLAIM 重新认领
+		return nil
+	})
+
+	// 调用业务层的 handler
+	// 从 Metadata 恢复追踪上下文
+	ctx := messaging.ExtractTraceContext(context.Background(), domainMsg)
+	err = c.handler(ctx, domainMsg)
+	if domainMsg.IsDeferred() {
+		// 处理器接管确认（如批量消费），Nack 的条目留在 PEL 中等待认领
+		return
+	}
+	if err != nil {
+		if !domainMsg.IsSettled() {
+			domainMsg.Nack()
+		}
+		return
+	}
+
+	if !domainMsg.IsSettled() {
+		domainMsg.Ack()
+	}
+}
+
+// decodeEntry 将 stream 条目字段还原为领域消息
+func decodeEntry(entry goredis.XMessage) (*messaging.Message, error) {
+	msg := &messaging.Message{
+		UUID:     entry.ID,
+		Metadata: make(map[string]string),
+	}
+	if uuid, ok := entry.Values[fieldUUID].(string); ok && uuid != "" {
+		msg.UUID = uuid
+	}
+	if payload, ok := entry.Values[fieldPayload].(string); ok {
+		msg.Payload = []byte(payload)
+	}
+	if metadata, ok := entry.Values[fieldMetadata].(string); ok && metadata != "" {
+		if err := json.Unmarshal([]byte(metadata), &msg.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to decode message metadata: %w", err)
+		}
+	}
+	return msg, nil
+}
+
+// entryTimestamp 从条目 ID（<毫秒>-<序号>）解析纳秒时间戳
+func entryTimestamp(id string) int64 {
+	ms, _, _ := strings.Cut(id, "-")
+	value, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return value * int64(time.Millisecond)
+}
+
+func defaultConsumerName() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "consumer"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+func sleepContext(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
@@ -0,0 +1,308 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// ========== 请求-响应 ==========
+
+// 请求-响应使用的 Metadata
+const (
+	// MetadataReplyTo 响应应发布到的 topic
+	MetadataReplyTo = "reply_to"
+
+	// MetadataCorrelationID 关联请求与响应的 ID
+	MetadataCorrelationID = "correlation_id"
+
+	// MetadataReplyError 响应方处理失败时的错误信息
+	MetadataReplyError = "reply_error"
+)
+
+// DefaultRequestTimeout 请求 ctx 未设置截止时间时的默认等待时间
+const DefaultRequestTimeout = 10 * time.Second
+
+var (
+	// ErrRequestTimeout 等待响应超时
+	ErrRequestTimeout = errors.New("messaging: request timed out waiting for reply")
+
+	// ErrRequesterClosed 请求者已关闭
+	ErrRequesterClosed = errors.New("messaging: requester is closed")
+)
+
+// ReplyError 响应方处理请求失败（由 Responder 回传）
+type ReplyError struct {
+	Message string
+}
+
+func (e *ReplyError) Error() string {
+	return "messaging: responder failed: " + e.Message
+}
+
+// Requester 基于消息总线的同步请求
+//
+// 每个 Requester 订阅一个独占的响应 topic（默认 "reply.<随机 ID>"），channel 与 topic 同名：
+// RabbitMQ 的 channel 即队列名，固定名称会让所有 Requester 共用一个绑定到全部响应 exchange 的队列，
+// 响应被其他实例消费后丢弃。
+// 请求消息携带 reply_to 与 correlation_id，Request 阻塞到收到对应响应或超时。
+// 响应方使用 NewResponder 包装处理器。
+//
+// 只依赖 Publisher/Subscriber 接口，适用于所有提供者。
+// EventBus 实现 TopicDeleter 时，Close 会删除响应 topic 在 broker 端的资源
+// （NSQ topic、RabbitMQ 队列、Redis Stream），避免每次重启遗留一个响应队列。
+type Requester struct {
+	publisher    Publisher
+	subscriber   Subscriber
+	deleter      TopicDeleter
+	replyTopic   string
+	replyChannel string
+	timeout      time.Duration
+
+	mu      sync.Mutex
+	pending map[string]chan *Message
+	closed  bool
+}
+
+// RequesterOption Requester 配置项
+type RequesterOption func(*Requester)
+
+// WithReplyTopic 指定响应 topic（每个 Requester 实例必须唯一）
+func WithReplyTopic(topic string) RequesterOption {
+	return func(r *Requester) {
+		r.replyTopic = topic
+	}
+}
+
+// WithRequestTimeout 设置请求 ctx 未设置截止时间时的等待时间
+func WithRequestTimeout(timeout time.Duration) RequesterOption {
+	return func(r *Requester) {
+		r.timeout = timeout
+	}
+}
+
+// NewRequester 创建请求者并订阅响应 topic
+//
+// 使用示例：
+//
+//	requester, err := messaging.NewRequester(bus)
+//	defer requester.Close()
+//
+//	reply, err := requester.Request(ctx, "pricing.quote", messaging.NewMessage("", payload))
+func NewRequester(bus EventBus, opts ...RequesterOption) (*Requester, error) {
+	deleter, _ := bus.(TopicDeleter)
+	r := &Requester{
+		publisher:  bus.Publisher(),
+		subscriber: bus.Subscriber(),
+		deleter:    deleter,
+		replyTopic: "reply." + randomHex(8),
+		timeout:    DefaultRequestTimeout,
+		pending:    make(map[string]chan *Message),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.replyChannel = r.replyTopic
+	if r.timeout <= 0 {
+		r.timeout = DefaultRequestTimeout
+	}
+
+	// 先发布一条空消息创建响应 topic：NSQ 订阅者通过 lookupd 发现 topic，
+	// topic 不存在时要等到下一次轮询（默认 60s）才能收到响应。
+	// 订阅前没有队列时预热可能失败（如 RabbitMQ mandatory 退回），订阅后再重试一次
+	warmUpErr := r.warmUp()
+
+	if err := r.subscriber.Subscribe(r.replyTopic, r.replyChannel, r.handleReply); err != nil {
+		err = fmt.Errorf("subscribe reply topic %s: %w", r.replyTopic, err)
+		// 预热可能已在 broker 端创建了响应 topic，未订阅时可直接删除
+		if r.deleter != nil {
+			if delErr := r.deleter.DeleteTopic(context.Background(), r.replyTopic, r.replyChannel); delErr != nil {
+				err = errors.Join(err, fmt.Errorf("delete reply topic %s: %w", r.replyTopic, delErr))
+			}
+		}
+		return nil, err
+	}
+	if warmUpErr != nil {
+		if err := r.warmUp(); err != nil {
+			_ = r.Close()
+			return nil, fmt.Errorf("warm up reply topic %s: %w", r.replyTopic, err)
+		}
+	}
+	return r, nil
+}
+
+// warmUp 向响应 topic 发布一条空消息（handleReply 会直接确认丢弃）
+func (r *Requester) warmUp() error {
+	return r.publisher.PublishMessage(context.Background(), r.replyTopic, NewMessage("", nil))
+}
+
+// ReplyTopic 返回响应 topic
+func (r *Requester) ReplyTopic() string {
+	return r.replyTopic
+}
+
+// Request 发布请求并等待响应
+// 不修改传入的消息；ctx 未设置截止时间时最多等待 WithRequestTimeout 配置的时长。
+// 超时返回 ErrRequestTimeout；响应方处理失败返回 *ReplyError
+func (r *Requester) Request(ctx context.Context, topic string, msg *Message) (*Message, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	correlationID := randomHex(16)
+	reply := make(chan *Message, 1)
+
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil, ErrRequesterClosed
+	}
+	r.pending[correlationID] = reply
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		delete(r.pending, correlationID)
+		r.mu.Unlock()
+	}()
+
+	request := &Message{
+		UUID:     msg.UUID,
+		Metadata: make(map[string]string, len(msg.Metadata)+2),
+		Payload:  msg.Payload,
+	}
+	for k, v := range msg.Metadata {
+		request.Metadata[k] = v
+	}
+	request.Metadata[MetadataReplyTo] = r.replyTopic
+	request.Metadata[MetadataCorrelationID] = correlationID
+
+	if err := r.publisher.PublishMessage(ctx, topic, request); err != nil {
+		return nil, fmt.Errorf("publish request to %s: %w", topic, err)
+	}
+
+	select {
+	case resp, ok := <-reply:
+		if !ok {
+			return nil, ErrRequesterClosed
+		}
+		if reason, failed := resp.Metadata[MetadataReplyError]; failed {
+			return nil, &ReplyError{Message: reason}
+		}
+		return resp, nil
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w: topic=%s correlation_id=%s", ErrRequestTimeout, topic, correlationID)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// handleReply 将响应交给等待中的请求；无人等待（已超时或预热消息）的响应直接确认丢弃
+func (r *Requester) handleReply(ctx context.Context, msg *Message) error {
+	correlationID := msg.Metadata[MetadataCorrelationID]
+	if correlationID == "" {
+		return nil
+	}
+
+	r.mu.Lock()
+	reply, ok := r.pending[correlationID]
+	if ok {
+		delete(r.pending, correlationID)
+	}
+	r.mu.Unlock()
+
+	if ok {
+		reply <- msg
+	}
+	return nil
+}
+
+// Close 取消响应订阅（订阅者支持 Unsubscriber 时）并删除响应 topic（EventBus 支持 TopicDeleter 时），
+// 等待中的请求返回 ErrRequesterClosed。不关闭底层的 EventBus
+func (r *Requester) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	pending := r.pending
+	r.pending = make(map[string]chan *Message)
+	r.mu.Unlock()
+
+	for _, reply := range pending {
+		close(reply)
+	}
+
+	unsubscriber, ok := r.subscriber.(Unsubscriber)
+	if !ok {
+		return nil
+	}
+	if err := unsubscriber.Unsubscribe(r.replyTopic, r.replyChannel); err != nil {
+		return err
+	}
+	// 仍在消费时删除会被订阅者重新创建，只在取消订阅后删除
+	if r.deleter != nil {
+		if err := r.deleter.DeleteTopic(context.Background(), r.replyTopic, r.replyChannel); err != nil {
+			return fmt.Errorf("delete reply topic %s: %w", r.replyTopic, err)
+		}
+	}
+	return nil
+}
+
+// ReplyHandler 处理请求并返回响应消息（返回 nil 时回复空消息）
+type ReplyHandler func(ctx context.Context, msg *Message) (*Message, error)
+
+// NewResponder 将 ReplyHandler 包装为普通处理器，把响应发布到请求的 reply_to
+//
+//   - 请求未携带 reply_to 时按普通消息处理，丢弃响应，返回 fn 的错误
+//   - fn 返回错误时回复携带 reply_error 的消息，请求方得到 *ReplyError；请求照常确认，不重试
+//   - 响应发布失败只记录日志（请求方会超时），避免重新执行已完成的处理
+//
+// 使用示例：
+//
+//	router.AddHandler("pricing.quote", "pricing", messaging.NewResponder(bus.Publisher(),
+//	    func(ctx context.Context, msg *messaging.Message) (*messaging.Message, error) {
+//	        quote, err := pricing.Quote(ctx, msg.Payload)
+//	        if err != nil {
+//	            return nil, err
+//	        }
+//	        return messaging.NewTypedMessage(quote, messaging.ContentTypeJSON)
+//	    }))
+func NewResponder(publisher Publisher, fn ReplyHandler) Handler {
+	return func(ctx context.Context, msg *Message) error {
+		replyTo := msg.Metadata[MetadataReplyTo]
+		reply, err := fn(ctx, msg)
+		if replyTo == "" {
+			return err
+		}
+
+		if err != nil {
+			reply = NewMessage("", nil)
+			reply.Metadata[MetadataReplyError] = err.Error()
+		} else if reply == nil {
+			reply = NewMessage("", nil)
+		}
+
+		out := &Message{
+			UUID:     reply.UUID,
+			Metadata: make(map[string]string, len(reply.Metadata)+1),
+			Payload:  reply.Payload,
+		}
+		for k, v := range reply.Metadata {
+			out.Metadata[k] = v
+		}
+		out.Metadata[MetadataCorrelationID] = msg.Metadata[MetadataCorrelationID]
+
+		if pubErr := publisher.PublishMessage(ctx, replyTo, out); pubErr != nil {
+			log.Printf("[messaging] publish reply for message %s to %s failed: %v", msg.UUID, replyTo, pubErr)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,344 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// loopbackBus 进程内事件总线：发布的消息异步交给该 topic 的所有订阅
+type loopbackBus struct {
+	mu           sync.Mutex
+	handlers     map[string]map[string]Handler
+	deleted      []string
+	publishErr   func(topic string) error
+	subscribeErr error
+}
+
+func newLoopbackBus() *loopbackBus {
+	return &loopbackBus{handlers: make(map[string]map[string]Handler)}
+}
+
+func (b *loopbackBus) Publish(ctx context.Context, topic string, body []byte) error {
+	return b.PublishMessage(ctx, topic, NewMessage("", body))
+}
+
+func (b *loopbackBus) PublishMessage(ctx context.Context, topic string, msg *Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.publishErr != nil {
+		if err := b.publishErr(topic); err != nil {
+			return err
+		}
+	}
+	for channel, handler := range b.handlers[topic] {
+		delivered := &Message{UUID: msg.UUID, Metadata: msg.Metadata, Payload: msg.Payload, Topic: topic, Channel: channel}
+		go func(h Handler) { _ = h(context.Background(), delivered) }(handler)
+	}
+	return nil
+}
+
+func (b *loopbackBus) Subscribe(topic, channel string, handler Handler) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subscribeErr != nil {
+		return b.subscribeErr
+	}
+	if b.handlers[topic] == nil {
+		b.handlers[topic] = make(map[string]Handler)
+	}
+	b.handlers[topic][channel] = handler
+	return nil
+}
+
+func (b *loopbackBus) SubscribeWithMiddleware(topic, channel string, handler Handler, middlewares ...Middleware) error {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return b.Subscribe(topic, channel, handler)
+}
+
+func (b *loopbackBus) Unsubscribe(topic, channel string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.handlers[topic], channel)
+	return nil
+}
+
+func (b *loopbackBus) DeleteTopic(ctx context.Context, topic, channel string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.deleted = append(b.deleted, topic+"/"+channel)
+	return nil
+}
+
+func (b *loopbackBus) subscriptions(topic string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.handlers[topic])
+}
+
+func (b *loopbackBus) Stop()                  {}
+func (b *loopbackBus) Close() error           { return nil }
+func (b *loopbackBus) Publisher() Publisher   { return b }
+func (b *loopbackBus) Subscriber() Subscriber { return b }
+func (b *loopbackBus) Router() *Router        { return NewRouter(b) }
+func (b *loopbackBus) Health() error          { return nil }
+
+// queueBus 按 RabbitMQ 语义模拟：channel 是全局队列，同名 channel 绑定到多个 topic 时共用一个队列，
+// 同一队列的多个消费者轮流接收消息
+type queueBus struct {
+	loopbackBus
+	bindings  map[string]map[string]bool // topic -> queues
+	consumers map[string][]Handler       // queue -> consumers
+	next      map[string]int
+}
+
+func newQueueBus() *queueBus {
+	return &queueBus{
+		bindings:  make(map[string]map[string]bool),
+		consumers: make(map[string][]Handler),
+		next:      make(map[string]int),
+	}
+}
+
+func (b *queueBus) Publish(ctx context.Context, topic string, body []byte) error {
+	return b.PublishMessage(ctx, topic, NewMessage("", body))
+}
+
+func (b *queueBus) PublishMessage(ctx context.Context, topic string, msg *Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for queue := range b.bindings[topic] {
+		consumers := b.consumers[queue]
+		if len(consumers) == 0 {
+			continue
+		}
+		handler := consumers[b.next[queue]%len(consumers)]
+		b.next[queue]++
+		delivered := &Message{UUID: msg.UUID, Metadata: msg.Metadata, Payload: msg.Payload, Topic: topic, Channel: queue}
+		go func(h Handler) { _ = h(context.Background(), delivered) }(handler)
+	}
+	return nil
+}
+
+func (b *queueBus) Subscribe(topic, channel string, handler Handler) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.bindings[topic] == nil {
+		b.bindings[topic] = make(map[string]bool)
+	}
+	b.bindings[topic][channel] = true
+	b.consumers[channel] = append(b.consumers[channel], handler)
+	return nil
+}
+
+func (b *queueBus) Unsubscribe(topic, channel string) error {
+	return nil
+}
+
+func (b *queueBus) Publisher() Publisher   { return b }
+func (b *queueBus) Subscriber() Subscriber { return b }
+
+func TestRequestersOnSharedSubscriberReceiveOwnReplies(t *testing.T) {
+	bus := newQueueBus()
+	responder := NewResponder(bus, func(ctx context.Context, msg *Message) (*Message, error) {
+		return NewMessage("", msg.Payload), nil
+	})
+	if err := bus.Subscribe("pricing.quote", "pricing", responder); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	first, err := NewRequester(bus, WithRequestTimeout(time.Second))
+	if err != nil {
+		t.Fatalf("NewRequester: %v", err)
+	}
+	defer first.Close()
+	second, err := NewRequester(bus, WithRequestTimeout(time.Second))
+	if err != nil {
+		t.Fatalf("NewRequester: %v", err)
+	}
+	defer second.Close()
+
+	// 连续请求：共用队列时轮询会把第二个响应交给另一个实例
+	for _, requester := range []*Requester{first, second} {
+		for i := 0; i < 3; i++ {
+			payload := fmt.Sprintf("%s-%d", requester.ReplyTopic(), i)
+			reply, err := requester.Request(context.Background(), "pricing.quote", NewMessage("", []byte(payload)))
+			if err != nil {
+				t.Fatalf("Request(%s): %v", payload, err)
+			}
+			if string(reply.Payload) != payload {
+				t.Fatalf("reply = %q, want %q", reply.Payload, payload)
+			}
+		}
+	}
+}
+
+func TestRequesterRoundTrip(t *testing.T) {
+	bus := newLoopbackBus()
+	responder := NewResponder(bus, func(ctx context.Context, msg *Message) (*Message, error) {
+		reply := NewMessage("", append([]byte("quote:"), msg.Payload...))
+		reply.Metadata["currency"] = "CNY"
+		return reply, nil
+	})
+	if err := bus.Subscribe("pricing.quote", "pricing", responder); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	requester, err := NewRequester(bus)
+	if err != nil {
+		t.Fatalf("NewRequester: %v", err)
+	}
+	defer requester.Close()
+
+	req := NewMessage("req-1", []byte("sku-1"))
+	reply, err := requester.Request(context.Background(), "pricing.quote", req)
+	if err != nil {
+		t.Fatalf("Request: %v", err)
+	}
+	if string(reply.Payload) != "quote:sku-1" || reply.Metadata["currency"] != "CNY" {
+		t.Fatalf("reply = %q %v", reply.Payload, reply.Metadata)
+	}
+	if reply.Metadata[MetadataCorrelationID] == "" {
+		t.Fatalf("reply is missing correlation id: %v", reply.Metadata)
+	}
+	if _, ok := req.Metadata[MetadataReplyTo]; ok {
+		t.Fatalf("Request mutated the caller's message: %v", req.Metadata)
+	}
+}
+
+func TestRequesterReplyError(t *testing.T) {
+	bus := newLoopbackBus()
+	responder := NewResponder(bus, func(ctx context.Context, msg *Message) (*Message, error) {
+		return nil, errors.New("sku not found")
+	})
+	_ = bus.Subscribe("pricing.quote", "pricing", responder)
+
+	requester, err := NewRequester(bus)
+	if err != nil {
+		t.Fatalf("NewRequester: %v", err)
+	}
+	defer requester.Close()
+
+	_, err = requester.Request(context.Background(), "pricing.quote", NewMessage("", nil))
+	var replyErr *ReplyError
+	if !errors.As(err, &replyErr) || replyErr.Message != "sku not found" {
+		t.Fatalf("error = %v, want ReplyError(sku not found)", err)
+	}
+}
+
+func TestRequesterTimeout(t *testing.T) {
+	bus := newLoopbackBus()
+	requester, err := NewRequester(bus, WithRequestTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewRequester: %v", err)
+	}
+	defer requester.Close()
+
+	_, err = requester.Request(context.Background(), "pricing.quote", NewMessage("", nil))
+	if !errors.Is(err, ErrRequestTimeout) {
+		t.Fatalf("error = %v, want ErrRequestTimeout", err)
+	}
+	requester.mu.Lock()
+	pending := len(requester.pending)
+	requester.mu.Unlock()
+	if pending != 0 {
+		t.Fatalf("pending = %d after timeout, want 0", pending)
+	}
+}
+
+func TestRequesterClose(t *testing.T) {
+	bus := newLoopbackBus()
+	requester, err := NewRequester(bus)
+	if err != nil {
+		t.Fatalf("NewRequester: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := requester.Request(context.Background(), "pricing.quote", NewMessage("", nil))
+		done <- err
+	}()
+	deadline := time.Now().Add(time.Second)
+	for {
+		requester.mu.Lock()
+		waiting := len(requester.pending)
+		requester.mu.Unlock()
+		if waiting == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("request was not registered")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := requester.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := <-done; !errors.Is(err, ErrRequesterClosed) {
+		t.Fatalf("error = %v, want ErrRequesterClosed", err)
+	}
+	if n := bus.subscriptions(requester.ReplyTopic()); n != 0 {
+		t.Fatalf("reply subscriptions = %d after Close, want 0", n)
+	}
+	reply := requester.ReplyTopic()
+	if len(bus.deleted) != 1 || bus.deleted[0] != reply+"/"+reply {
+		t.Fatalf("deleted = %v, want reply topic and channel %s", bus.deleted, reply)
+	}
+}
+
+func TestRequesterWarmUp(t *testing.T) {
+	// 订阅前预热失败（如 mandatory 退回）时订阅后重试成功
+	bus := newLoopbackBus()
+	bus.publishErr = func(topic string) error {
+		if len(bus.handlers[topic]) == 0 {
+			return errors.New("unroutable")
+		}
+		return nil
+	}
+	requester, err := NewRequester(bus)
+	if err != nil {
+		t.Fatalf("NewRequester after retried warm-up: %v", err)
+	}
+	_ = requester.Close()
+
+	// broker 不可用时返回错误，并清理响应订阅与 topic
+	down := newLoopbackBus()
+	brokerErr := errors.New("broker down")
+	down.publishErr = func(string) error { return brokerErr }
+	if _, err := NewRequester(down, WithReplyTopic("reply.test")); !errors.Is(err, brokerErr) {
+		t.Fatalf("NewRequester error = %v, want warm-up error", err)
+	}
+	if n := down.subscriptions("reply.test"); n != 0 || len(down.deleted) != 1 {
+		t.Fatalf("subscriptions=%d deleted=%v after failed warm-up, want cleaned up", n, down.deleted)
+	}
+
+	// 订阅失败时删除预热创建的响应 topic
+	unsubscribable := newLoopbackBus()
+	unsubscribable.subscribeErr = errors.New("access refused")
+	if _, err := NewRequester(unsubscribable, WithReplyTopic("reply.test")); !errors.Is(err, unsubscribable.subscribeErr) {
+		t.Fatalf("NewRequester error = %v, want subscribe error", err)
+	}
+	if len(unsubscribable.deleted) != 1 || unsubscribable.deleted[0] != "reply.test/reply.test" {
+		t.Fatalf("deleted = %v after failed subscribe, want reply topic deleted", unsubscribable.deleted)
+	}
+}
+
+func TestResponderWithoutReplyTo(t *testing.T) {
+	pub := &fakePublisher{}
+	handlerErr := errors.New("boom")
+	responder := NewResponder(pub, func(ctx context.Context, msg *Message) (*Message, error) {
+		return nil, handlerErr
+	})
+	if err := responder(context.Background(), NewMessage("", nil)); !errors.Is(err, handlerErr) {
+		t.Fatalf("error = %v, want handler error", err)
+	}
+	if len(pub.messages()) != 0 {
+		t.Fatalf("published %d replies without reply_to", len(pub.messages()))
+	}
+}
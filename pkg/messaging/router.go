@@ -2,7 +2,9 @@ package messaging
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"sync"
 )
 
@@ -15,6 +17,7 @@ type Router struct {
 	mu          sync.RWMutex
 	running     bool
 	stopChan    chan struct{}
+	drain       *drainTracker
 }
 
 // handlerConfig 处理器配置
@@ -23,14 +26,21 @@ type handlerConfig struct {
 	channel     string
 	handler     Handler
 	middlewares []Middleware
+	deadLetter  *DeadLetterPolicy
+	ordered     *OrderedProcessor
+	stats       *handlerStats
 }
 
+// ErrUnsubscribeNotSupported 订阅者不支持取消单个订阅，无法在运行期间移除处理器
+var ErrUnsubscribeNotSupported = errors.New("messaging: subscriber does not support unsubscribe")
+
 // NewRouter 创建路由器
 func NewRouter(subscriber Subscriber) *Router {
 	return &Router{
 		subscriber: subscriber,
 		handlers:   make(map[string]*handlerConfig),
 		stopChan:   make(chan struct{}),
+		drain:      newDrainTracker(),
 	}
 }
 
@@ -38,16 +48,11 @@ func NewRouter(subscriber Subscriber) *Router {
 // topic: 主题名称
 // channel: 通道名称
 // handler: 消息处理函数
+//
+// Run 之前调用时只登记（同名处理器会被替换）；运行期间调用会立即订阅，
+// 失败（同名处理器已存在或订阅失败）时只记录日志，需要感知错误时使用 TryAddHandler
 func (r *Router) AddHandler(topic, channel string, handler Handler) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	key := fmt.Sprintf("%s:%s", topic, channel)
-	r.handlers[key] = &handlerConfig{
-		topic:   topic,
-		channel: channel,
-		handler: handler,
-	}
+	logAddHandlerError(r.TryAddHandler(topic, channel, handler))
 }
 
 // AddHandlerWithMiddleware 注册消息处理器（支持中间件）
@@ -56,20 +61,145 @@ func (r *Router) AddHandler(topic, channel string, handler Handler) {
 // handler: 消息处理函数
 // middlewares: 中间件列表（按顺序执行）
 func (r *Router) AddHandlerWithMiddleware(topic, channel string, handler Handler, middlewares ...Middleware) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	logAddHandlerError(r.TryAddHandler(topic, channel, handler, middlewares...))
+}
 
-	key := fmt.Sprintf("%s:%s", topic, channel)
-	r.handlers[key] = &handlerConfig{
+// AddHandlerWithDeadLetter 注册带死信策略的消息处理器
+// 死信中间件位于中间件链最外层，因此 RetryMiddleware 等内部重试只计为一次处理
+// policy: 死信策略（非法策略会 panic；未设置 ConsumerMaxAttempts 时取自实现 DeliveryLimiter 的订阅者）
+// middlewares: 中间件列表（按顺序执行）
+func (r *Router) AddHandlerWithDeadLetter(topic, channel string, handler Handler, policy DeadLetterPolicy, middlewares ...Middleware) {
+	err := r.TryAddHandlerWithDeadLetter(topic, channel, handler, policy, middlewares...)
+	var invalid *invalidDeadLetterPolicyError
+	if errors.As(err, &invalid) {
+		panic("messaging: invalid dead letter policy: " + invalid.err.Error())
+	}
+	logAddHandlerError(err)
+}
+
+// TryAddHandler 注册消息处理器（支持中间件），返回注册错误
+// Run 之前调用时只登记（同名处理器会被替换），总是返回 nil；
+// 运行期间调用会立即订阅，同名处理器已存在或订阅失败时返回错误
+func (r *Router) TryAddHandler(topic, channel string, handler Handler, middlewares ...Middleware) error {
+	return r.addHandler(&handlerConfig{
 		topic:       topic,
 		channel:     channel,
 		handler:     handler,
 		middlewares: middlewares,
+	})
+}
+
+// TryAddHandlerWithDeadLetter 注册带死信策略的消息处理器，返回注册错误
+// 与 AddHandlerWithDeadLetter 相同，但非法策略返回错误而不是 panic
+func (r *Router) TryAddHandlerWithDeadLetter(topic, channel string, handler Handler, policy DeadLetterPolicy, middlewares ...Middleware) error {
+	if limiter, ok := r.subscriber.(DeliveryLimiter); ok && policy.ConsumerMaxAttempts == 0 {
+		policy.ConsumerMaxAttempts = limiter.MaxDeliveryAttempts()
+	}
+	if err := policy.Validate(); err != nil {
+		return &invalidDeadLetterPolicyError{err: err}
+	}
+
+	return r.addHandler(&handlerConfig{
+		topic:       topic,
+		channel:     channel,
+		handler:     handler,
+		middlewares: middlewares,
+		deadLetter:  &policy,
+	})
+}
+
+// invalidDeadLetterPolicyError 死信策略校验失败，AddHandlerWithDeadLetter 据此 panic
+type invalidDeadLetterPolicyError struct {
+	err error
+}
+
+func (e *invalidDeadLetterPolicyError) Error() string {
+	return "invalid dead letter policy: " + e.err.Error()
+}
+
+func (e *invalidDeadLetterPolicyError) Unwrap() error { return e.err }
+
+// logAddHandlerError 记录不返回错误的 AddHandler* 在运行期间的注册失败
+func logAddHandlerError(err error) {
+	if err != nil {
+		log.Printf("[messaging] add handler failed: %v", err)
+	}
+}
+
+// AddOrderedHandler 注册按键有序的消息处理器
+// 整条处理器链（含排空跟踪、统计、死信）在 processor 的 worker 中执行，
+// 订阅者实现 SerialSubscriber 时以串行方式订阅，见 OrderedProcessor
+func (r *Router) AddOrderedHandler(topic, channel string, processor *OrderedProcessor, handler Handler, middlewares ...Middleware) error {
+	return r.addHandler(&handlerConfig{
+		topic:       topic,
+		channel:     channel,
+		handler:     handler,
+		middlewares: middlewares,
+		ordered:     processor,
+	})
+}
+
+func (r *Router) addHandler(cfg *handlerConfig) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := handlerKey(cfg.topic, cfg.channel)
+	cfg.stats = newHandlerStats(cfg.topic, cfg.channel)
+	if !r.running {
+		r.handlers[key] = cfg
+		return nil
 	}
+
+	if _, exists := r.handlers[key]; exists {
+		return fmt.Errorf("handler %s is already registered", key)
+	}
+	if err := r.subscribeLocked(cfg); err != nil {
+		return err
+	}
+	r.handlers[key] = cfg
+	return nil
+}
+
+// RemoveHandler 移除消息处理器
+// 运行期间会取消对应的订阅并等待其处理中的消息完成，
+// 订阅者未实现 Unsubscriber 时返回 ErrUnsubscribeNotSupported
+func (r *Router) RemoveHandler(topic, channel string) error {
+	key := handlerKey(topic, channel)
+
+	r.mu.Lock()
+	if _, ok := r.handlers[key]; !ok {
+		r.mu.Unlock()
+		return fmt.Errorf("handler %s is not registered", key)
+	}
+	if !r.running {
+		delete(r.handlers, key)
+		r.mu.Unlock()
+		return nil
+	}
+	unsubscriber, ok := r.subscriber.(Unsubscriber)
+	if !ok {
+		r.mu.Unlock()
+		return fmt.Errorf("%w: %T", ErrUnsubscribeNotSupported, r.subscriber)
+	}
+	cfg := r.handlers[key]
+	r.mu.Unlock()
+
+	// 取消订阅会等待处理中的消息，不持有路由器锁；
+	// 失败时订阅仍然有效，保留处理器，避免之后重复注册同一订阅
+	if err := unsubscriber.Unsubscribe(topic, channel); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	if r.handlers[key] == cfg {
+		delete(r.handlers, key)
+	}
+	r.mu.Unlock()
+	return nil
 }
 
 // AddMiddleware 添加全局中间件
-// 全局中间件会应用到所有处理器
+// 全局中间件会应用到所有处理器（运行期间添加时只对之后注册的处理器生效）
 func (r *Router) AddMiddleware(mw Middleware) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -86,15 +216,15 @@ func (r *Router) Run(ctx context.Context) error {
 		return fmt.Errorf("router is already running")
 	}
 	r.running = true
-	r.mu.Unlock()
 
 	// 订阅所有处理器
 	for _, cfg := range r.handlers {
-		decoratedHandler := r.decorateHandler(cfg.handler, cfg.middlewares)
-		if err := r.subscriber.Subscribe(cfg.topic, cfg.channel, decoratedHandler); err != nil {
-			return fmt.Errorf("failed to subscribe %s:%s: %w", cfg.topic, cfg.channel, err)
+		if err := r.subscribeLocked(cfg); err != nil {
+			r.mu.Unlock()
+			return err
 		}
 	}
+	r.mu.Unlock()
 
 	// 等待退出信号
 	select {
@@ -105,7 +235,29 @@ func (r *Router) Run(ctx context.Context) error {
 	}
 }
 
+// subscribeLocked 组装处理器链并订阅
+// 由外到内：（有序分派）→ 排空跟踪 → 统计 → 死信 → 全局中间件 → 局部中间件 → 处理器
+func (r *Router) subscribeLocked(cfg *handlerConfig) error {
+	decoratedHandler := r.decorateHandler(cfg.handler, cfg.middlewares)
+	if cfg.deadLetter != nil {
+		decoratedHandler = DeadLetterMiddleware(*cfg.deadLetter)(decoratedHandler)
+	}
+	decoratedHandler = r.drain.track(cfg.stats.record(decoratedHandler))
+
+	var err error
+	if cfg.ordered != nil {
+		err = subscribeSerial(r.subscriber, cfg.topic, cfg.channel, cfg.ordered.Handler(decoratedHandler))
+	} else {
+		err = r.subscriber.Subscribe(cfg.topic, cfg.channel, decoratedHandler)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to subscribe %s:%s: %w", cfg.topic, cfg.channel, err)
+	}
+	return nil
+}
+
 // Stop 停止路由器
+// 立即停止订阅，不等待处理中的消息；需要优雅排空时使用 Shutdown
 func (r *Router) Stop() {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -119,6 +271,10 @@ func (r *Router) Stop() {
 	r.subscriber.Stop()
 }
 
+func handlerKey(topic, channel string) string {
+	return fmt.Sprintf("%s:%s", topic, channel)
+}
+
 // decorateHandler 装饰处理器（应用中间件）
 // 先应用全局中间件，再应用局部中间件
 func (r *Router) decorateHandler(handler Handler, localMiddlewares []Middleware) Handler {
@@ -0,0 +1,206 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/FangcunMount/component-base/pkg/shutdown"
+)
+
+// ========== 优雅排空 ==========
+
+// ErrRouterShuttingDown 路由器正在关闭，消息未交给处理器（已 Nack）
+var ErrRouterShuttingDown = errors.New("messaging: router is shutting down")
+
+// DrainStats 优雅关闭的排空统计
+type DrainStats struct {
+	// InFlight 开始关闭时正在处理的消息数
+	InFlight int
+
+	// Completed 关闭期间处理完成（由处理器自行 Ack/Nack）的消息数
+	Completed int
+
+	// Nacked 超过截止时间仍未完成、被强制 Nack 的消息数
+	Nacked int
+
+	// Rejected 开始关闭后才到达（如预取缓冲中的消息），未交给处理器直接 Nack 的消息数
+	Rejected int
+
+	// Duration 排空耗时
+	Duration time.Duration
+}
+
+// drainTracker 跟踪处理中的消息
+type drainTracker struct {
+	mu       sync.Mutex
+	inflight map[*Message]context.CancelFunc
+	draining bool
+	idle     chan struct{} // 排空期间处理中的消息清零时关闭
+	stats    DrainStats
+}
+
+func newDrainTracker() *drainTracker {
+	return &drainTracker{
+		inflight: make(map[*Message]context.CancelFunc),
+		idle:     make(chan struct{}),
+	}
+}
+
+// begin 登记开始处理；排空期间返回 false
+func (t *drainTracker) begin(msg *Message, cancel context.CancelFunc) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		t.stats.Rejected++
+		return false
+	}
+	t.inflight[msg] = cancel
+	return true
+}
+
+// end 登记处理结束
+func (t *drainTracker) end(msg *Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.inflight[msg]; !ok {
+		// 已被 abort 强制 Nack
+		return
+	}
+	delete(t.inflight, msg)
+	if t.draining {
+		t.stats.Completed++
+		if len(t.inflight) == 0 {
+			close(t.idle)
+		}
+	}
+}
+
+// start 进入排空状态，返回处理中的消息清零时关闭的 channel
+func (t *drainTracker) start() <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.draining {
+		t.draining = true
+		t.stats.InFlight = len(t.inflight)
+		if len(t.inflight) == 0 {
+			close(t.idle)
+		}
+	}
+	return t.idle
+}
+
+// abort 强制 Nack 所有未完成的消息，并取消其处理器 ctx
+func (t *drainTracker) abort() {
+	t.mu.Lock()
+	inflight := t.inflight
+	t.inflight = make(map[*Message]context.CancelFunc)
+	t.stats.Nacked += len(inflight)
+	t.mu.Unlock()
+
+	for msg, cancel := range inflight {
+		cancel()
+		if err := msg.Nack(); err != nil {
+			log.Printf("[messaging] nack message %s on shutdown failed: %v", msg.UUID, err)
+		}
+	}
+}
+
+func (t *drainTracker) snapshot() DrainStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stats
+}
+
+// track 最外层处理器包装：登记处理中的消息，排空期间直接 Nack 新到达的消息
+// 处理器通过 Defer 接管确认的消息（如 BatchConsumer、PriorityConsumer 缓冲的消息）
+// 在 Ack/Nack 之前仍视为处理中：排空会等待其确认，截止时与其他消息一样被强制 Nack
+func (t *drainTracker) track(next Handler) Handler {
+	return func(ctx context.Context, msg *Message) error {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		if !t.begin(msg, cancel) {
+			_ = msg.Nack()
+			return ErrRouterShuttingDown
+		}
+
+		err := next(ctx, msg)
+		if err == nil && msg.IsDeferred() {
+			msg.whenSettled(func(bool) { t.end(msg) })
+			return nil
+		}
+		t.end(msg)
+		return err
+	}
+}
+
+// Shutdown 优雅关闭路由器
+//
+//  1. 停止订阅，不再拉取新消息（已预取但未处理的消息直接 Nack，交由 broker 重新投递）
+//  2. 等待处理中的消息完成（含处理器 Defer 后尚未确认的缓冲消息），最长到 ctx 截止
+//  3. 截止时仍未完成的消息强制 Nack，并取消其处理器 ctx
+//
+// 返回排空统计；ctx 截止时同时返回 ctx.Err()。路由器未运行时返回零值统计。
+func (r *Router) Shutdown(ctx context.Context) (DrainStats, error) {
+	started := time.Now()
+
+	r.mu.Lock()
+	if !r.running {
+		r.mu.Unlock()
+		return DrainStats{}, nil
+	}
+	r.running = false
+	close(r.stopChan)
+	r.mu.Unlock()
+
+	idle := r.drain.start()
+
+	// 部分订阅者的 Stop 会等待处理中的消息，放到后台执行
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		r.subscriber.Stop()
+	}()
+
+	var err error
+	select {
+	case <-idle:
+	case <-ctx.Done():
+		err = ctx.Err()
+		r.drain.abort()
+	}
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		if err == nil {
+			err = ctx.Err()
+		}
+	}
+
+	stats := r.drain.snapshot()
+	stats.Duration = time.Since(started)
+	return stats, err
+}
+
+// ShutdownCallback 返回可注册到 shutdown.GracefulShutdown 的回调
+// timeout: 排空的最长等待时间
+//
+// 使用示例：
+//
+//	gs := shutdown.New()
+//	gs.AddShutdownCallback(router.ShutdownCallback(30 * time.Second))
+func (r *Router) ShutdownCallback(timeout time.Duration) shutdown.ShutdownCallback {
+	return shutdown.ShutdownFunc(func(string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		stats, err := r.Shutdown(ctx)
+		log.Printf("[messaging] router drained: in_flight=%d completed=%d nacked=%d rejected=%d duration=%s",
+			stats.InFlight, stats.Completed, stats.Nacked, stats.Rejected, stats.Duration)
+		return err
+	})
+}
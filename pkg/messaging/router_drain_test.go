@@ -0,0 +1,171 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// startRouter 启动路由器并等待订阅完成
+func startRouter(t *testing.T, router *Router, sub *fakeSubscriber, key string) Handler {
+	t.Helper()
+	go router.Run(context.Background())
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if h := sub.handler(key); h != nil {
+			return h
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("handler %s was not subscribed", key)
+	return nil
+}
+
+func TestRouterShutdownWaitsForInFlightHandlers(t *testing.T) {
+	sub := &fakeSubscriber{}
+	router := NewRouter(sub)
+	release := make(chan struct{})
+	entered := make(chan struct{})
+	router.AddHandler("orders", "billing", func(ctx context.Context, msg *Message) error {
+		close(entered)
+		<-release
+		return nil
+	})
+	handler := startRouter(t, router, sub, "orders:billing")
+
+	acked := make(chan struct{})
+	msg := NewMessage("msg-1", nil)
+	msg.SetAckFunc(func() error { close(acked); return nil })
+	go func() {
+		if err := handler(context.Background(), msg); err == nil {
+			_ = msg.Ack()
+		}
+	}()
+	<-entered
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+	stats, err := router.Shutdown(context.Background())
+	if err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if stats.InFlight != 1 || stats.Completed != 1 || stats.Nacked != 0 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	<-acked
+
+	// 关闭后到达的消息不再交给处理器
+	late := NewMessage("msg-2", nil)
+	nacked := false
+	late.SetNackFunc(func() error { nacked = true; return nil })
+	if err := handler(context.Background(), late); !errors.Is(err, ErrRouterShuttingDown) || !nacked {
+		t.Fatalf("late message error = %v, nacked = %v", err, nacked)
+	}
+	if stats := router.drain.snapshot(); stats.Rejected != 1 {
+		t.Fatalf("rejected = %d, want 1", stats.Rejected)
+	}
+	if !sub.stopped {
+		t.Fatalf("subscriber should be stopped")
+	}
+}
+
+func TestRouterShutdownNacksUnfinishedHandlersAtDeadline(t *testing.T) {
+	sub := &fakeSubscriber{}
+	router := NewRouter(sub)
+	entered := make(chan struct{})
+	router.AddHandler("orders", "billing", func(ctx context.Context, msg *Message) error {
+		close(entered)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	handler := startRouter(t, router, sub, "orders:billing")
+
+	nacked := make(chan struct{})
+	msg := NewMessage("msg-1", nil)
+	msg.SetNackFunc(func() error { close(nacked); return nil })
+	go handler(context.Background(), msg)
+	<-entered
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	stats, err := router.Shutdown(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown error = %v, want deadline exceeded", err)
+	}
+	if stats.InFlight != 1 || stats.Nacked != 1 || stats.Completed != 0 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	select {
+	case <-nacked:
+	case <-time.After(time.Second):
+		t.Fatal("unfinished message was not nacked")
+	}
+}
+
+func TestRouterShutdownTracksBufferedBatchMessages(t *testing.T) {
+	deliver := func(t *testing.T, flushInterval time.Duration) (*Router, []*Message, *[]string) {
+		sub := &fakeSubscriber{}
+		router := NewRouter(sub)
+		batch := NewBatchConsumer(BatchConfig{Size: 10, FlushInterval: flushInterval},
+			func(ctx context.Context, msgs []*Message) error { return nil })
+		t.Cleanup(func() { _ = batch.Close() })
+		router.AddHandler("metrics", "ingest", batch.Handler())
+		handler := startRouter(t, router, sub, "metrics:ingest")
+
+		var settled []string
+		msgs := make([]*Message, 3)
+		for i := range msgs {
+			msg := NewMessage(string(rune('a'+i)), nil)
+			msg.SetAckFunc(func() error { settled = append(settled, "ack "+msg.UUID); return nil })
+			msg.SetNackFunc(func() error { settled = append(settled, "nack "+msg.UUID); return nil })
+			if err := handler(context.Background(), msg); err != nil {
+				t.Fatalf("handler: %v", err)
+			}
+			msgs[i] = msg
+		}
+		// 缓冲中的消息尚未确认，不计为成功
+		if st := router.Stats()["metrics:ingest"]; st.Succeeded != 0 || st.InFlight != 3 {
+			t.Fatalf("stats before flush = %+v, want 3 in flight", st)
+		}
+		return router, msgs, &settled
+	}
+
+	t.Run("waits for flush", func(t *testing.T) {
+		router, _, settled := deliver(t, 20*time.Millisecond)
+		stats, err := router.Shutdown(context.Background())
+		if err != nil {
+			t.Fatalf("Shutdown: %v", err)
+		}
+		if stats.InFlight != 3 || stats.Completed != 3 || stats.Nacked != 0 || len(*settled) != 3 {
+			t.Fatalf("stats = %+v settled = %v, want the partial batch flushed before drain completes", stats, *settled)
+		}
+		if st := router.Stats()["metrics:ingest"]; st.Succeeded != 3 || st.InFlight != 0 {
+			t.Fatalf("handler stats = %+v", st)
+		}
+	})
+
+	t.Run("nacks at deadline", func(t *testing.T) {
+		router, msgs, settled := deliver(t, time.Hour)
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		stats, err := router.Shutdown(ctx)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Shutdown error = %v, want deadline exceeded", err)
+		}
+		if stats.InFlight != 3 || stats.Nacked != 3 {
+			t.Fatalf("stats = %+v, want buffered messages nacked", stats)
+		}
+		for _, msg := range msgs {
+			if !msg.IsSettled() {
+				t.Fatalf("message %s left unsettled", msg.UUID)
+			}
+		}
+		if len(*settled) != 3 || (*settled)[0][:4] != "nack" {
+			t.Fatalf("settled = %v, want all nacked", *settled)
+		}
+	})
+}
@@ -0,0 +1,177 @@
+package messaging
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ========== 处理器统计 ==========
+
+// DefaultLatencyBuckets 处理耗时直方图的默认桶上界
+var DefaultLatencyBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// HandlerStats 单个处理器的统计快照
+type HandlerStats struct {
+	Topic   string
+	Channel string
+
+	// Received 交给处理器的消息数
+	Received uint64
+
+	// Succeeded 处理成功的消息数
+	Succeeded uint64
+
+	// Failed 处理失败（返回错误）的消息数
+	Failed uint64
+
+	// Retried 重新投递的消息数（Attempts > 1）
+	Retried uint64
+
+	// InFlight 正在处理的消息数
+	InFlight int64
+
+	// Latency 处理耗时分布
+	Latency LatencyHistogram
+}
+
+// LatencyHistogram 耗时直方图快照
+type LatencyHistogram struct {
+	// Bounds 各桶上界（升序）
+	Bounds []time.Duration
+
+	// Counts 各桶计数（非累积），长度为 len(Bounds)+1，最后一个桶为超过最大上界的部分
+	Counts []uint64
+
+	// Count 样本数
+	Count uint64
+
+	// Sum 耗时总和
+	Sum time.Duration
+}
+
+// Mean 平均耗时
+func (h LatencyHistogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// handlerStats 处理器统计计数器
+type handlerStats struct {
+	topic   string
+	channel string
+
+	received  atomic.Uint64
+	succeeded atomic.Uint64
+	failed    atomic.Uint64
+	retried   atomic.Uint64
+	inFlight  atomic.Int64
+
+	mu     sync.Mutex
+	counts []uint64
+	count  uint64
+	sum    time.Duration
+}
+
+func newHandlerStats(topic, channel string) *handlerStats {
+	return &handlerStats{
+		topic:   topic,
+		channel: channel,
+		counts:  make([]uint64, len(DefaultLatencyBuckets)+1),
+	}
+}
+
+// record 统计包装：记录接收、结果与耗时
+// 处理器通过 Defer 接管确认的消息（如 BatchConsumer 缓冲的消息）在 Ack/Nack 时才计入结果
+func (s *handlerStats) record(next Handler) Handler {
+	return func(ctx context.Context, msg *Message) error {
+		s.received.Add(1)
+		if msg.Attempts > 1 {
+			s.retried.Add(1)
+		}
+		s.inFlight.Add(1)
+		started := time.Now()
+
+		err := next(ctx, msg)
+
+		if err == nil && msg.IsDeferred() {
+			msg.whenSettled(func(acked bool) { s.finish(started, acked) })
+			return nil
+		}
+		s.finish(started, err == nil)
+		return err
+	}
+}
+
+// finish 记录一条消息的处理结果
+func (s *handlerStats) finish(started time.Time, succeeded bool) {
+	s.observe(time.Since(started))
+	s.inFlight.Add(-1)
+	if succeeded {
+		s.succeeded.Add(1)
+	} else {
+		s.failed.Add(1)
+	}
+}
+
+func (s *handlerStats) observe(d time.Duration) {
+	i := 0
+	for i < len(DefaultLatencyBuckets) && d > DefaultLatencyBuckets[i] {
+		i++
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts[i]++
+	s.count++
+	s.sum += d
+}
+
+func (s *handlerStats) snapshot() HandlerStats {
+	s.mu.Lock()
+	latency := LatencyHistogram{
+		Bounds: append([]time.Duration(nil), DefaultLatencyBuckets...),
+		Counts: append([]uint64(nil), s.counts...),
+		Count:  s.count,
+		Sum:    s.sum,
+	}
+	s.mu.Unlock()
+
+	return HandlerStats{
+		Topic:     s.topic,
+		Channel:   s.channel,
+		Received:  s.received.Load(),
+		Succeeded: s.succeeded.Load(),
+		Failed:    s.failed.Load(),
+		Retried:   s.retried.Load(),
+		InFlight:  s.inFlight.Load(),
+		Latency:   latency,
+	}
+}
+
+// Stats 返回所有已注册处理器的统计快照，key 为 "topic:channel"
+func (r *Router) Stats() map[string]HandlerStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := make(map[string]HandlerStats, len(r.handlers))
+	for key, cfg := range r.handlers {
+		stats[key] = cfg.stats.snapshot()
+	}
+	return stats
+}
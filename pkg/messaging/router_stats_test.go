@@ -0,0 +1,133 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeUnsubscriber struct {
+	fakeSubscriber
+	unsubscribed []string
+	err          error
+}
+
+func (s *fakeUnsubscriber) Unsubscribe(topic, channel string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	key := topic + ":" + channel
+	delete(s.handlers, key)
+	s.unsubscribed = append(s.unsubscribed, key)
+	return nil
+}
+
+func TestRouterAddAndRemoveHandlerWhileRunning(t *testing.T) {
+	sub := &fakeUnsubscriber{}
+	router := NewRouter(sub)
+	if err := router.TryAddHandler("orders", "billing", func(ctx context.Context, msg *Message) error { return nil }); err != nil {
+		t.Fatalf("TryAddHandler before run: %v", err)
+	}
+	startRouter(t, router, &sub.fakeSubscriber, "orders:billing")
+
+	if err := router.TryAddHandler("orders", "audit", func(ctx context.Context, msg *Message) error { return nil }); err != nil {
+		t.Fatalf("TryAddHandler while running: %v", err)
+	}
+	if sub.handler("orders:audit") == nil {
+		t.Fatalf("handler added at runtime should be subscribed immediately")
+	}
+	if err := router.TryAddHandler("orders", "audit", func(ctx context.Context, msg *Message) error { return nil }); err == nil {
+		t.Fatalf("duplicate handler while running should fail")
+	}
+
+	if err := router.RemoveHandler("orders", "audit"); err != nil {
+		t.Fatalf("RemoveHandler: %v", err)
+	}
+	if len(sub.unsubscribed) != 1 || sub.unsubscribed[0] != "orders:audit" {
+		t.Fatalf("unsubscribed = %v", sub.unsubscribed)
+	}
+	if _, ok := router.Stats()["orders:audit"]; ok {
+		t.Fatalf("removed handler should not be reported")
+	}
+	if err := router.RemoveHandler("orders", "audit"); err == nil {
+		t.Fatalf("removing an unknown handler should fail")
+	}
+	router.Stop()
+}
+
+func TestRouterRemoveHandlerRequiresUnsubscriber(t *testing.T) {
+	sub := &fakeSubscriber{}
+	router := NewRouter(sub)
+	router.AddHandler("orders", "billing", func(ctx context.Context, msg *Message) error { return nil })
+	startRouter(t, router, sub, "orders:billing")
+	defer router.Stop()
+
+	if err := router.RemoveHandler("orders", "billing"); !errors.Is(err, ErrUnsubscribeNotSupported) {
+		t.Fatalf("error = %v, want ErrUnsubscribeNotSupported", err)
+	}
+	if _, ok := router.Stats()["orders:billing"]; !ok {
+		t.Fatalf("handler should remain registered when unsubscribe is unsupported")
+	}
+}
+
+func TestRouterRemoveHandlerKeepsHandlerWhenUnsubscribeFails(t *testing.T) {
+	sub := &fakeUnsubscriber{err: errors.New("channel closed")}
+	router := NewRouter(sub)
+	router.AddHandler("orders", "billing", func(ctx context.Context, msg *Message) error { return nil })
+	startRouter(t, router, &sub.fakeSubscriber, "orders:billing")
+	defer router.Stop()
+
+	if err := router.RemoveHandler("orders", "billing"); err == nil {
+		t.Fatalf("RemoveHandler should return the unsubscribe error")
+	}
+	if _, ok := router.Stats()["orders:billing"]; !ok {
+		t.Fatalf("handler should remain registered while its subscription is live")
+	}
+	// 订阅仍然有效，不能重复订阅
+	if err := router.TryAddHandler("orders", "billing", func(ctx context.Context, msg *Message) error { return nil }); err == nil {
+		t.Fatalf("re-adding a live handler should fail")
+	}
+
+	sub.mu.Lock()
+	sub.err = nil
+	sub.mu.Unlock()
+	if err := router.RemoveHandler("orders", "billing"); err != nil {
+		t.Fatalf("RemoveHandler retry: %v", err)
+	}
+	if _, ok := router.Stats()["orders:billing"]; ok {
+		t.Fatalf("handler should be removed after a successful unsubscribe")
+	}
+}
+
+func TestRouterStatsCountsOutcomes(t *testing.T) {
+	sub := &fakeSubscriber{}
+	router := NewRouter(sub)
+	router.TryAddHandler("orders", "billing", func(ctx context.Context, msg *Message) error {
+		if msg.UUID == "bad" {
+			return errors.New("boom")
+		}
+		time.Sleep(6 * time.Millisecond)
+		return nil
+	})
+	handler := startRouter(t, router, sub, "orders:billing")
+	defer router.Stop()
+
+	handler(context.Background(), NewMessage("ok", nil))
+	retry := NewMessage("bad", nil)
+	retry.Attempts = 2
+	handler(context.Background(), retry)
+
+	stats := router.Stats()["orders:billing"]
+	if stats.Received != 2 || stats.Succeeded != 1 || stats.Failed != 1 || stats.Retried != 1 || stats.InFlight != 0 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	if stats.Latency.Count != 2 || len(stats.Latency.Counts) != len(stats.Latency.Bounds)+1 {
+		t.Fatalf("latency = %+v", stats.Latency)
+	}
+	if stats.Latency.Counts[0] != 1 || stats.Latency.Mean() <= 0 {
+		t.Fatalf("latency buckets = %v, mean = %s", stats.Latency.Counts, stats.Latency.Mean())
+	}
+}
@@ -0,0 +1,72 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ========== 路由键与绑定 ==========
+
+// MetadataRoutingKey 发布时使用的路由键
+// 支持路由的提供者（RabbitMQ direct/topic exchange）会以该值作为 routing key；
+// 不支持路由的提供者将其作为普通 Metadata 透传
+const MetadataRoutingKey = "routing_key"
+
+// ErrBindingNotSupported 提供者不支持路由绑定
+var ErrBindingNotSupported = errors.New("messaging: subscription binding is not supported by provider")
+
+// Binding 订阅绑定规则
+//
+// RabbitMQ 中的映射：
+//   - direct/topic exchange：RoutingKeys 为绑定键，topic 支持通配符（orders.*.created、orders.#）
+//   - headers exchange：Headers 为匹配的消息头，MatchAll 决定 x-match=all 还是 any
+type Binding struct {
+	// RoutingKeys 绑定键（可多个，队列会分别绑定）
+	RoutingKeys []string
+
+	// Headers headers exchange 匹配的消息头
+	Headers map[string]string
+
+	// MatchAll true: 所有 Headers 都匹配（x-match=all）；false: 任意一个匹配（x-match=any）
+	MatchAll bool
+}
+
+// IsZero 是否为空绑定（等价于普通 Subscribe）
+func (b Binding) IsZero() bool {
+	return len(b.RoutingKeys) == 0 && len(b.Headers) == 0
+}
+
+// BindingSubscriber 支持路由绑定的订阅者
+// 由支持路由的提供者实现（如 rabbitmq），业务代码通过 SubscribeWithBinding 使用
+type BindingSubscriber interface {
+	SubscribeWithBinding(topic, channel string, binding Binding, handler Handler, middlewares ...Middleware) error
+}
+
+// SubscribeWithBinding 按绑定规则订阅
+// 空绑定等价于 SubscribeWithMiddleware；提供者不支持绑定时返回 ErrBindingNotSupported
+//
+// 使用示例：
+//
+//	err := messaging.SubscribeWithBinding(subscriber, "orders", "billing",
+//	    messaging.Binding{RoutingKeys: []string{"orders.*.created"}}, handler)
+func SubscribeWithBinding(subscriber Subscriber, topic, channel string, binding Binding, handler Handler, middlewares ...Middleware) error {
+	if bs, ok := subscriber.(BindingSubscriber); ok {
+		return bs.SubscribeWithBinding(topic, channel, binding, handler, middlewares...)
+	}
+	if binding.IsZero() {
+		return subscriber.SubscribeWithMiddleware(topic, channel, handler, middlewares...)
+	}
+	return fmt.Errorf("%w: %T", ErrBindingNotSupported, subscriber)
+}
+
+// PublishWithRoutingKey 携带路由键发布消息
+//
+// 使用示例：
+//
+//	messaging.PublishWithRoutingKey(ctx, publisher, "orders", "orders.eu.created", data)
+func PublishWithRoutingKey(ctx context.Context, publisher Publisher, topic, routingKey string, body []byte) error {
+	msg := NewMessage("", body)
+	msg.Metadata[MetadataRoutingKey] = routingKey
+	return publisher.PublishMessage(ctx, topic, msg)
+}
@@ -0,0 +1,90 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+type fakeSubscriber struct {
+	mu         sync.Mutex
+	subscribed []string
+	bindings   []Binding
+	handlers   map[string]Handler
+	stopped    bool
+}
+
+func (s *fakeSubscriber) Subscribe(topic, channel string, handler Handler) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := topic + ":" + channel
+	s.subscribed = append(s.subscribed, key)
+	if s.handlers == nil {
+		s.handlers = make(map[string]Handler)
+	}
+	s.handlers[key] = handler
+	return nil
+}
+
+func (s *fakeSubscriber) handler(key string) Handler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.handlers[key]
+}
+
+func (s *fakeSubscriber) SubscribeWithMiddleware(topic, channel string, handler Handler, middlewares ...Middleware) error {
+	return s.Subscribe(topic, channel, handler)
+}
+
+func (s *fakeSubscriber) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = true
+}
+
+func (s *fakeSubscriber) Close() error { return nil }
+
+type fakeBindingSubscriber struct {
+	fakeSubscriber
+}
+
+func (s *fakeBindingSubscriber) SubscribeWithBinding(topic, channel string, binding Binding, handler Handler, middlewares ...Middleware) error {
+	s.bindings = append(s.bindings, binding)
+	return s.Subscribe(topic, channel, handler)
+}
+
+func TestSubscribeWithBinding(t *testing.T) {
+	handler := func(ctx context.Context, msg *Message) error { return nil }
+	binding := Binding{RoutingKeys: []string{"orders.*.created"}}
+
+	bs := &fakeBindingSubscriber{}
+	if err := SubscribeWithBinding(bs, "orders", "billing", binding, handler); err != nil {
+		t.Fatalf("binding subscriber: %v", err)
+	}
+	if len(bs.bindings) != 1 || bs.bindings[0].RoutingKeys[0] != "orders.*.created" {
+		t.Fatalf("bindings = %+v", bs.bindings)
+	}
+
+	plain := &fakeSubscriber{}
+	if err := SubscribeWithBinding(plain, "orders", "billing", Binding{}, handler); err != nil {
+		t.Fatalf("zero binding should fall back to Subscribe: %v", err)
+	}
+	if len(plain.subscribed) != 1 {
+		t.Fatalf("subscribed = %v", plain.subscribed)
+	}
+	if err := SubscribeWithBinding(plain, "orders", "audit", binding, handler); !errors.Is(err, ErrBindingNotSupported) {
+		t.Fatalf("error = %v, want ErrBindingNotSupported", err)
+	}
+}
+
+func TestPublishWithRoutingKey(t *testing.T) {
+	pub := &fakePublisher{}
+	if err := PublishWithRoutingKey(context.Background(), pub, "orders", "orders.eu.created", []byte("x")); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	published := pub.messages()
+	if len(published) != 1 || published[0].msg.Metadata[MetadataRoutingKey] != "orders.eu.created" {
+		t.Fatalf("published = %+v", published)
+	}
+}
@@ -0,0 +1,273 @@
+package messaging
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ========== 消息签名 ==========
+
+// 签名写入的 Metadata 键
+const (
+	// MetadataSignature HMAC-SHA256 签名（十六进制）
+	MetadataSignature = "signature"
+
+	// MetadataSignatureKeyID 签名密钥 ID
+	MetadataSignatureKeyID = "signature_key_id"
+
+	// MetadataSignatureTimestamp 签名时间（Unix 秒）
+	MetadataSignatureTimestamp = "signature_timestamp"
+
+	// MetadataSignatureNonce 签名随机数（防重放）
+	MetadataSignatureNonce = "signature_nonce"
+
+	// MetadataSignedMetadata 参与签名的 Metadata 键（逗号分隔，有序）
+	MetadataSignedMetadata = "signature_metadata"
+)
+
+// DefaultSignatureMaxAge 签名默认有效期
+const DefaultSignatureMaxAge = time.Hour
+
+var (
+	// ErrSignatureInvalid 签名缺失、密钥未知、签名不匹配或已过期
+	ErrSignatureInvalid = errors.New("messaging: message signature is invalid")
+
+	// ErrMessageReplayed 签名随机数已被使用（消息重放）
+	ErrMessageReplayed = errors.New("messaging: message replayed")
+
+	// ErrUnknownKey 密钥 ID 不存在，由 VerifierConfig.GetKey 返回（可包装）
+	ErrUnknownKey = errors.New("messaging: unknown signing key")
+)
+
+// NonceStore 随机数存储（防重放）
+// 方法集与 grpc/interceptors.NonceStore 一致，其实现可以直接复用
+type NonceStore interface {
+	// Exists 检查 nonce 是否已存在
+	Exists(ctx context.Context, nonce string) (bool, error)
+	// Store 存储 nonce
+	Store(ctx context.Context, nonce string, ttl time.Duration) error
+}
+
+// NonceClaimer 可原子占用随机数的存储（NonceStore 的可选能力）
+//
+// 只实现 NonceStore 时，先检查后记录之间存在竞争：并发投递的副本或原消息处理期间到达的重放都能通过检查。
+// 实现本接口后，验证中间件在调用处理器之前占用随机数，处理失败时释放以便 broker 重投。
+// redisdedup.NonceStore 已实现
+type NonceClaimer interface {
+	// ClaimNonce 原子地占用 nonce（SETNX 语义），已被占用时返回 false
+	ClaimNonce(ctx context.Context, nonce string, ttl time.Duration) (bool, error)
+	// ReleaseNonce 释放占用
+	ReleaseNonce(ctx context.Context, nonce string) error
+}
+
+// SigningPublisherMiddleware 签名发布中间件
+// 使用 HMAC-SHA256 对 Payload 与 metadataKeys 指定的 Metadata 签名，
+// 签名、密钥 ID、时间戳与随机数写入 Metadata。不修改传入的消息
+//
+// 使用示例：
+//
+//	publisher := messaging.WrapPublisher(bus.Publisher(),
+//	    messaging.SigningPublisherMiddleware("partner-2024", secret, messaging.MetadataContentType))
+func SigningPublisherMiddleware(keyID string, key []byte, metadataKeys ...string) PublisherMiddleware {
+	signed := append([]string(nil), metadataKeys...)
+	sort.Strings(signed)
+
+	return func(next PublishFunc) PublishFunc {
+		return func(ctx context.Context, topic string, msg *Message) error {
+			md := make(map[string]string, len(msg.Metadata)+5)
+			for k, v := range msg.Metadata {
+				md[k] = v
+			}
+			md[MetadataSignatureKeyID] = keyID
+			md[MetadataSignatureTimestamp] = strconv.FormatInt(time.Now().Unix(), 10)
+			md[MetadataSignatureNonce] = randomHex(16)
+			md[MetadataSignedMetadata] = strings.Join(signed, ",")
+			md[MetadataSignature] = computeSignature(key, md, signed, msg.Payload)
+
+			return next(ctx, topic, &Message{
+				UUID:      msg.UUID,
+				Metadata:  md,
+				Payload:   msg.Payload,
+				Attempts:  msg.Attempts,
+				Timestamp: msg.Timestamp,
+				Topic:     msg.Topic,
+				Channel:   msg.Channel,
+			})
+		}
+	}
+}
+
+// VerifierConfig 签名验证配置
+type VerifierConfig struct {
+	// GetKey 按密钥 ID 获取密钥（必填）
+	// 密钥不存在时必须返回 ErrUnknownKey（可包装），消息按签名无效处理；
+	// 其他错误（如密钥存储超时）视为临时错误原样返回，消息被 Nack 后重投再验证
+	GetKey func(keyID string) ([]byte, error)
+
+	// MaxAge 签名有效期（默认 1h），同时限制时钟偏差
+	// 应大于消息在队列中的最长等待时间，延迟发布的消息需额外考虑延迟时长
+	MaxAge time.Duration
+
+	// NonceStore 随机数存储（可选），配置后拒绝重放的消息
+	// 同时实现 NonceClaimer 时在处理前原子占用随机数，推荐使用
+	NonceStore NonceStore
+
+	// DeadLetterPublisher 验证失败的消息发布到死信主题（可选）
+	// 未配置时返回错误，可由外层的 DeadLetterMiddleware 处理
+	DeadLetterPublisher Publisher
+
+	// DeadLetterTopicFunc 死信主题命名函数（默认 DeadLetterTopic）
+	DeadLetterTopicFunc func(topic string) string
+}
+
+// VerifySignatureMiddleware 签名验证中间件
+//
+// 校验签名、时间戳（MaxAge 内）与随机数（未被处理过）。验证失败属于永久错误：
+// 配置了 DeadLetterPublisher 时立即转入死信主题并确认原消息，否则返回 ErrSignatureInvalid / ErrMessageReplayed。
+//
+// 随机数的记录方式取决于 NonceStore：
+//   - 实现了 NonceClaimer：处理前占用，处理失败时释放，broker 对失败消息的重投不会被视为重放
+//   - 只实现 NonceStore：处理成功后记录；记录失败时返回错误，消息会被重投并再次处理
+func VerifySignatureMiddleware(cfg VerifierConfig) Middleware {
+	if cfg.GetKey == nil {
+		panic("messaging: signature verifier GetKey is nil")
+	}
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = DefaultSignatureMaxAge
+	}
+	claimer, _ := cfg.NonceStore.(NonceClaimer)
+	nonceTTL := 2 * cfg.MaxAge
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *Message) error {
+			nonce, err := verifySignature(cfg, msg)
+			if err != nil {
+				return rejectUnverified(ctx, cfg, msg, err)
+			}
+
+			if claimer != nil {
+				claimed, err := claimer.ClaimNonce(ctx, nonce, nonceTTL)
+				if err != nil {
+					// 存储不可用属于临时错误，重投后再验证
+					return fmt.Errorf("failed to claim signature nonce: %w", err)
+				}
+				if !claimed {
+					return rejectUnverified(ctx, cfg, msg, fmt.Errorf("%w: nonce %s already used", ErrMessageReplayed, nonce))
+				}
+				if err := next(ctx, msg); err != nil {
+					if releaseErr := claimer.ReleaseNonce(ctx, nonce); releaseErr != nil {
+						return errors.Join(err, fmt.Errorf("failed to release signature nonce: %w", releaseErr))
+					}
+					return err
+				}
+				return nil
+			}
+
+			if cfg.NonceStore != nil {
+				exists, err := cfg.NonceStore.Exists(ctx, nonce)
+				if err != nil {
+					return fmt.Errorf("failed to check signature nonce: %w", err)
+				}
+				if exists {
+					return rejectUnverified(ctx, cfg, msg, fmt.Errorf("%w: nonce %s already used", ErrMessageReplayed, nonce))
+				}
+			}
+			if err := next(ctx, msg); err != nil {
+				return err
+			}
+			if cfg.NonceStore != nil {
+				if err := cfg.NonceStore.Store(ctx, nonce, nonceTTL); err != nil {
+					return fmt.Errorf("failed to store signature nonce: %w", err)
+				}
+			}
+			return nil
+		}
+	}
+}
+
+// verifySignature 校验消息签名，返回签名随机数
+func verifySignature(cfg VerifierConfig, msg *Message) (string, error) {
+	md := msg.Metadata
+	signature, keyID, nonce := md[MetadataSignature], md[MetadataSignatureKeyID], md[MetadataSignatureNonce]
+	if signature == "" || keyID == "" || nonce == "" {
+		return "", fmt.Errorf("%w: signature is missing", ErrSignatureInvalid)
+	}
+
+	ts, err := strconv.ParseInt(md[MetadataSignatureTimestamp], 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("%w: malformed timestamp", ErrSignatureInvalid)
+	}
+	age := time.Since(time.Unix(ts, 0))
+	if age > cfg.MaxAge || age < -cfg.MaxAge {
+		return "", fmt.Errorf("%w: signed %s ago, max age %s", ErrSignatureInvalid, age.Round(time.Second), cfg.MaxAge)
+	}
+
+	key, err := cfg.GetKey(keyID)
+	if errors.Is(err, ErrUnknownKey) {
+		return "", fmt.Errorf("%w: unknown key %s: %v", ErrSignatureInvalid, keyID, err)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get signing key %s: %w", keyID, err)
+	}
+	var signed []string
+	if list := md[MetadataSignedMetadata]; list != "" {
+		signed = strings.Split(list, ",")
+	}
+	expected := computeSignature(key, md, signed, msg.Payload)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return "", fmt.Errorf("%w: signature mismatch", ErrSignatureInvalid)
+	}
+	return nonce, nil
+}
+
+// rejectUnverified 处理验证失败的消息：永久错误转入死信主题，其余错误原样返回
+func rejectUnverified(ctx context.Context, cfg VerifierConfig, msg *Message, cause error) error {
+	permanent := errors.Is(cause, ErrSignatureInvalid) || errors.Is(cause, ErrMessageReplayed)
+	if !permanent || cfg.DeadLetterPublisher == nil {
+		return cause
+	}
+
+	topic := DeadLetterTopic(msg.Topic)
+	if cfg.DeadLetterTopicFunc != nil {
+		topic = cfg.DeadLetterTopicFunc(msg.Topic)
+	}
+	now := time.Now()
+	dead := buildDeadLetter(msg, cause, failureRecord{attempts: int(msg.Attempts), firstFailure: now}, now)
+	if err := cfg.DeadLetterPublisher.PublishMessage(ctx, topic, dead); err != nil {
+		return errors.Join(cause, fmt.Errorf("failed to publish dead letter: %w", err))
+	}
+	return nil
+}
+
+// computeSignature 计算签名：依次写入密钥 ID、时间戳、随机数、签名键列表、参与签名的 Metadata 键值与 Payload，
+// 每个字段带长度前缀，避免字段拼接产生歧义
+func computeSignature(key []byte, md map[string]string, signed []string, payload []byte) string {
+	h := hmac.New(sha256.New, key)
+	writeSignatureField(h, []byte(md[MetadataSignatureKeyID]))
+	writeSignatureField(h, []byte(md[MetadataSignatureTimestamp]))
+	writeSignatureField(h, []byte(md[MetadataSignatureNonce]))
+	writeSignatureField(h, []byte(md[MetadataSignedMetadata]))
+	for _, k := range signed {
+		writeSignatureField(h, []byte(k))
+		writeSignatureField(h, []byte(md[k]))
+	}
+	writeSignatureField(h, payload)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeSignatureField(h hash.Hash, field []byte) {
+	var size [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(size[:], uint64(len(field)))
+	h.Write(size[:n])
+	h.Write(field)
+}
@@ -0,0 +1,292 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeNonceStore struct {
+	mu     sync.Mutex
+	nonces map[string]time.Duration
+}
+
+func (s *fakeNonceStore) Exists(ctx context.Context, nonce string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.nonces[nonce]
+	return ok, nil
+}
+
+func (s *fakeNonceStore) Store(ctx context.Context, nonce string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.nonces == nil {
+		s.nonces = make(map[string]time.Duration)
+	}
+	s.nonces[nonce] = ttl
+	return nil
+}
+
+// claimingNonceStore 实现 NonceClaimer 的随机数存储
+type claimingNonceStore struct {
+	fakeNonceStore
+	released int
+}
+
+func (s *claimingNonceStore) ClaimNonce(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.nonces[nonce]; ok {
+		return false, nil
+	}
+	if s.nonces == nil {
+		s.nonces = make(map[string]time.Duration)
+	}
+	s.nonces[nonce] = ttl
+	return true, nil
+}
+
+func (s *claimingNonceStore) ReleaseNonce(ctx context.Context, nonce string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.nonces, nonce)
+	s.released++
+	return nil
+}
+
+// failingNonceStore 记录随机数总是失败
+type failingNonceStore struct {
+	fakeNonceStore
+}
+
+func (s *failingNonceStore) Store(ctx context.Context, nonce string, ttl time.Duration) error {
+	return errors.New("redis unavailable")
+}
+
+var signingKeys = map[string][]byte{"partner-1": []byte("s3cret")}
+
+func getSigningKey(keyID string) ([]byte, error) {
+	key, ok := signingKeys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+	return key, nil
+}
+
+// signedMessage 经签名中间件发布一条消息并返回发布结果
+func signedMessage(t *testing.T, keyID string, key []byte) *Message {
+	t.Helper()
+	pub := &fakePublisher{}
+	wrapped := WrapPublisher(pub, SigningPublisherMiddleware(keyID, key, MetadataContentType))
+	msg := NewMessage("m1", []byte(`{"amount":100}`))
+	msg.Metadata[MetadataContentType] = ContentTypeJSON
+	msg.Metadata["unsigned"] = "free"
+	if err := wrapped.PublishMessage(context.Background(), "partner.orders", msg); err != nil {
+		t.Fatalf("PublishMessage: %v", err)
+	}
+	if _, ok := msg.Metadata[MetadataSignature]; ok {
+		t.Fatalf("signing mutated the caller's message")
+	}
+	signed := pub.messages()[0].msg
+	signed.Topic = "partner.orders"
+	return signed
+}
+
+func TestVerifySignatureAcceptsSignedMessages(t *testing.T) {
+	nonces := &fakeNonceStore{}
+	calls := 0
+	handler := VerifySignatureMiddleware(VerifierConfig{GetKey: getSigningKey, NonceStore: nonces})(
+		func(ctx context.Context, msg *Message) error {
+			calls++
+			return nil
+		})
+
+	msg := signedMessage(t, "partner-1", signingKeys["partner-1"])
+	msg.Metadata["unsigned"] = "changed" // 未签名的 Metadata 可以被修改
+	if err := handler(context.Background(), msg); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("handler calls = %d", calls)
+	}
+	if ttl := nonces.nonces[msg.Metadata[MetadataSignatureNonce]]; ttl != 2*DefaultSignatureMaxAge {
+		t.Fatalf("nonce ttl = %v", ttl)
+	}
+
+	if err := handler(context.Background(), msg); !errors.Is(err, ErrMessageReplayed) {
+		t.Fatalf("replayed message = %v, want ErrMessageReplayed", err)
+	}
+	if calls != 1 {
+		t.Fatalf("replayed message reached the handler")
+	}
+}
+
+func TestVerifySignatureRejectsTampering(t *testing.T) {
+	handler := VerifySignatureMiddleware(VerifierConfig{GetKey: getSigningKey, MaxAge: time.Minute})(
+		func(ctx context.Context, msg *Message) error { return nil })
+
+	tests := map[string]func(*Message){
+		"payload":     func(m *Message) { m.Payload = []byte(`{"amount":1}`) },
+		"metadata":    func(m *Message) { m.Metadata[MetadataContentType] = "text/plain" },
+		"signed keys": func(m *Message) { m.Metadata[MetadataSignedMetadata] = "" },
+		"missing":     func(m *Message) { delete(m.Metadata, MetadataSignature) },
+		"unknown key": func(m *Message) { m.Metadata[MetadataSignatureKeyID] = "other" },
+		"stale": func(m *Message) {
+			m.Metadata[MetadataSignatureTimestamp] = strconv.FormatInt(time.Now().Add(-2*time.Minute).Unix(), 10)
+		},
+	}
+	for name, tamper := range tests {
+		msg := signedMessage(t, "partner-1", signingKeys["partner-1"])
+		tamper(msg)
+		if err := handler(context.Background(), msg); !errors.Is(err, ErrSignatureInvalid) {
+			t.Fatalf("%s: error = %v, want ErrSignatureInvalid", name, err)
+		}
+	}
+
+	forged := signedMessage(t, "partner-1", []byte("wrong-key"))
+	if err := handler(context.Background(), forged); !errors.Is(err, ErrSignatureInvalid) {
+		t.Fatalf("forged: error = %v, want ErrSignatureInvalid", err)
+	}
+}
+
+func TestVerifySignatureRoutesFailuresToDeadLetter(t *testing.T) {
+	dlq := &fakePublisher{}
+	nonces := &fakeNonceStore{}
+	handlerErr := errors.New("downstream failed")
+	fail := true
+	handler := VerifySignatureMiddleware(VerifierConfig{
+		GetKey:              getSigningKey,
+		NonceStore:          nonces,
+		DeadLetterPublisher: dlq,
+	})(func(ctx context.Context, msg *Message) error {
+		if fail {
+			return handlerErr
+		}
+		return nil
+	})
+
+	// 处理失败的消息重投时不被视为重放
+	msg := signedMessage(t, "partner-1", signingKeys["partner-1"])
+	if err := handler(context.Background(), msg); !errors.Is(err, handlerErr) {
+		t.Fatalf("first delivery = %v, want handler error", err)
+	}
+	fail = false
+	if err := handler(context.Background(), msg); err != nil {
+		t.Fatalf("redelivery = %v", err)
+	}
+
+	tampered := signedMessage(t, "partner-1", signingKeys["partner-1"])
+	tampered.Payload = []byte("evil")
+	if err := handler(context.Background(), tampered); err != nil {
+		t.Fatalf("tampered message should be acked after dead-lettering, got %v", err)
+	}
+	dead := dlq.messages()
+	if len(dead) != 1 || dead[0].topic != "partner.orders.dlq" {
+		t.Fatalf("dead letters = %+v", dead)
+	}
+	if dead[0].msg.Metadata[MetadataDLQLastError] == "" {
+		t.Fatalf("dead letter should carry the verification error")
+	}
+}
+
+func TestVerifySignatureClaimsNonceBeforeHandling(t *testing.T) {
+	nonces := &claimingNonceStore{}
+	var calls atomic.Int32
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	handler := VerifySignatureMiddleware(VerifierConfig{GetKey: getSigningKey, NonceStore: nonces})(
+		func(ctx context.Context, msg *Message) error {
+			calls.Add(1)
+			close(entered)
+			<-release
+			return nil
+		})
+
+	msg := signedMessage(t, "partner-1", signingKeys["partner-1"])
+	first := make(chan error, 1)
+	go func() { first <- handler(context.Background(), msg) }()
+	<-entered
+
+	// 原消息处理期间到达的副本被视为重放
+	const duplicates = 3
+	for i := 0; i < duplicates; i++ {
+		if err := handler(context.Background(), msg); !errors.Is(err, ErrMessageReplayed) {
+			t.Fatalf("duplicate %d = %v, want ErrMessageReplayed", i, err)
+		}
+	}
+	close(release)
+	if err := <-first; err != nil {
+		t.Fatalf("original delivery = %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("handler calls = %d, want 1", calls.Load())
+	}
+	if err := handler(context.Background(), msg); !errors.Is(err, ErrMessageReplayed) {
+		t.Fatalf("replay after success = %v, want ErrMessageReplayed", err)
+	}
+}
+
+func TestVerifySignatureReleasesClaimOnFailure(t *testing.T) {
+	nonces := &claimingNonceStore{}
+	handlerErr := errors.New("downstream failed")
+	fail := true
+	handler := VerifySignatureMiddleware(VerifierConfig{GetKey: getSigningKey, NonceStore: nonces})(
+		func(ctx context.Context, msg *Message) error {
+			if fail {
+				return handlerErr
+			}
+			return nil
+		})
+
+	msg := signedMessage(t, "partner-1", signingKeys["partner-1"])
+	if err := handler(context.Background(), msg); !errors.Is(err, handlerErr) {
+		t.Fatalf("first delivery = %v, want handler error", err)
+	}
+	if nonces.released != 1 {
+		t.Fatalf("released = %d, want 1", nonces.released)
+	}
+	fail = false
+	if err := handler(context.Background(), msg); err != nil {
+		t.Fatalf("redelivery = %v", err)
+	}
+}
+
+func TestVerifySignatureReturnsNonceStoreError(t *testing.T) {
+	handler := VerifySignatureMiddleware(VerifierConfig{GetKey: getSigningKey, NonceStore: &failingNonceStore{}})(
+		func(ctx context.Context, msg *Message) error { return nil })
+
+	msg := signedMessage(t, "partner-1", signingKeys["partner-1"])
+	if err := handler(context.Background(), msg); err == nil {
+		t.Fatal("a failed nonce Store should be returned so the message is redelivered")
+	}
+}
+
+func TestVerifySignatureRetriesKeyStoreOutage(t *testing.T) {
+	dlq := &fakePublisher{}
+	outage := errors.New("vault: timeout")
+	handler := VerifySignatureMiddleware(VerifierConfig{
+		GetKey:              func(keyID string) ([]byte, error) { return nil, outage },
+		DeadLetterPublisher: dlq,
+	})(func(ctx context.Context, msg *Message) error { return nil })
+
+	// 密钥存储不可用不是签名无效：返回错误重投，不转入死信
+	msg := signedMessage(t, "partner-1", signingKeys["partner-1"])
+	if err := handler(context.Background(), msg); !errors.Is(err, outage) || errors.Is(err, ErrSignatureInvalid) {
+		t.Fatalf("error = %v, want key store error returned for redelivery", err)
+	}
+	if len(dlq.messages()) != 0 {
+		t.Fatalf("valid message dead-lettered during a key store outage")
+	}
+
+	unknown := signedMessage(t, "retired", []byte("old-key"))
+	if err := VerifySignatureMiddleware(VerifierConfig{GetKey: getSigningKey})(
+		func(ctx context.Context, msg *Message) error { return nil })(context.Background(), unknown); !errors.Is(err, ErrSignatureInvalid) {
+		t.Fatalf("unknown key = %v, want ErrSignatureInvalid", err)
+	}
+}
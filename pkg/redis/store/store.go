@@ -35,6 +35,27 @@ func (k StoreKey) String() string {
 	return string(k)
 }
 
+var (
+	compareAndSetScript = goredis.NewScript(`
+if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+else
+	redis.call("SET", KEYS[1], ARGV[2])
+end
+return 1
+`)
+	compareAndDeleteScript = goredis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+else
+	return 0
+end
+`)
+)
+
 // ValueStore 是最小可复用的 typed Redis value store。
 type ValueStore[T any] struct {
 	client   goredis.UniversalClient
@@ -155,6 +176,72 @@ func (s *ValueStore[T]) SetIfAbsent(ctx context.Context, key StoreKey, value T,
 	return ok, err
 }
 
+// CompareAndSet 仅在当前值等于 expected 时原子地写入 value，返回是否写入。
+// 按编码后的字节比较，要求 codec 的编码结果是确定的。
+func (s *ValueStore[T]) CompareAndSet(ctx context.Context, key StoreKey, expected, value T, ttl time.Duration) (bool, error) {
+	if s == nil || s.client == nil {
+		return false, fmt.Errorf("redis client is nil")
+	}
+	if s.codec == nil {
+		return false, fmt.Errorf("value codec is nil")
+	}
+	if key.String() == "" {
+		return false, fmt.Errorf("store key is empty")
+	}
+
+	expectedPayload, err := s.codec.Marshal(expected)
+	if err != nil {
+		return false, err
+	}
+	payload, err := s.codec.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := compareAndSetScript.Run(ctx, s.client, []string{key.String()}, expectedPayload, payload, ttl.Milliseconds()).Int64()
+	ok := result == 1
+	s.observer.OnStore(ctx, observability.StoreEvent{
+		Operation: "compare_and_set",
+		Key:       key.String(),
+		Codec:     s.codec.Name(),
+		Hit:       ok,
+		TTL:       ttl,
+		Size:      len(payload),
+		Err:       err,
+	})
+	return ok, err
+}
+
+// CompareAndDelete 仅在当前值等于 expected 时原子地删除键，返回是否删除。
+// 按编码后的字节比较，要求 codec 的编码结果是确定的。
+func (s *ValueStore[T]) CompareAndDelete(ctx context.Context, key StoreKey, expected T) (bool, error) {
+	if s == nil || s.client == nil {
+		return false, fmt.Errorf("redis client is nil")
+	}
+	if s.codec == nil {
+		return false, fmt.Errorf("value codec is nil")
+	}
+	if key.String() == "" {
+		return false, fmt.Errorf("store key is empty")
+	}
+
+	expectedPayload, err := s.codec.Marshal(expected)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := compareAndDeleteScript.Run(ctx, s.client, []string{key.String()}, expectedPayload).Int64()
+	ok := result == 1
+	s.observer.OnStore(ctx, observability.StoreEvent{
+		Operation: "compare_and_delete",
+		Key:       key.String(),
+		Codec:     s.codec.Name(),
+		Hit:       ok,
+		Err:       err,
+	})
+	return ok, err
+}
+
 // Delete 从 Redis 中删除一个键。
 func (s *ValueStore[T]) Delete(ctx context.Context, key StoreKey) error {
 	if s == nil || s.client == nil {
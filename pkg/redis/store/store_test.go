@@ -118,3 +118,38 @@ func newTestClient(t *testing.T) (*goredis.Client, func()) {
 		_ = client.Close()
 	}
 }
+
+func TestValueStoreCompareAndSetAndDelete(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+
+	valueStore := NewValueStore[string](client, StringCodec{})
+	key := MustStoreKey("sample:cas")
+	ctx := context.Background()
+
+	if ok, err := valueStore.CompareAndSet(ctx, key, "a", "b", time.Minute); err != nil || ok {
+		t.Fatalf("CompareAndSet() on missing key = (%v, %v), want (false, nil)", ok, err)
+	}
+	if err := valueStore.Set(ctx, key, "a", 0); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if ok, err := valueStore.CompareAndSet(ctx, key, "x", "b", time.Minute); err != nil || ok {
+		t.Fatalf("CompareAndSet() with stale value = (%v, %v), want (false, nil)", ok, err)
+	}
+	if ok, err := valueStore.CompareAndSet(ctx, key, "a", "b", time.Minute); err != nil || !ok {
+		t.Fatalf("CompareAndSet() = (%v, %v), want (true, nil)", ok, err)
+	}
+	if ttl := client.PTTL(ctx, key.String()).Val(); ttl <= 0 || ttl > time.Minute {
+		t.Fatalf("TTL after CompareAndSet() = %s, want (0, 1m]", ttl)
+	}
+
+	if ok, err := valueStore.CompareAndDelete(ctx, key, "a"); err != nil || ok {
+		t.Fatalf("CompareAndDelete() with stale value = (%v, %v), want (false, nil)", ok, err)
+	}
+	if ok, err := valueStore.CompareAndDelete(ctx, key, "b"); err != nil || !ok {
+		t.Fatalf("CompareAndDelete() = (%v, %v), want (true, nil)", ok, err)
+	}
+	if exists, _ := valueStore.Exists(ctx, key); exists {
+		t.Fatalf("key should be deleted")
+	}
+}
@@ -28,11 +28,28 @@ type txState struct {
 }
 
 type UnitOfWork struct {
-	db *gorm.DB
+	db                 *gorm.DB
+	onAfterCommitError func(context.Context, error)
 }
 
-func NewUnitOfWork(db *gorm.DB) *UnitOfWork {
-	return &UnitOfWork{db: db}
+type Option func(*UnitOfWork)
+
+// WithAfterCommitErrorHandler reports after-commit hook failures. The transaction
+// is already committed when hooks run, so WithinTransaction still returns nil.
+func WithAfterCommitErrorHandler(handler func(ctx context.Context, err error)) Option {
+	return func(u *UnitOfWork) {
+		u.onAfterCommitError = handler
+	}
+}
+
+func NewUnitOfWork(db *gorm.DB, opts ...Option) *UnitOfWork {
+	u := &UnitOfWork{db: db}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(u)
+		}
+	}
+	return u
 }
 
 func WithTx(ctx context.Context, tx *gorm.DB) context.Context {
@@ -105,6 +122,9 @@ func (u *UnitOfWork) WithinTransaction(ctx context.Context, fn func(txCtx contex
 	for _, hook := range state.afterCommit {
 		if hookErr := hook(ctx); hookErr != nil {
 			log.Warnf("after commit hook failed: %v", hookErr)
+			if u.onAfterCommitError != nil {
+				u.onAfterCommitError(ctx, hookErr)
+			}
 		}
 	}
 	return nil
@@ -77,6 +77,40 @@ func TestWithinTransactionCommitRunsAfterCommit(t *testing.T) {
 	}
 }
 
+func TestWithinTransactionReportsAfterCommitFailures(t *testing.T) {
+	t.Parallel()
+
+	db, mock := newMockGORM(t)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	hookErr := errors.New("publish failed")
+	var reported []error
+	secondCalled := false
+	uow := NewUnitOfWork(db, WithAfterCommitErrorHandler(func(_ context.Context, err error) {
+		reported = append(reported, err)
+	}))
+	err := uow.WithinTransaction(context.Background(), func(txCtx context.Context) error {
+		_ = AfterCommit(txCtx, func(context.Context) error { return hookErr })
+		return AfterCommit(txCtx, func(context.Context) error {
+			secondCalled = true
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatalf("WithinTransaction() error = %v, want nil after commit", err)
+	}
+	if len(reported) != 1 || !errors.Is(reported[0], hookErr) {
+		t.Fatalf("reported = %v, want [%v]", reported, hookErr)
+	}
+	if !secondCalled {
+		t.Fatal("a failing hook should not skip later hooks")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("sql expectations: %v", err)
+	}
+}
+
 func TestWithinTransactionRollbackSkipsAfterCommit(t *testing.T) {
 	t.Parallel()
 